@@ -0,0 +1,210 @@
+// Package ash records samples of the active sessions of a database in the
+// manner of Oracle's Active Session History, without needing the
+// Diagnostics Pack: otop polls V$SESSION itself and keeps the samples in
+// memory, optionally appending them to a local file as well.
+package ash
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+)
+
+const (
+	// DefaultInterval is how often active sessions are sampled, the same
+	// rate as Oracle's own ASH.
+	DefaultInterval = time.Second
+
+	// DefaultRetention is how much history is kept in memory.
+	DefaultRetention = time.Hour
+)
+
+// Options configures a Recorder.
+type Options struct {
+	// Interval between samples. Zero disables recording.
+	Interval time.Duration
+
+	// Retention is how far back samples are kept in memory. Zero means
+	// DefaultRetention.
+	Retention time.Duration
+
+	// File, if set, is a CSV file every sample is appended to.
+	File string
+}
+
+// header is the first line of a new sample file.
+var header = []string{"sample_time", "inst_id", "con_id", "sid", "serial#", "username", "sql_id", "session_state", "wait_class", "event"}
+
+// Recorder samples a Source's active sessions on an interval into a ring
+// buffer covering the retention period. It samples the whole database;
+// readers narrow the samples to their own db.Scope. It is safe for
+// concurrent use.
+type Recorder struct {
+	src      db.Source
+	interval time.Duration
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	file *os.File
+	out  *csv.Writer
+
+	mu      sync.Mutex
+	ticks   []tick // ring buffer, oldest at next once full
+	next    int
+	lastErr error
+}
+
+// tick is the result of one sample.
+type tick struct {
+	at   time.Time
+	rows []models.ASHSample
+}
+
+// Start begins sampling src as configured by opts. It returns nil, and no
+// error, when opts.Interval is zero.
+func Start(src db.Source, opts Options) (*Recorder, error) {
+	if opts.Interval <= 0 {
+		return nil, nil
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = DefaultRetention
+	}
+	r := &Recorder{
+		src:      src,
+		interval: opts.Interval,
+		done:     make(chan struct{}),
+		ticks:    make([]tick, 0, max(int(opts.Retention/opts.Interval), 1)),
+	}
+	if opts.File != "" {
+		if err := r.openFile(opts.File); err != nil {
+			return nil, err
+		}
+	}
+	var ctx context.Context
+	ctx, r.cancel = context.WithCancel(context.Background())
+	go r.run(ctx)
+	return r, nil
+}
+
+// openFile opens path for appending, writing the header if it is new.
+func (r *Recorder) openFile(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("ash file: %w", err)
+	}
+	r.file = f
+	r.out = csv.NewWriter(f)
+	if fi, err := f.Stat(); err == nil && fi.Size() == 0 {
+		r.out.Write(header)
+	}
+	return nil
+}
+
+// Interval returns the time between samples.
+func (r *Recorder) Interval() time.Duration { return r.interval }
+
+// Since returns every sample taken at or after t, oldest first.
+func (r *Recorder) Since(t time.Time) []models.ASHSample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []models.ASHSample
+	n := len(r.ticks)
+	for i := range n {
+		tk := r.ticks[(r.next+i)%n]
+		if !tk.at.Before(t) {
+			out = append(out, tk.rows...)
+		}
+	}
+	return out
+}
+
+// Err returns the error of the latest sample, or nil if it succeeded.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastErr
+}
+
+// Close stops sampling and closes the sample file.
+func (r *Recorder) Close() error {
+	r.cancel()
+	<-r.done
+	if r.file == nil {
+		return nil
+	}
+	r.out.Flush()
+	if err := r.out.Error(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+// run samples immediately and then once per interval until ctx is done.
+func (r *Recorder) run(ctx context.Context) {
+	defer close(r.done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		rows, err := r.src.SampleActiveSessions(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		r.record(time.Now(), rows, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// record stores one sample. A failed sample is skipped, leaving a gap.
+func (r *Recorder) record(at time.Time, rows []models.ASHSample, err error) {
+	for i := range rows {
+		rows[i].Interval = r.interval
+	}
+	r.mu.Lock()
+	r.lastErr = err
+	if err == nil {
+		if len(r.ticks) < cap(r.ticks) {
+			r.ticks = append(r.ticks, tick{at: at, rows: rows})
+		} else {
+			r.ticks[r.next] = tick{at: at, rows: rows}
+			r.next = (r.next + 1) % len(r.ticks)
+		}
+	}
+	r.mu.Unlock()
+
+	if err != nil || r.out == nil {
+		return
+	}
+	for _, a := range rows {
+		r.out.Write([]string{
+			a.SampleTime.Format(time.RFC3339),
+			strconv.Itoa(a.InstID),
+			strconv.Itoa(a.ConID),
+			strconv.Itoa(a.SID),
+			strconv.Itoa(a.Serial),
+			a.Username,
+			a.SQLID,
+			a.State,
+			a.WaitClass,
+			a.Event,
+		})
+	}
+	r.out.Flush()
+	if err := r.out.Error(); err != nil {
+		r.mu.Lock()
+		r.lastErr = fmt.Errorf("ash file: %w", err)
+		r.mu.Unlock()
+	}
+}
@@ -0,0 +1,171 @@
+// Package audit guards the actions otop takes that change a database, as
+// opposed to reading it, and keeps a local record of every one.
+//
+// The log is a file of JSON lines, appended to and never rewritten, so it
+// can be tailed or shipped to a central store. Each action gets two
+// entries: one when it is attempted, written before it runs, and one with
+// its outcome. An attempt without an outcome is an action otop did not see
+// through, because it hung or otop died.
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrReadOnly is returned for every action while otop runs read-only.
+var ErrReadOnly = errors.New("read-only mode: actions that change the database are disabled")
+
+// Outcomes recorded in Entry.Outcome.
+const (
+	OutcomeAttempted = "attempted"
+	OutcomeOK        = "ok"
+	OutcomeFailed    = "failed"
+)
+
+// Entry is one line of the audit log.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	OSUser  string    `json:"os_user"`
+	Target  string    `json:"target"`
+	Action  string    `json:"action"`  // the statement run, e.g. ALTER SYSTEM KILL SESSION ...
+	Subject string    `json:"subject"` // what it was run against, for readers of the log
+	Outcome string    `json:"outcome"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Log decides whether an action may run and records that it did. It is
+// shared by every target and safe for concurrent use. A nil Log refuses
+// every action.
+type Log struct {
+	path     string
+	readOnly bool
+	osUser   string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// DefaultPath returns otop/audit.log under $XDG_STATE_HOME or
+// ~/.local/state, or "" if there is no home directory to put it in. Callers
+// must not take that for a choice to keep no record.
+func DefaultPath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "otop", "audit.log")
+}
+
+// New returns a Log appending to the file at path, which is created on the
+// first action. An empty path keeps no record. If readOnly is set, every
+// action is refused.
+func New(path string, readOnly bool) *Log {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	return &Log{path: path, readOnly: readOnly, osUser: name}
+}
+
+// ReadOnly reports whether actions are refused.
+func (l *Log) ReadOnly() bool {
+	return l == nil || l.readOnly
+}
+
+// Do runs fn, the action described by action and subject against target,
+// unless otop is read-only. It appends the attempt to the log before fn
+// runs and the outcome after. An action is never run if its attempt cannot
+// be recorded.
+func (l *Log) Do(target, action, subject string, fn func() error) error {
+	if l.ReadOnly() {
+		return ErrReadOnly
+	}
+	e := Entry{OSUser: l.osUser, Target: target, Action: action, Subject: subject, Outcome: OutcomeAttempted}
+	l.mu.Lock()
+	err := l.open()
+	if err == nil {
+		err = l.write(e)
+	}
+	l.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// fn may run for long; other actions need not wait for it.
+	err = fn()
+	e.Outcome = OutcomeOK
+	if err != nil {
+		e.Outcome, e.Error = OutcomeFailed, err.Error()
+	}
+	l.mu.Lock()
+	werr := l.write(e)
+	l.mu.Unlock()
+	if werr != nil && err == nil {
+		return fmt.Errorf("done, but %w", werr)
+	}
+	return err
+}
+
+// open opens the log file for appending if it is not open yet, creating it
+// and its directory readable only by the user. Callers must hold l.mu.
+func (l *Log) open() error {
+	if l.f != nil || l.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	l.f = f
+	return nil
+}
+
+// write appends e to the log, stamped with the current time, and syncs it
+// to disk, so that it survives otop crashing. Callers must hold l.mu and
+// have opened the log.
+func (l *Log) write(e Entry) error {
+	if l.f == nil {
+		return nil // no path: keep no record
+	}
+	e.Time = time.Now()
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("audit log: %w", err)
+	}
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.f == nil {
+		return nil
+	}
+	err := l.f.Close()
+	l.f = nil
+	return err
+}
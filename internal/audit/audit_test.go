@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// entries reads back every line of the log at path.
+func entries(t *testing.T, path string) []Entry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out []Entry
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func TestDoRecordsTheAttemptBeforeRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otop", "audit.log")
+	l := New(path, false)
+	defer l.Close()
+
+	ran := false
+	err := l.Do("prod", "ALTER SYSTEM KILL SESSION '1,2,@1' IMMEDIATE", "SCOTT", func() error {
+		ran = true
+		// A crash here must leave the attempt on disk.
+		got := entries(t, path)
+		if len(got) != 1 || got[0].Outcome != OutcomeAttempted {
+			t.Errorf("while running: log = %+v, want one attempted entry", got)
+		}
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatalf("Do = %v, ran %v", err, ran)
+	}
+
+	failure := errors.New("ORA-00030: User session ID does not exist.")
+	if err := l.Do("prod", "ALTER SYSTEM KILL SESSION '3,4,@1' IMMEDIATE", "SCOTT", func() error { return failure }); err != failure {
+		t.Fatalf("Do = %v, want %v", err, failure)
+	}
+
+	got := entries(t, path)
+	want := []struct{ action, outcome, err string }{
+		{"ALTER SYSTEM KILL SESSION '1,2,@1' IMMEDIATE", OutcomeAttempted, ""},
+		{"ALTER SYSTEM KILL SESSION '1,2,@1' IMMEDIATE", OutcomeOK, ""},
+		{"ALTER SYSTEM KILL SESSION '3,4,@1' IMMEDIATE", OutcomeAttempted, ""},
+		{"ALTER SYSTEM KILL SESSION '3,4,@1' IMMEDIATE", OutcomeFailed, failure.Error()},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("log has %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if g := got[i]; g.Action != w.action || g.Outcome != w.outcome || g.Error != w.err || g.Target != "prod" {
+			t.Errorf("entry %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestDoRefusesWhenReadOnlyOrUnrecordable(t *testing.T) {
+	run := func() error {
+		t.Error("action ran")
+		return nil
+	}
+	if err := New(filepath.Join(t.TempDir(), "audit.log"), true).Do("prod", "x", "y", run); err != ErrReadOnly {
+		t.Errorf("read-only Do = %v, want ErrReadOnly", err)
+	}
+	var nilLog *Log
+	if err := nilLog.Do("prod", "x", "y", run); err != ErrReadOnly {
+		t.Errorf("nil Log Do = %v, want ErrReadOnly", err)
+	}
+
+	// The log's directory cannot be created under a regular file.
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(filepath.Join(file, "audit.log"), false).Do("prod", "x", "y", run); err == nil {
+		t.Error("Do with an unwritable log succeeded")
+	}
+}
@@ -0,0 +1,324 @@
+// Package config loads named connection profiles from otop's YAML config
+// file and turns them into godror connection strings. The file also chooses
+// the metrics the metrics panel shows.
+//
+// Profiles never hold passwords: a password is read from the environment
+// variable named by password_env, or prompted for, so config files can be
+// shared within a team.
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/godror/godror"
+	"gopkg.in/yaml.v3"
+)
+
+// Environment variables consulted when the corresponding flag is not given.
+const (
+	EnvConfig  = "OTOP_CONFIG"  // path of the config file
+	EnvProfile = "OTOP_PROFILE" // profile to connect with
+)
+
+// ErrNoConfig is returned by Load when the config file does not exist.
+var ErrNoConfig = errors.New("config file not found")
+
+// Config is the contents of the config file.
+type Config struct {
+	// Default names the profile used when none is selected.
+	Default string `yaml:"default"`
+
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// Metrics chooses what the metrics panel shows, for every profile
+	// that does not choose its own. Unset, it shows DefaultMetrics.
+	Metrics []Metric `yaml:"metrics"`
+}
+
+// Metric is a V$SYSMETRIC metric for the metrics panel, with the values at
+// which its gauge turns yellow and red. A Warn above Crit means lower
+// values are worse, as for a hit ratio.
+type Metric struct {
+	Name  string  `yaml:"name"`  // METRIC_NAME, e.g. "Host CPU Utilization (%)"
+	Label string  `yaml:"label"` // shown in place of Name if set
+	Max   float64 `yaml:"max"`   // full scale of the gauge; 0 scales to the highest value seen
+	Warn  float64 `yaml:"warn"`  // 0 for no threshold
+	Crit  float64 `yaml:"crit"`  // 0 for no threshold
+}
+
+// DefaultMetrics are the metrics shown when the config file chooses none.
+var DefaultMetrics = []Metric{
+	{Name: "Host CPU Utilization (%)", Label: "Host CPU %", Max: 100, Warn: 80, Crit: 95},
+	{Name: "CPU Usage Per Sec", Label: "DB CPU cs/s"},
+	{Name: "Average Active Sessions", Label: "Active sessions"},
+	{Name: "Logical Reads Per Sec", Label: "Logical reads/s"},
+	{Name: "Physical Reads Per Sec", Label: "Physical reads/s"},
+	{Name: "Redo Generated Per Sec", Label: "Redo bytes/s"},
+	{Name: "User Calls Per Sec", Label: "User calls/s"},
+	{Name: "Total Parse Count Per Sec", Label: "Parses/s"},
+	{Name: "Hard Parse Count Per Sec", Label: "Hard parses/s", Warn: 100, Crit: 500},
+	{Name: "User Commits Per Sec", Label: "Commits/s"},
+}
+
+// Profile describes one database connection. Exactly one of Host or TNS
+// identifies the database.
+type Profile struct {
+	User string `yaml:"user"`
+
+	// Host, Port and Service form an Easy Connect string.
+	Host    string `yaml:"host"`
+	Port    int    `yaml:"port"`
+	Service string `yaml:"service"`
+
+	// TNS is a net service name resolved through tnsnames.ora, e.g. in
+	// TNSAdmin or the wallet directory.
+	TNS      string `yaml:"tns"`
+	TNSAdmin string `yaml:"tns_admin"`
+
+	// Wallet is a wallet directory holding cwallet.sso together with its
+	// sqlnet.ora and tnsnames.ora. It takes the place of TNSAdmin.
+	Wallet string `yaml:"wallet"`
+
+	// ExternalAuth authenticates with the wallet's stored credentials or
+	// the operating system user instead of a password.
+	ExternalAuth bool `yaml:"external_auth"`
+
+	// PasswordEnv names an environment variable holding the password.
+	// If it is unset or empty, the password is prompted for.
+	PasswordEnv string `yaml:"password_env"`
+
+	// Cluster and Timeout default the -cluster and -timeout flags.
+	Cluster bool          `yaml:"cluster"`
+	Timeout time.Duration `yaml:"timeout"`
+
+	// AllowDML lets the query editor be switched to read-write against
+	// this database, to run DML and DDL. It defaults the -allow-dml flag.
+	AllowDML bool `yaml:"allow_dml"`
+
+	// Metrics, if set, replaces the file's metrics for this database.
+	Metrics []Metric `yaml:"metrics"`
+}
+
+// DefaultPath returns the config file location: $OTOP_CONFIG if set,
+// otherwise otop/config.yaml under $XDG_CONFIG_HOME or ~/.config.
+func DefaultPath() string {
+	if p := os.Getenv(EnvConfig); p != "" {
+		return p
+	}
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "otop", "config.yaml")
+}
+
+// Load reads and validates the config file at path. String values may
+// reference environment variables as $VAR or ${VAR}, and paths may start
+// with ~/.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", path, ErrNoConfig)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	if err := validateMetrics(cfg.Metrics); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	for name, p := range cfg.Profiles {
+		p.expand()
+		if err := p.validate(); err != nil {
+			return nil, fmt.Errorf("config %s: profile %q: %w", path, name, err)
+		}
+		cfg.Profiles[name] = p
+	}
+	if cfg.Default != "" {
+		if _, ok := cfg.Profiles[cfg.Default]; !ok {
+			return nil, fmt.Errorf("config %s: default profile %q is not defined", path, cfg.Default)
+		}
+	}
+	return &cfg, nil
+}
+
+// MetricsFor returns the metrics to show for p: its own, else the file's,
+// else DefaultMetrics.
+func (c *Config) MetricsFor(p Profile) []Metric {
+	switch {
+	case len(p.Metrics) > 0:
+		return p.Metrics
+	case len(c.Metrics) > 0:
+		return c.Metrics
+	}
+	return DefaultMetrics
+}
+
+// Names returns the profile names in sorted order.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the named profile. An empty name selects $OTOP_PROFILE,
+// then the configured default, then the only profile if there is just one.
+func (c *Config) Profile(name string) (string, Profile, error) {
+	if name == "" {
+		name = os.Getenv(EnvProfile)
+	}
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" && len(c.Profiles) == 1 {
+		name = c.Names()[0]
+	}
+	if name == "" {
+		return "", Profile{}, fmt.Errorf("no profile selected; choose one of %s with -profile", strings.Join(c.Names(), ", "))
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return "", Profile{}, fmt.Errorf("unknown profile %q; defined profiles: %s", name, strings.Join(c.Names(), ", "))
+	}
+	return name, p, nil
+}
+
+// DSN returns the godror connection string for p. prompt is called for the
+// password when the profile neither uses external authentication nor finds
+// its password in the environment.
+func (p Profile) DSN(prompt func(label string) (string, error)) (string, error) {
+	params, err := godror.ParseDSN("")
+	if err != nil {
+		return "", fmt.Errorf("dsn: %w", err)
+	}
+	params.ConnectString = p.connectString()
+	params.ConfigDir = p.TNSAdmin
+	if p.Wallet != "" {
+		params.ConfigDir = p.Wallet
+	}
+	if p.ExternalAuth {
+		// With external auth, User is only set for proxy authentication
+		// and takes the form [user].
+		params.ExternalAuth = sql.NullBool{Valid: true, Bool: true}
+		params.Username = p.User
+		return params.StringWithPassword(), nil
+	}
+
+	params.Username = p.User
+	password := ""
+	if p.PasswordEnv != "" {
+		password = os.Getenv(p.PasswordEnv)
+	}
+	if password == "" {
+		if password, err = prompt(fmt.Sprintf("Password for %s@%s: ", p.User, params.ConnectString)); err != nil {
+			return "", fmt.Errorf("password: %w", err)
+		}
+	}
+	params.Password = godror.NewPassword(password)
+	return params.StringWithPassword(), nil
+}
+
+// WithPassword fills in a missing password in a -conn connection string by
+// calling prompt, so the password need not appear on the command line.
+func WithPassword(connStr string, prompt func(label string) (string, error)) (string, error) {
+	params, err := godror.ParseDSN(connStr)
+	if err != nil {
+		return "", fmt.Errorf("dsn: %w", err)
+	}
+	if params.Username == "" || !params.Password.IsZero() ||
+		(params.ExternalAuth.Valid && params.ExternalAuth.Bool) {
+		return connStr, nil
+	}
+	password, err := prompt(fmt.Sprintf("Password for %s@%s: ", params.Username, params.ConnectString))
+	if err != nil {
+		return "", fmt.Errorf("password: %w", err)
+	}
+	params.Password = godror.NewPassword(password)
+	return params.StringWithPassword(), nil
+}
+
+// Describe returns a password-free name for a connection string, such as
+// scott@db.example.com:1521/ORCL.
+func Describe(connStr string) string {
+	params, err := godror.ParseDSN(connStr)
+	if err != nil {
+		return "database"
+	}
+	if params.Username == "" {
+		return params.ConnectString
+	}
+	return params.Username + "@" + params.ConnectString
+}
+
+// connectString returns the TNS alias, or an Easy Connect string built from
+// Host, Port and Service.
+func (p Profile) connectString() string {
+	if p.TNS != "" {
+		return p.TNS
+	}
+	s := p.Host
+	if p.Port != 0 {
+		s = fmt.Sprintf("%s:%d", s, p.Port)
+	}
+	if p.Service != "" {
+		s += "/" + p.Service
+	}
+	return s
+}
+
+func (p *Profile) expand() {
+	for _, s := range []*string{&p.User, &p.Host, &p.Service, &p.TNS, &p.PasswordEnv} {
+		*s = os.ExpandEnv(*s)
+	}
+	for _, s := range []*string{&p.TNSAdmin, &p.Wallet} {
+		*s = expandPath(os.ExpandEnv(*s))
+	}
+}
+
+func (p Profile) validate() error {
+	switch {
+	case p.Host == "" && p.TNS == "":
+		return errors.New("one of host or tns is required")
+	case p.Host != "" && p.TNS != "":
+		return errors.New("host and tns are mutually exclusive")
+	case p.User == "" && !p.ExternalAuth:
+		return errors.New("user is required unless external_auth is set")
+	}
+	return validateMetrics(p.Metrics)
+}
+
+func validateMetrics(metrics []Metric) error {
+	for i, m := range metrics {
+		if m.Name == "" {
+			return fmt.Errorf("metric %d: name is required", i+1)
+		}
+	}
+	return nil
+}
+
+// expandPath replaces a leading ~/ with the user's home directory.
+func expandPath(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
@@ -0,0 +1,315 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/godror/godror"
+)
+
+// writeConfig writes data to a config file in a new directory and returns
+// its path.
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	t.Setenv("HOME", "/home/scott")
+	t.Setenv("OTOP_TEST_HOST", "db.example.com")
+	t.Setenv("OTOP_TEST_WALLETS", "/etc/wallets")
+
+	cfg, err := Load(writeConfig(t, `
+default: prod
+metrics:
+  - name: Host CPU Utilization (%)
+    max: 100
+profiles:
+  prod:
+    user: scott
+    host: $OTOP_TEST_HOST
+    port: 1521
+    service: ORCL
+    password_env: PROD_PASSWORD
+    timeout: 30s
+  cloud:
+    tns: prod_high
+    wallet: ${OTOP_TEST_WALLETS}/prod
+    external_auth: true
+    metrics:
+      - name: Average Active Sessions
+  dev:
+    user: app
+    tns: dev
+    tns_admin: ~/tns
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(cfg.Names(), ","); got != "cloud,dev,prod" {
+		t.Errorf("Names = %s, want cloud,dev,prod", got)
+	}
+	if p := cfg.Profiles["prod"]; p.Host != "db.example.com" || p.Timeout.Seconds() != 30 {
+		t.Errorf("prod = %+v, want host db.example.com and a 30s timeout", p)
+	}
+	if p := cfg.Profiles["cloud"]; p.Wallet != "/etc/wallets/prod" {
+		t.Errorf("cloud wallet = %q, want /etc/wallets/prod", p.Wallet)
+	}
+	if p := cfg.Profiles["dev"]; p.TNSAdmin != "/home/scott/tns" {
+		t.Errorf("dev tns_admin = %q, want /home/scott/tns", p.TNSAdmin)
+	}
+	if m := cfg.MetricsFor(cfg.Profiles["cloud"]); len(m) != 1 || m[0].Name != "Average Active Sessions" {
+		t.Errorf("MetricsFor(cloud) = %+v, want the profile's own", m)
+	}
+	if m := cfg.MetricsFor(cfg.Profiles["prod"]); len(m) != 1 || m[0].Name != "Host CPU Utilization (%)" {
+		t.Errorf("MetricsFor(prod) = %+v, want the file's", m)
+	}
+	if m := (&Config{}).MetricsFor(Profile{}); len(m) != len(DefaultMetrics) {
+		t.Errorf("MetricsFor with none chosen = %+v, want DefaultMetrics", m)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, ErrNoConfig) {
+		t.Errorf("Load of a missing file = %v, want ErrNoConfig", err)
+	}
+
+	tests := []struct {
+		name, data, want string
+	}{
+		{"not yaml", "profiles: [", "yaml"},
+		{"no host or tns", "profiles:\n  p:\n    user: scott\n", "one of host or tns is required"},
+		{"host and tns", "profiles:\n  p:\n    user: scott\n    host: db\n    tns: prod\n", "mutually exclusive"},
+		{"no user", "profiles:\n  p:\n    host: db\n", "user is required"},
+		{"external auth needs no user", "default: p\nprofiles:\n  p:\n    tns: prod\n    external_auth: true\n", ""},
+		{"unnamed metric", "metrics:\n  - label: CPU\n", "metric 1: name is required"},
+		{"unnamed profile metric", "profiles:\n  p:\n    user: scott\n    host: db\n    metrics:\n      - name: x\n      - max: 1\n", `profile "p": metric 2`},
+		{"undefined default", "default: q\nprofiles:\n  p:\n    user: scott\n    host: db\n", `default profile "q" is not defined`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.data))
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("Load = %v, want no error", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("Load = %v, want an error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfile(t *testing.T) {
+	two := &Config{Default: "prod", Profiles: map[string]Profile{
+		"prod": {Host: "prod"},
+		"dev":  {Host: "dev"},
+	}}
+	one := &Config{Profiles: map[string]Profile{"only": {Host: "only"}}}
+	tests := []struct {
+		name    string
+		cfg     *Config
+		env     string // $OTOP_PROFILE
+		arg     string
+		want    string
+		wantErr string
+	}{
+		{name: "named", cfg: two, arg: "dev", want: "dev"},
+		{name: "named beats the environment", cfg: two, env: "prod", arg: "dev", want: "dev"},
+		{name: "environment beats the default", cfg: two, env: "dev", want: "dev"},
+		{name: "default", cfg: two, want: "prod"},
+		{name: "the only profile", cfg: one, want: "only"},
+		{name: "none selected", cfg: &Config{Profiles: two.Profiles}, wantErr: "no profile selected; choose one of dev, prod with -profile"},
+		{name: "unknown", cfg: two, arg: "test", wantErr: `unknown profile "test"; defined profiles: dev, prod`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(EnvProfile, tt.env)
+			name, p, err := tt.cfg.Profile(tt.arg)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Profile(%q) = %v, want error %q", tt.arg, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || name != tt.want || p.Host != tt.want {
+				t.Errorf("Profile(%q) = %q, %+v, %v, want %q", tt.arg, name, p, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDSN(t *testing.T) {
+	t.Setenv("OTOP_TEST_PASSWORD", "from-env")
+	t.Setenv("OTOP_TEST_EMPTY", "")
+	tests := []struct {
+		name      string
+		profile   Profile
+		wantUser  string
+		wantPass  string
+		wantConn  string
+		wantDir   string
+		wantExt   bool
+		wantLabel string // "" if the password must not be prompted for
+	}{
+		{
+			name:      "easy connect, prompted",
+			profile:   Profile{User: "scott", Host: "db.example.com", Port: 1521, Service: "ORCL"},
+			wantUser:  "scott",
+			wantPass:  "typed",
+			wantConn:  "db.example.com:1521/ORCL",
+			wantLabel: "Password for scott@db.example.com:1521/ORCL: ",
+		},
+		{
+			name:     "host only",
+			profile:  Profile{User: "scott", Host: "db", PasswordEnv: "OTOP_TEST_PASSWORD"},
+			wantUser: "scott",
+			wantPass: "from-env",
+			wantConn: "db",
+		},
+		{
+			name:     "tns with tns_admin",
+			profile:  Profile{User: "app", TNS: "prod", TNSAdmin: "/etc/tns", PasswordEnv: "OTOP_TEST_PASSWORD"},
+			wantUser: "app",
+			wantPass: "from-env",
+			wantConn: "prod",
+			wantDir:  "/etc/tns",
+		},
+		{
+			name:      "empty password_env is prompted for",
+			profile:   Profile{User: "app", TNS: "prod", PasswordEnv: "OTOP_TEST_EMPTY"},
+			wantUser:  "app",
+			wantPass:  "typed",
+			wantConn:  "prod",
+			wantLabel: "Password for app@prod: ",
+		},
+		{
+			name:     "wallet takes the place of tns_admin",
+			profile:  Profile{TNS: "prod_high", TNSAdmin: "/etc/tns", Wallet: "/etc/wallet", ExternalAuth: true},
+			wantConn: "prod_high",
+			wantDir:  "/etc/wallet",
+			wantExt:  true,
+		},
+		{
+			name:     "external auth with a proxy user",
+			profile:  Profile{User: "[app]", TNS: "prod", ExternalAuth: true, PasswordEnv: "OTOP_TEST_PASSWORD"},
+			wantUser: "[app]",
+			wantConn: "prod",
+			wantExt:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			label := ""
+			dsn, err := tt.profile.DSN(func(l string) (string, error) {
+				label = l
+				return "typed", nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if label != tt.wantLabel {
+				t.Errorf("prompted with %q, want %q", label, tt.wantLabel)
+			}
+			params, err := godror.ParseDSN(dsn)
+			if err != nil {
+				t.Fatalf("ParseDSN(%q): %v", dsn, err)
+			}
+			ext := params.ExternalAuth.Valid && params.ExternalAuth.Bool
+			if params.Username != tt.wantUser || params.Password.Secret() != tt.wantPass ||
+				params.ConnectString != tt.wantConn || params.ConfigDir != tt.wantDir || ext != tt.wantExt {
+				t.Errorf("DSN = %q, want user %q, password %q, connect string %q, config dir %q, external auth %v",
+					dsn, tt.wantUser, tt.wantPass, tt.wantConn, tt.wantDir, tt.wantExt)
+			}
+		})
+	}
+
+	failure := errors.New("no terminal")
+	_, err := Profile{User: "scott", Host: "db"}.DSN(func(string) (string, error) { return "", failure })
+	if !errors.Is(err, failure) {
+		t.Errorf("DSN with a failing prompt = %v, want %v", err, failure)
+	}
+}
+
+func TestWithPassword(t *testing.T) {
+	tests := []struct {
+		name, conn string
+		prompted   bool
+		wantPass   string
+	}{
+		{"missing", "scott@db:1521/ORCL", true, "typed"},
+		{"given", "scott/tiger@db:1521/ORCL", false, "tiger"},
+		{"no user", "/@prod", false, ""},
+		{"external auth", "user=scott connectString=prod externalAuth=1", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompted := false
+			got, err := WithPassword(tt.conn, func(string) (string, error) {
+				prompted = true
+				return "typed", nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prompted != tt.prompted {
+				t.Errorf("prompted = %v, want %v", prompted, tt.prompted)
+			}
+			if !tt.prompted && got != tt.conn {
+				t.Errorf("WithPassword(%q) = %q, want it unchanged", tt.conn, got)
+			}
+			params, err := godror.ParseDSN(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if params.Password.Secret() != tt.wantPass {
+				t.Errorf("WithPassword(%q) has password %q, want %q", tt.conn, params.Password.Secret(), tt.wantPass)
+			}
+		})
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		conn, want string
+	}{
+		{"scott/tiger@db.example.com:1521/ORCL", "scott@db.example.com:1521/ORCL"},
+		{"scott@db.example.com:1521/ORCL", "scott@db.example.com:1521/ORCL"},
+		{"user=scott password=tiger connectString=prod", "scott@prod"},
+		{"/@prod_high", "prod_high"},
+	}
+	for _, tt := range tests {
+		got := Describe(tt.conn)
+		if got != tt.want {
+			t.Errorf("Describe(%q) = %q, want %q", tt.conn, got, tt.want)
+		}
+		if strings.Contains(got, "tiger") {
+			t.Errorf("Describe(%q) = %q shows the password", tt.conn, got)
+		}
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	t.Setenv("HOME", "/home/scott")
+	tests := []struct {
+		path, want string
+	}{
+		{"~/wallet", "/home/scott/wallet"},
+		{"~/a/../b", "/home/scott/b"},
+		{"/etc/wallet", "/etc/wallet"},
+		{"~scott/wallet", "~scott/wallet"},
+		{"wallet/~/x", "wallet/~/x"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := expandPath(tt.path); got != tt.want {
+			t.Errorf("expandPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
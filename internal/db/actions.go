@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// KillMode selects how KillSession ends a session.
+type KillMode int
+
+const (
+	// KillImmediate rolls the session's transaction back and ends it at
+	// once: ALTER SYSTEM KILL SESSION ... IMMEDIATE.
+	KillImmediate KillMode = iota
+
+	// DisconnectPostTransaction lets the session's current transaction
+	// finish, then drops its server process.
+	DisconnectPostTransaction
+
+	// DisconnectImmediate drops the session's server process at once,
+	// rolling back any open transaction.
+	DisconnectImmediate
+)
+
+func (m KillMode) String() string {
+	switch m {
+	case DisconnectPostTransaction:
+		return "disconnect post-transaction"
+	case DisconnectImmediate:
+		return "disconnect immediate"
+	default:
+		return "kill immediate"
+	}
+}
+
+// SessionKiller is implemented by sources that can end other sessions.
+// Unlike Source, it changes the database: callers are expected to confirm
+// with the user and record what they did.
+type SessionKiller interface {
+	// KillSession ends session s as mode says.
+	KillSession(ctx context.Context, s models.Session, mode KillMode) error
+}
+
+var _ SessionKiller = (*DB)(nil)
+
+// KillStatement returns the statement that ends s as mode says. Naming the
+// instance lets it reach sessions on any RAC instance.
+func KillStatement(s models.Session, mode KillMode) string {
+	session := fmt.Sprintf("'%d,%d,@%d'", s.SID, s.Serial, s.InstID)
+	switch mode {
+	case DisconnectPostTransaction:
+		return "ALTER SYSTEM DISCONNECT SESSION " + session + " POST_TRANSACTION"
+	case DisconnectImmediate:
+		return "ALTER SYSTEM DISCONNECT SESSION " + session + " IMMEDIATE"
+	default:
+		return "ALTER SYSTEM KILL SESSION " + session + " IMMEDIATE"
+	}
+}
+
+// KillSession ends session s with ALTER SYSTEM KILL or DISCONNECT SESSION.
+// The connecting user needs the ALTER SYSTEM privilege.
+func (db *DB) KillSession(ctx context.Context, s models.Session, mode KillMode) error {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return fmt.Errorf("KillSession: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, KillStatement(s, mode)); err != nil {
+		return db.observe(fmt.Errorf("KillSession: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// GetBinds returns the bind variables of the given SQL ID's child cursor,
+// or of its lowest-numbered child cursor (on the lowest-numbered instance
+// in cluster mode) if child is nil. Captured values come from
+// V$SQL_BIND_CAPTURE, which samples them at most every 15 minutes; peeked
+// values are decoded from the plan's OTHER_XML.
+func (db *DB) GetBinds(ctx context.Context, sqlID string, child *models.ChildCursor) ([]models.Bind, error) {
+	const pick = `
+WITH pick AS (
+    SELECT INST_ID, CON_ID, CHILD_NUMBER
+    FROM (
+        SELECT INST_ID, CON_ID, CHILD_NUMBER
+        FROM   GV$SQL
+        WHERE  SQL_ID = :sqlid
+          AND  (:inst = 0 OR INST_ID = :inst)
+          AND  (:con  = 0 OR CON_ID  = :con)
+          AND  (:child < 0 OR (INST_ID = :child_inst AND CON_ID = :child_con AND CHILD_NUMBER = :child))
+        ORDER BY INST_ID, CHILD_NUMBER
+    )
+    WHERE ROWNUM = 1
+)`
+	const captured = pick + `
+SELECT
+    b.POSITION,
+    NVL(b.NAME, '')            AS NAME,
+    NVL(b.DATATYPE_STRING, '') AS DATATYPE,
+    b.WAS_CAPTURED,
+    -- VALUE_STRING shows dates in the session's NLS format. Local time
+    -- zone timestamps are given the session's offset, so that they still
+    -- name the same instant.
+    CASE
+        WHEN b.DATATYPE_STRING = 'DATE'
+            THEN TO_CHAR(ANYDATA.ACCESSDATE(b.VALUE_ANYDATA), 'YYYY-MM-DD HH24:MI:SS')
+        WHEN b.DATATYPE_STRING LIKE 'TIMESTAMP%WITH LOCAL TIME ZONE'
+            THEN TO_CHAR(CAST(ANYDATA.ACCESSTIMESTAMPLTZ(b.VALUE_ANYDATA) AS TIMESTAMP WITH TIME ZONE), 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM')
+        WHEN b.DATATYPE_STRING LIKE 'TIMESTAMP%WITH TIME ZONE'
+            THEN TO_CHAR(ANYDATA.ACCESSTIMESTAMPTZ(b.VALUE_ANYDATA), 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM')
+        WHEN b.DATATYPE_STRING LIKE 'TIMESTAMP%'
+            THEN TO_CHAR(ANYDATA.ACCESSTIMESTAMP(b.VALUE_ANYDATA), 'YYYY-MM-DD HH24:MI:SS.FF')
+        ELSE b.VALUE_STRING
+    END                        AS VALUE,
+    b.LAST_CAPTURED
+FROM GV$SQL_BIND_CAPTURE b
+JOIN pick
+  ON b.INST_ID      = pick.INST_ID
+ AND b.CON_ID       = pick.CON_ID
+ AND b.CHILD_NUMBER = pick.CHILD_NUMBER
+WHERE b.SQL_ID = :sqlid
+ORDER BY b.POSITION`
+	const peeked = pick + `
+SELECT p.OTHER_XML
+FROM GV$SQL_PLAN_STATISTICS_ALL p
+JOIN pick
+  ON p.INST_ID      = pick.INST_ID
+ AND p.CON_ID       = pick.CON_ID
+ AND p.CHILD_NUMBER = pick.CHILD_NUMBER
+WHERE p.SQL_ID = :sqlid
+  AND p.OTHER_XML IS NOT NULL
+  AND ROWNUM = 1`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetBinds: %w", err)
+	}
+	p := models.ChildCursor{ChildNumber: -1}
+	if child != nil {
+		p = *child
+	}
+	args := []any{
+		sql.Named("sqlid", sqlID), db.instance(ctx), container(ctx),
+		sql.Named("child", p.ChildNumber), sql.Named("child_inst", p.InstID), sql.Named("child_con", p.ConID),
+	}
+
+	rows, err := conn.QueryContext(ctx, captured, args...)
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetBinds: %w", err))
+	}
+	defer rows.Close()
+	var binds []models.Bind
+	for rows.Next() {
+		var b models.Bind
+		var wasCaptured string
+		var value sql.NullString
+		var at sql.NullTime
+		if err := rows.Scan(&b.Position, &b.Name, &b.Datatype, &wasCaptured, &value, &at); err != nil {
+			return nil, fmt.Errorf("GetBinds scan: %w", err)
+		}
+		if wasCaptured == "YES" {
+			b.Captured = &models.BindValue{Text: value.String, Null: !value.Valid}
+			b.CapturedAt = at.Time
+		}
+		binds = append(binds, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, db.observe(fmt.Errorf("GetBinds: %w", err))
+	}
+
+	// godror reads the CLOB into a string.
+	var otherXML string
+	err = conn.QueryRowContext(ctx, peeked, args...).Scan(&otherXML)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, db.observe(fmt.Errorf("GetBinds peeked: %w", err))
+	}
+	return mergePeekedBinds(binds, otherXML), nil
+}
+
+// otherXML is the part of V$SQL_PLAN.OTHER_XML that holds peeked binds:
+//
+//	<peeked_binds><bind nam=":1" pos="1" dty="2" pre="0" scl="0" mxl="22">c102</bind></peeked_binds>
+type otherXML struct {
+	Binds []struct {
+		Name     string `xml:"nam,attr"`
+		Position int    `xml:"pos,attr"`
+		Type     int    `xml:"dty,attr"`
+		Form     int    `xml:"frm,attr"` // 2 for the national character set
+		MaxLen   int    `xml:"mxl,attr"`
+		Value    string `xml:",chardata"`
+	} `xml:"peeked_binds>bind"`
+}
+
+// mergePeekedBinds adds the peeked binds in doc, an OTHER_XML document, to
+// binds, matching them by position.
+func mergePeekedBinds(binds []models.Bind, doc string) []models.Bind {
+	var x otherXML
+	if doc == "" || xml.Unmarshal([]byte(doc), &x) != nil {
+		return binds
+	}
+	for _, pb := range x.Binds {
+		i := -1
+		for j := range binds {
+			if binds[j].Position == pb.Position {
+				i = j
+			}
+		}
+		if i < 0 {
+			binds = append(binds, models.Bind{Position: pb.Position, Name: pb.Name, Datatype: datatypeName(pb.Type, pb.Form, pb.MaxLen)})
+			i = len(binds) - 1
+		}
+		binds[i].Peeked = decodeBind(pb.Type, pb.Form, strings.TrimSpace(pb.Value))
+	}
+	sort.Slice(binds, func(i, j int) bool { return binds[i].Position < binds[j].Position })
+	return binds
+}
+
+// datatypeName names an Oracle internal datatype code the way
+// V$SQL_BIND_CAPTURE.DATATYPE_STRING does.
+func datatypeName(dty, form, maxLen int) string {
+	national := ""
+	if form == 2 {
+		national = "N"
+	}
+	switch dty {
+	case 1:
+		return fmt.Sprintf("%sVARCHAR2(%d)", national, maxLen)
+	case 2:
+		return "NUMBER"
+	case 12:
+		return "DATE"
+	case 23:
+		return fmt.Sprintf("RAW(%d)", maxLen)
+	case 96:
+		return fmt.Sprintf("%sCHAR(%d)", national, maxLen)
+	case 100:
+		return "BINARY_FLOAT"
+	case 101:
+		return "BINARY_DOUBLE"
+	case 180:
+		return "TIMESTAMP"
+	}
+	return fmt.Sprintf("TYPE#%d", dty)
+}
+
+// decodeBind decodes a peeked value, hex of Oracle's internal format, to
+// text. A value it cannot decode is left as hex and marked Raw; an empty
+// value is NULL.
+func decodeBind(dty, form int, value string) *models.BindValue {
+	if value == "" {
+		return &models.BindValue{Null: true}
+	}
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return &models.BindValue{Text: value, Raw: true}
+	}
+	switch dty {
+	case 1, 96:
+		if form == 2 && len(b)%2 == 0 {
+			// The national character set is AL16UTF16.
+			units := make([]uint16, len(b)/2)
+			for i := range units {
+				units[i] = binary.BigEndian.Uint16(b[2*i:])
+			}
+			return &models.BindValue{Text: string(utf16.Decode(units))}
+		}
+		return &models.BindValue{Text: string(b)}
+	case 2:
+		if n, ok := decodeNumber(b); ok {
+			return &models.BindValue{Text: n}
+		}
+	case 12, 180:
+		if t, ok := decodeDate(b); ok {
+			if dty == 12 {
+				return &models.BindValue{Text: t.Format("2006-01-02 15:04:05")}
+			}
+			return &models.BindValue{Text: t.Format("2006-01-02 15:04:05.000000000")}
+		}
+	case 23:
+		return &models.BindValue{Text: strings.ToUpper(value)}
+	case 100, 101:
+		if f, ok := decodeBinaryFloat(b); ok {
+			return &models.BindValue{Text: f}
+		}
+	}
+	return &models.BindValue{Text: value, Raw: true}
+}
+
+// decodeNumber decodes Oracle's NUMBER format: an exponent byte followed by
+// base-100 digits, each stored plus one; negative numbers have both
+// complemented and end in 102.
+func decodeNumber(b []byte) (string, bool) {
+	if len(b) == 0 {
+		return "", false
+	}
+	if len(b) == 1 && b[0] == 0x80 {
+		return "0", true
+	}
+	negative := b[0]&0x80 == 0
+	var exp int
+	var digits strings.Builder
+	if negative {
+		exp = int(^b[0]&0x7f) - 65
+		rest := b[1:]
+		if len(rest) > 0 && rest[len(rest)-1] == 102 {
+			rest = rest[:len(rest)-1]
+		}
+		for _, d := range rest {
+			if d < 2 || d > 101 {
+				return "", false
+			}
+			fmt.Fprintf(&digits, "%02d", 101-int(d))
+		}
+	} else {
+		exp = int(b[0]&0x7f) - 65
+		for _, d := range b[1:] {
+			if d < 1 || d > 100 {
+				return "", false
+			}
+			fmt.Fprintf(&digits, "%02d", int(d)-1)
+		}
+	}
+	ds := digits.String()
+	if ds == "" {
+		return "", false
+	}
+
+	// The first base-100 digit is the one for 100^exp.
+	var whole, frac string
+	switch point := 2 * (exp + 1); {
+	case point <= 0:
+		whole, frac = "0", strings.Repeat("0", -point)+ds
+	case point >= len(ds):
+		whole = ds + strings.Repeat("0", point-len(ds))
+	default:
+		whole, frac = ds[:point], ds[point:]
+	}
+	whole = strings.TrimLeft(whole, "0")
+	if whole == "" {
+		whole = "0"
+	}
+	n := whole
+	if frac = strings.TrimRight(frac, "0"); frac != "" {
+		n += "." + frac
+	}
+	if negative {
+		n = "-" + n
+	}
+	return n, true
+}
+
+// decodeBinaryFloat decodes Oracle's BINARY_FLOAT and BINARY_DOUBLE
+// formats: big-endian IEEE 754 with the sign bit flipped for positive
+// numbers and every bit flipped for negative ones, so that the bytes sort
+// as the numbers do. Infinities and NaN, which have no literal, are not
+// decoded.
+func decodeBinaryFloat(b []byte) (string, bool) {
+	if len(b) != 4 && len(b) != 8 {
+		return "", false
+	}
+	ieee := make([]byte, len(b))
+	for i, c := range b {
+		if b[0]&0x80 != 0 {
+			ieee[i] = c
+		} else {
+			ieee[i] = ^c
+		}
+	}
+	ieee[0] ^= b[0] & 0x80
+	var f float64
+	bits := 64
+	if len(b) == 4 {
+		f, bits = float64(math.Float32frombits(binary.BigEndian.Uint32(ieee))), 32
+	} else {
+		f = math.Float64frombits(binary.BigEndian.Uint64(ieee))
+	}
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", false
+	}
+	return strconv.FormatFloat(f, 'g', -1, bits), true
+}
+
+// decodeDate decodes Oracle's DATE format, century and year of century
+// plus 100, month, day, and hour, minute and second plus one, followed for
+// TIMESTAMP by big-endian nanoseconds.
+func decodeDate(b []byte) (time.Time, bool) {
+	if len(b) < 7 {
+		return time.Time{}, false
+	}
+	year := (int(b[0])-100)*100 + int(b[1]) - 100
+	nanos := 0
+	if len(b) >= 11 {
+		nanos = int(binary.BigEndian.Uint32(b[7:11]))
+	}
+	return time.Date(year, time.Month(b[2]), int(b[3]), int(b[4])-1, int(b[5])-1, int(b[6])-1, nanos, time.UTC), true
+}
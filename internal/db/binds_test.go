@@ -0,0 +1,126 @@
+package db
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/mdoeren/otop/internal/models"
+)
+
+func unhex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecodeNumber(t *testing.T) {
+	tests := []struct {
+		hex, want string
+	}{
+		{"80", "0"},
+		{"c102", "1"},
+		{"c10b", "10"},
+		{"c202", "100"},
+		{"c20218", "123"},
+		{"c30b", "100000"},
+		{"c033", "0.5"},
+		{"c20d2333", "1234.5"},
+		{"be0b", "0.00001"},
+		{"3e6466", "-1"},
+		{"3e64", "-1"}, // a full-length negative number has no 102 trailer
+		{"3f3366", "-0.5"},
+		{"3d644e3866", "-123.45"},
+	}
+	for _, tt := range tests {
+		got, ok := decodeNumber(unhex(t, tt.hex))
+		if !ok || got != tt.want {
+			t.Errorf("decodeNumber(%s) = %q, %v, want %q", tt.hex, got, ok, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "c1", "c100", "c165", "3e01"} {
+		if got, ok := decodeNumber(unhex(t, bad)); ok {
+			t.Errorf("decodeNumber(%s) = %q, want failure", bad, got)
+		}
+	}
+}
+
+func TestDecodeDate(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want time.Time
+	}{
+		{"787c030f0e2e1f", time.Date(2024, 3, 15, 13, 45, 30, 0, time.UTC)},
+		{"77c70c1f183c3c", time.Date(1999, 12, 31, 23, 59, 59, 0, time.UTC)},
+		{"7878010101010100000000", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"787c030f0e2e1f075bcd15", time.Date(2024, 3, 15, 13, 45, 30, 123456789, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, ok := decodeDate(unhex(t, tt.hex))
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("decodeDate(%s) = %v, %v, want %v", tt.hex, got, ok, tt.want)
+		}
+	}
+	if got, ok := decodeDate(unhex(t, "787c03")); ok {
+		t.Errorf("decodeDate of 3 bytes = %v, want failure", got)
+	}
+}
+
+func TestDecodeBinaryFloat(t *testing.T) {
+	tests := []struct {
+		hex, want string
+	}{
+		{"bff0000000000000", "1"},
+		{"400fffffffffffff", "-1"},
+		{"bfb999999999999a", "0.1"},
+		{"8000000000000000", "0"},
+		{"bfc00000", "1.5"},
+		{"3fdfffff", "-2.5"},
+	}
+	for _, tt := range tests {
+		got, ok := decodeBinaryFloat(unhex(t, tt.hex))
+		if !ok || got != tt.want {
+			t.Errorf("decodeBinaryFloat(%s) = %q, %v, want %q", tt.hex, got, ok, tt.want)
+		}
+	}
+	// Infinity, NaN and odd lengths.
+	for _, bad := range []string{"fff0000000000000", "ff800000", "fff8000000000000", "bff000"} {
+		if got, ok := decodeBinaryFloat(unhex(t, bad)); ok {
+			t.Errorf("decodeBinaryFloat(%s) = %q, want failure", bad, got)
+		}
+	}
+}
+
+func TestDecodeBind(t *testing.T) {
+	tests := []struct {
+		name      string
+		dty, form int
+		value     string
+		want      models.BindValue
+	}{
+		{"null", 2, 1, "", models.BindValue{Null: true}},
+		{"varchar2", 1, 1, "414243", models.BindValue{Text: "ABC"}},
+		{"nvarchar2", 1, 2, "00410042", models.BindValue{Text: "AB"}},
+		{"char", 96, 1, "5820", models.BindValue{Text: "X "}},
+		{"number", 2, 0, "c20218", models.BindValue{Text: "123"}},
+		{"date", 12, 0, "787c030f0e2e1f", models.BindValue{Text: "2024-03-15 13:45:30"}},
+		{"timestamp", 180, 0, "787c030f0e2e1f075bcd15", models.BindValue{Text: "2024-03-15 13:45:30.123456789"}},
+		{"raw", 23, 0, "abcd", models.BindValue{Text: "ABCD"}},
+		{"binary_float", 100, 0, "bfc00000", models.BindValue{Text: "1.5"}},
+		{"binary_double", 101, 0, "400fffffffffffff", models.BindValue{Text: "-1"}},
+		{"bad number", 2, 0, "c100", models.BindValue{Text: "c100", Raw: true}},
+		{"infinity", 101, 0, "fff0000000000000", models.BindValue{Text: "fff0000000000000", Raw: true}},
+		{"unknown type", 113, 0, "00ff", models.BindValue{Text: "00ff", Raw: true}},
+		{"not hex", 2, 0, "zz", models.BindValue{Text: "zz", Raw: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeBind(tt.dty, tt.form, tt.value); *got != tt.want {
+				t.Errorf("decodeBind(%d, %d, %q) = %+v, want %+v", tt.dty, tt.form, tt.value, *got, tt.want)
+			}
+		})
+	}
+}
@@ -1,138 +1,733 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
 
-	"github.com/mdoeren/otop/internal/models"
 	_ "github.com/godror/godror"
+	"github.com/mdoeren/otop/internal/models"
 )
 
-// DB wraps a sql.DB connection to Oracle.
+// DefaultQueryTimeout bounds each monitoring query when Options.QueryTimeout
+// is zero.
+const DefaultQueryTimeout = 30 * time.Second
+
+// Options tunes how a DB issues its queries and supervises its connection.
+type Options struct {
+	// QueryTimeout bounds every individual query, on top of any deadline
+	// carried by the caller's context. Zero means DefaultQueryTimeout.
+	QueryTimeout time.Duration
+
+	// HealthInterval is how often the supervisor pings the database.
+	// Zero means DefaultHealthInterval.
+	HealthInterval time.Duration
+
+	// DegradedLatency is the ping round trip above which the connection is
+	// reported as degraded. Zero means DefaultDegradedLatency.
+	DegradedLatency time.Duration
+
+	// Cluster reports on every RAC instance instead of only the one the
+	// connection landed on. A Scope carried by the query context can still
+	// narrow it to a single instance.
+	Cluster bool
+}
+
+// DB wraps a sql.DB connection to Oracle. A background supervisor pings the
+// database, reopens the pool when the connection breaks, and reports the
+// connection's Health.
+//
+// Queries read the GV$ views so that they work unchanged on RAC; outside
+// cluster mode they are restricted to the local instance, which gives the
+// same rows as the V$ views.
 type DB struct {
+	connStr   string
+	opts      Options
+	localInst int
+
+	mu   sync.RWMutex
 	conn *sql.DB
+
+	sup *supervisor
 }
 
 // Connect opens a connection to Oracle using a godror connection string.
 // connStr format: user/password@host:port/service
-func Connect(connStr string) (*DB, error) {
-	conn, err := sql.Open("godror", connStr)
+func Connect(ctx context.Context, connStr string, opts Options) (*DB, error) {
+	if opts.QueryTimeout <= 0 {
+		opts.QueryTimeout = DefaultQueryTimeout
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = DefaultHealthInterval
+	}
+	if opts.DegradedLatency <= 0 {
+		opts.DegradedLatency = DefaultDegradedLatency
+	}
+	db := &DB{connStr: connStr, opts: opts}
+	conn, latency, err := db.open(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("open: %w", err)
+		return nil, err
 	}
-	if err := conn.Ping(); err != nil {
-		return nil, fmt.Errorf("ping: %w", err)
+	db.conn = conn
+	if db.localInst, err = db.lookupInstance(ctx, conn); err != nil {
+		conn.Close()
+		return nil, err
 	}
-	return &DB{conn: conn}, nil
+	db.sup = newSupervisor(db, latency)
+	go db.sup.run()
+	return db, nil
 }
 
-// Close closes the underlying database connection.
+// Close stops the supervisor and closes the underlying database connection.
 func (db *DB) Close() error {
+	db.sup.stop()
+	db.mu.Lock()
+	defer db.mu.Unlock()
 	return db.conn.Close()
 }
 
+// open creates a new pool and pings it, returning the ping round trip.
+func (db *DB) open(ctx context.Context) (*sql.DB, time.Duration, error) {
+	conn, err := sql.Open("godror", db.connStr)
+	if err != nil {
+		return nil, 0, fmt.Errorf("open: %w", err)
+	}
+	latency, err := db.ping(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, 0, fmt.Errorf("ping: %w", err)
+	}
+	return conn, latency, nil
+}
+
+// ping measures one round trip to the database on conn.
+func (db *DB) ping(ctx context.Context, conn *sql.DB) (time.Duration, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	start := time.Now()
+	if err := conn.PingContext(ctx); err != nil {
+		return 0, err
+	}
+	return time.Since(start), nil
+}
+
+// pool returns the current connection pool for a query, ErrReconnecting
+// while the supervisor is replacing a broken one, or ErrLoginRefused once
+// the database has refused to let it log back in.
+func (db *DB) pool() (*sql.DB, error) {
+	switch h := db.sup.Health(); h.State {
+	case Reconnecting:
+		return nil, ErrReconnecting
+	case LoginRefused:
+		// Using the pool would open sessions with the refused password.
+		return nil, fmt.Errorf("%w: %w", ErrLoginRefused, h.Err)
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.conn, nil
+}
+
+// lookupInstance returns the number of the instance conn connects to.
+func (db *DB) lookupInstance(ctx context.Context, conn *sql.DB) (int, error) {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	var inst int
+	if err := conn.QueryRowContext(ctx, `SELECT USERENV('INSTANCE') FROM DUAL`).Scan(&inst); err != nil {
+		return 0, fmt.Errorf("instance: %w", err)
+	}
+	return inst, nil
+}
+
+// instance returns the value for a query's :inst bind: the scoped instance
+// (0 for all) in cluster mode, otherwise the local instance.
+func (db *DB) instance(ctx context.Context) sql.NamedArg {
+	if db.opts.Cluster {
+		return sql.Named("inst", ScopeOf(ctx).InstID)
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return sql.Named("inst", db.localInst)
+}
+
+// container returns the value for a query's :con bind: the scoped
+// container ID, or 0 for every container the connection can see.
+func container(ctx context.Context) sql.NamedArg {
+	return sql.Named("con", ScopeOf(ctx).ConID)
+}
+
+// withTimeout derives the context a single query runs under.
+func (db *DB) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, db.opts.QueryTimeout)
+}
+
 // GetActiveSessions returns all user sessions joined with their current SQL
-// text and wait event. Active sessions sort first.
-func (db *DB) GetActiveSessions() ([]models.Session, error) {
+// text, wait event and cumulative CPU, DB time and I/O counters. Active
+// sessions sort first.
+func (db *DB) GetActiveSessions(ctx context.Context) ([]models.Session, error) {
 	const query = `
 SELECT
+    s.INST_ID,
+    s.CON_ID,
+    NVL(c.NAME, '')                  AS PDB_NAME,
     s.SID,
     s.SERIAL#,
     NVL(s.USERNAME, '(background)')  AS USERNAME,
     s.STATUS,
     NVL(s.SQL_ID, '')                AS SQL_ID,
+    NVL(s.SQL_CHILD_NUMBER, -1)      AS SQL_CHILD_NUMBER,
     NVL(q.SQL_TEXT, '')              AS SQL_TEXT,
     NVL(s.PROGRAM, '')               AS PROGRAM,
     NVL(s.MACHINE, '')               AS MACHINE,
-    NVL(w.EVENT, '')                 AS WAIT_EVENT,
-    NVL(w.SECONDS_IN_WAIT, 0)        AS WAIT_SECONDS,
-    NVL(q.CPU_TIME,     0) / 1e6    AS CPU_TIME,
-    NVL(q.ELAPSED_TIME, 0) / 1e6    AS ELAPSED_TIME,
-    NVL(q.DISK_READS,   0)           AS PHYSICAL_READS,
-    NVL(q.BUFFER_GETS,  0)           AS LOGICAL_READS
-FROM V$SESSION s
-LEFT JOIN V$SQL q
-       ON s.SQL_ID          = q.SQL_ID
+    -- Outside a wait, EVENT is the last wait, which has ended.
+    CASE WHEN w.STATE IS NULL THEN '' WHEN w.STATE = 'WAITING' THEN w.EVENT ELSE 'ON CPU' END AS WAIT_EVENT,
+    CASE WHEN w.STATE = 'WAITING' THEN w.SECONDS_IN_WAIT ELSE 0 END AS WAIT_SECONDS,
+    NVL(t.CPU_TIME, 0)               AS CPU_TIME,
+    NVL(t.DB_TIME,  0)               AS DB_TIME,
+    NVL(io.PHYSICAL_READS, 0)        AS PHYSICAL_READS,
+    NVL(io.BLOCK_GETS + io.CONSISTENT_GETS, 0) AS LOGICAL_READS
+FROM GV$SESSION s
+LEFT JOIN GV$SQL q
+       ON s.INST_ID          = q.INST_ID
+      AND s.CON_ID           = q.CON_ID
+      AND s.SQL_ID           = q.SQL_ID
       AND s.SQL_CHILD_NUMBER = q.CHILD_NUMBER
-LEFT JOIN V$SESSION_WAIT w
-       ON s.SID = w.SID
+LEFT JOIN GV$SESSION_WAIT w
+       ON s.INST_ID = w.INST_ID
+      AND s.SID     = w.SID
+LEFT JOIN GV$CONTAINERS c
+       ON s.INST_ID = c.INST_ID
+      AND s.CON_ID  = c.CON_ID
+LEFT JOIN (
+    -- Both statistics are reported in centiseconds.
+    SELECT st.INST_ID, st.SID,
+           SUM(CASE n.NAME WHEN 'CPU used by this session' THEN st.VALUE END) / 100 AS CPU_TIME,
+           SUM(CASE n.NAME WHEN 'DB time'                  THEN st.VALUE END) / 100 AS DB_TIME
+    FROM   GV$SESSTAT st
+    JOIN   GV$STATNAME n
+           ON n.INST_ID    = st.INST_ID
+          AND n.STATISTIC# = st.STATISTIC#
+    WHERE  n.NAME IN ('CPU used by this session', 'DB time')
+    GROUP BY st.INST_ID, st.SID
+) t
+       ON s.INST_ID = t.INST_ID
+      AND s.SID     = t.SID
+LEFT JOIN GV$SESS_IO io
+       ON s.INST_ID = io.INST_ID
+      AND s.SID     = io.SID
 WHERE s.TYPE = 'USER'
+  AND (:inst = 0 OR s.INST_ID = :inst)
+  AND (:con  = 0 OR s.CON_ID  = :con)
 ORDER BY
     CASE s.STATUS WHEN 'ACTIVE' THEN 0 ELSE 1 END,
+    s.INST_ID,
     s.SID`
 
-	rows, err := db.conn.Query(query)
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
 	if err != nil {
 		return nil, fmt.Errorf("GetActiveSessions: %w", err)
 	}
+	rows, err := conn.QueryContext(ctx, query, db.instance(ctx), container(ctx))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetActiveSessions: %w", err))
+	}
 	defer rows.Close()
 
 	var sessions []models.Session
 	for rows.Next() {
 		var s models.Session
 		if err := rows.Scan(
-			&s.SID, &s.Serial, &s.Username, &s.Status,
-			&s.SQLID, &s.SQLText, &s.Program, &s.Machine,
+			&s.InstID, &s.ConID, &s.PDBName, &s.SID, &s.Serial, &s.Username, &s.Status,
+			&s.SQLID, &s.SQLChildNumber, &s.SQLText, &s.Program, &s.Machine,
 			&s.WaitEvent, &s.WaitSeconds,
-			&s.CPUTime, &s.ElapsedTime,
+			&s.CPUTime, &s.DBTime,
 			&s.PhysicalReads, &s.LogicalReads,
 		); err != nil {
 			return nil, fmt.Errorf("GetActiveSessions scan: %w", err)
 		}
 		sessions = append(sessions, s)
 	}
-	return sessions, rows.Err()
+	return sessions, db.observe(rows.Err())
 }
 
-// GetExecutionPlan returns the execution plan rows for the given SQL ID,
-// using the lowest child cursor number to get a consistent plan.
-func (db *DB) GetExecutionPlan(sqlID string) ([]models.PlanRow, error) {
+// SampleActiveSessions returns the user sessions that are on CPU or in a
+// non-idle wait right now, the way ASH samples them. The connection's own
+// session is left out so that sampling does not show up in the samples.
+func (db *DB) SampleActiveSessions(ctx context.Context) ([]models.ASHSample, error) {
 	const query = `
 SELECT
-    ID,
-    NVL(PARENT_ID,   0)  AS PARENT_ID,
-    DEPTH,
-    OPERATION,
-    NVL(OPTIONS,     '') AS OPTIONS,
-    NVL(OBJECT_NAME, '') AS OBJECT_NAME,
-    NVL(CARDINALITY, 0)  AS CARDINALITY,
-    NVL(BYTES,       0)  AS BYTES,
-    NVL(COST,        0)  AS COST
-FROM V$SQL_PLAN
-WHERE SQL_ID = :sqlid
-  AND CHILD_NUMBER = (
-      SELECT MIN(CHILD_NUMBER)
-      FROM   V$SQL_PLAN
-      WHERE  SQL_ID = :sqlid
-  )
-ORDER BY ID`
+    s.INST_ID,
+    s.CON_ID,
+    s.SID,
+    s.SERIAL#,
+    NVL(s.USERNAME, '(background)') AS USERNAME,
+    NVL(s.SQL_ID, '')               AS SQL_ID,
+    CASE WHEN s.STATE = 'WAITING' THEN 'WAITING' ELSE 'ON CPU' END AS STATE,
+    CASE WHEN s.STATE = 'WAITING' THEN s.WAIT_CLASS ELSE 'CPU' END AS WAIT_CLASS,
+    CASE WHEN s.STATE = 'WAITING' THEN s.EVENT ELSE '' END AS EVENT
+FROM GV$SESSION s
+WHERE s.TYPE   = 'USER'
+  AND s.STATUS = 'ACTIVE'
+  AND (s.STATE <> 'WAITING' OR s.WAIT_CLASS <> 'Idle')
+  AND NOT (s.INST_ID = USERENV('INSTANCE') AND s.SID = SYS_CONTEXT('USERENV', 'SID'))
+  AND (:inst = 0 OR s.INST_ID = :inst)
+  AND (:con  = 0 OR s.CON_ID  = :con)`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("SampleActiveSessions: %w", err)
+	}
+	rows, err := conn.QueryContext(ctx, query, db.instance(ctx), container(ctx))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("SampleActiveSessions: %w", err))
+	}
+	defer rows.Close()
 
-	rows, err := db.conn.Query(query, sql.Named("sqlid", sqlID))
+	now := time.Now()
+	var samples []models.ASHSample
+	for rows.Next() {
+		a := models.ASHSample{SampleTime: now}
+		if err := rows.Scan(
+			&a.InstID, &a.ConID, &a.SID, &a.Serial, &a.Username, &a.SQLID,
+			&a.State, &a.WaitClass, &a.Event,
+		); err != nil {
+			return nil, fmt.Errorf("SampleActiveSessions scan: %w", err)
+		}
+		samples = append(samples, a)
+	}
+	return samples, db.observe(rows.Err())
+}
+
+// GetActiveSessionHistory returns ASH samples between from and to, merged
+// into step-long buckets. Recent samples come from
+// GV$ACTIVE_SESSION_HISTORY; older ones, which have aged out of memory, from
+// DBA_HIST_ACTIVE_SESS_HISTORY, where each sample stands for ten seconds.
+//
+// The range is passed as offsets from the database's clock, since
+// SAMPLE_TIME is recorded in the server's local time.
+func (db *DB) GetActiveSessionHistory(ctx context.Context, from, to time.Time, step time.Duration) ([]models.ASHSample, error) {
+	const query = `
+WITH bounds AS (
+    SELECT CAST(SYSTIMESTAMP AS TIMESTAMP) - NUMTODSINTERVAL(:from_ago, 'SECOND') AS T_FROM,
+           CAST(SYSTIMESTAMP AS TIMESTAMP) - NUMTODSINTERVAL(:to_ago,   'SECOND') AS T_TO
+    FROM DUAL
+), ash AS (
+    SELECT a.INST_ID, a.CON_ID, a.SESSION_ID, a.SESSION_SERIAL#, a.USER_ID, a.SQL_ID,
+           a.SESSION_STATE, a.WAIT_CLASS, a.EVENT, a.SAMPLE_TIME, 1 AS SECONDS
+    FROM   GV$ACTIVE_SESSION_HISTORY a, bounds b
+    WHERE  a.SAMPLE_TIME >= b.T_FROM
+      AND  a.SAMPLE_TIME <  b.T_TO
+    UNION ALL
+    -- AWR keeps one in ten in-memory samples, so each stands for ten
+    -- seconds. Only read it where the in-memory buffer no longer reaches.
+    SELECT h.INSTANCE_NUMBER, h.CON_ID, h.SESSION_ID, h.SESSION_SERIAL#, h.USER_ID, h.SQL_ID,
+           h.SESSION_STATE, h.WAIT_CLASS, h.EVENT, h.SAMPLE_TIME, 10
+    FROM   DBA_HIST_ACTIVE_SESS_HISTORY h, bounds b
+    WHERE  h.DBID = (SELECT DBID FROM V$DATABASE)
+      AND  h.SAMPLE_TIME >= b.T_FROM
+      AND  h.SAMPLE_TIME <  b.T_TO
+      AND  h.SAMPLE_TIME <  (SELECT NVL(MIN(m.SAMPLE_TIME), b.T_TO)
+                             FROM   GV$ACTIVE_SESSION_HISTORY m
+                             WHERE  m.INST_ID = h.INSTANCE_NUMBER)
+), samples AS (
+    SELECT
+        FLOOR((CAST(ash.SAMPLE_TIME AS DATE) - CAST(b.T_FROM AS DATE)) * 86400 / :step) AS BUCKET,
+        ash.INST_ID,
+        ash.CON_ID,
+        ash.SESSION_ID,
+        ash.SESSION_SERIAL#                   AS SERIAL,
+        NVL(u.USERNAME, TO_CHAR(ash.USER_ID)) AS USERNAME,
+        NVL(ash.SQL_ID, '')                   AS SQL_ID,
+        CASE ash.SESSION_STATE WHEN 'ON CPU' THEN 'ON CPU' ELSE 'WAITING' END       AS STATE,
+        CASE ash.SESSION_STATE WHEN 'ON CPU' THEN 'CPU' ELSE NVL(ash.WAIT_CLASS, 'Other') END AS WAIT_CLASS,
+        CASE ash.SESSION_STATE WHEN 'ON CPU' THEN '' ELSE NVL(ash.EVENT, '') END      AS EVENT,
+        ash.SECONDS
+    FROM ash
+    CROSS JOIN bounds b
+    LEFT JOIN CDB_USERS u
+           ON u.CON_ID  = ash.CON_ID
+          AND u.USER_ID = ash.USER_ID
+    WHERE (:inst = 0 OR ash.INST_ID = :inst)
+      AND (:con  = 0 OR ash.CON_ID  = :con)
+)
+SELECT BUCKET, INST_ID, CON_ID, SESSION_ID, SERIAL, USERNAME, SQL_ID, STATE, WAIT_CLASS, EVENT,
+       SUM(SECONDS) AS SECONDS
+FROM samples
+GROUP BY BUCKET, INST_ID, CON_ID, SESSION_ID, SERIAL, USERNAME, SQL_ID, STATE, WAIT_CLASS, EVENT
+ORDER BY BUCKET`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetActiveSessionHistory: %w", err)
+	}
+	now := time.Now()
+	step = max(step, time.Second)
+	rows, err := conn.QueryContext(ctx, query,
+		sql.Named("from_ago", now.Sub(from).Seconds()),
+		sql.Named("to_ago", now.Sub(to).Seconds()),
+		sql.Named("step", step.Seconds()),
+		db.instance(ctx), container(ctx))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetActiveSessionHistory: %w", err))
+	}
+	defer rows.Close()
+
+	var samples []models.ASHSample
+	for rows.Next() {
+		var (
+			a       models.ASHSample
+			bucket  int64
+			seconds int64
+		)
+		if err := rows.Scan(
+			&bucket, &a.InstID, &a.ConID, &a.SID, &a.Serial, &a.Username, &a.SQLID,
+			&a.State, &a.WaitClass, &a.Event, &seconds,
+		); err != nil {
+			return nil, fmt.Errorf("GetActiveSessionHistory scan: %w", err)
+		}
+		a.SampleTime = from.Add(time.Duration(bucket) * step)
+		a.Interval = time.Duration(seconds) * time.Second
+		samples = append(samples, a)
+	}
+	return samples, db.observe(rows.Err())
+}
+
+// GetLockWaits returns the sessions in the workflow's scope that are
+// blocked, together with every session blocking them (in any scope, so
+// chains that cross instances or containers stay whole). Each row carries the
+// lock it waits for, or else the lock it holds that others wait for, and
+// the object waited on.
+func (db *DB) GetLockWaits(ctx context.Context) ([]models.LockWait, error) {
+	const query = `
+WITH involved AS (
+    SELECT INST_ID, SID
+    FROM   GV$SESSION
+    WHERE  BLOCKING_SESSION IS NOT NULL
+      AND  (:inst = 0 OR INST_ID = :inst)
+      AND  (:con  = 0 OR CON_ID  = :con)
+    UNION
+    SELECT BLOCKING_INSTANCE, BLOCKING_SESSION
+    FROM   GV$SESSION
+    WHERE  BLOCKING_SESSION IS NOT NULL
+      AND  (:inst = 0 OR INST_ID = :inst)
+      AND  (:con  = 0 OR CON_ID  = :con)
+    UNION
+    SELECT FINAL_BLOCKING_INSTANCE, FINAL_BLOCKING_SESSION
+    FROM   GV$SESSION
+    WHERE  FINAL_BLOCKING_SESSION IS NOT NULL
+      AND  (:inst = 0 OR INST_ID = :inst)
+      AND  (:con  = 0 OR CON_ID  = :con)
+), locks AS (
+    -- The lock a session requests if it is waiting, otherwise the one it
+    -- holds that blocks others.
+    SELECT INST_ID, SID,
+           MAX(TYPE)    KEEP (DENSE_RANK FIRST ORDER BY REQUEST DESC, BLOCK DESC) AS TYPE,
+           MAX(LMODE)   KEEP (DENSE_RANK FIRST ORDER BY REQUEST DESC, BLOCK DESC) AS LMODE,
+           MAX(REQUEST) KEEP (DENSE_RANK FIRST ORDER BY REQUEST DESC, BLOCK DESC) AS REQUEST,
+           MAX(ID1)     KEEP (DENSE_RANK FIRST ORDER BY REQUEST DESC, BLOCK DESC) AS ID1
+    FROM   GV$LOCK
+    WHERE  REQUEST > 0 OR BLOCK > 0
+    GROUP BY INST_ID, SID
+)
+SELECT
+    s.INST_ID,
+    s.CON_ID,
+    s.SID,
+    s.SERIAL#,
+    NVL(s.USERNAME, '(background)')     AS USERNAME,
+    NVL(s.PROGRAM, '')                  AS PROGRAM,
+    s.STATUS,
+    NVL(s.SQL_ID, '')                   AS SQL_ID,
+    CASE WHEN s.STATE = 'WAITING' THEN s.EVENT ELSE 'ON CPU' END AS EVENT,
+    CASE WHEN s.STATE = 'WAITING' THEN s.WAIT_TIME_MICRO / 1e6 ELSE 0 END AS WAIT_SECONDS,
+    NVL(s.BLOCKING_INSTANCE, 0)         AS BLOCKING_INSTANCE,
+    NVL(s.BLOCKING_SESSION, 0)          AS BLOCKING_SESSION,
+    NVL(s.FINAL_BLOCKING_INSTANCE, 0)   AS FINAL_BLOCKING_INSTANCE,
+    NVL(s.FINAL_BLOCKING_SESSION, 0)    AS FINAL_BLOCKING_SESSION,
+    NVL(l.TYPE, '')                     AS LOCK_TYPE,
+    DECODE(l.LMODE,   2, 'SS', 3, 'SX', 4, 'S', 5, 'SSX', 6, 'X', '') AS MODE_HELD,
+    DECODE(l.REQUEST, 2, 'SS', 3, 'SX', 4, 'S', 5, 'SSX', 6, 'X', '') AS MODE_REQUESTED,
+    NVL2(o.OBJECT_NAME, o.OWNER || '.' || o.OBJECT_NAME, '') AS OBJECT
+FROM GV$SESSION s
+JOIN involved i
+  ON i.INST_ID = s.INST_ID
+ AND i.SID     = s.SID
+LEFT JOIN locks l
+       ON l.INST_ID = s.INST_ID
+      AND l.SID     = s.SID
+LEFT JOIN CDB_OBJECTS o
+       ON o.CON_ID    = s.CON_ID
+      AND o.OBJECT_ID = CASE
+                            WHEN s.BLOCKING_SESSION IS NOT NULL AND s.ROW_WAIT_OBJ# > 0 THEN s.ROW_WAIT_OBJ#
+                            WHEN l.TYPE = 'TM' THEN l.ID1
+                        END
+ORDER BY s.INST_ID, s.SID`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetLockWaits: %w", err)
+	}
+	rows, err := conn.QueryContext(ctx, query, db.instance(ctx), container(ctx))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetLockWaits: %w", err))
+	}
+	defer rows.Close()
+
+	var waits []models.LockWait
+	for rows.Next() {
+		var w models.LockWait
+		if err := rows.Scan(
+			&w.InstID, &w.ConID, &w.SID, &w.Serial, &w.Username, &w.Program, &w.Status,
+			&w.SQLID, &w.Event, &w.WaitSeconds,
+			&w.BlockingInstID, &w.BlockingSID, &w.FinalBlockingInstID, &w.FinalBlockingSID,
+			&w.LockType, &w.ModeHeld, &w.ModeRequested, &w.Object,
+		); err != nil {
+			return nil, fmt.Errorf("GetLockWaits scan: %w", err)
+		}
+		waits = append(waits, w)
+	}
+	return waits, db.observe(rows.Err())
+}
+
+// GetLongOps reads V$SESSION_LONGOPS. An operation abandoned halfway, say
+// by a cancelled query, stays there unfinished, so only those of the
+// execution their session is still running are returned.
+func (db *DB) GetLongOps(ctx context.Context) ([]models.LongOp, error) {
+	const query = `
+SELECT
+    l.INST_ID,
+    l.CON_ID,
+    l.SID,
+    l.SERIAL#,
+    NVL(l.USERNAME, '(background)')      AS USERNAME,
+    NVL(l.SQL_ID, '')                    AS SQL_ID,
+    l.OPNAME,
+    NVL(l.TARGET, NVL(l.TARGET_DESC, '')) AS TARGET,
+    l.SOFAR,
+    l.TOTALWORK,
+    NVL(l.UNITS, '')                     AS UNITS,
+    l.START_TIME,
+    NVL(l.ELAPSED_SECONDS, 0)            AS ELAPSED_SECONDS,
+    NVL(l.TIME_REMAINING, -1)            AS TIME_REMAINING
+FROM GV$SESSION_LONGOPS l
+JOIN GV$SESSION s
+  ON s.INST_ID = l.INST_ID
+ AND s.SID     = l.SID
+ AND s.SERIAL# = l.SERIAL#
+WHERE l.SOFAR < l.TOTALWORK
+  AND s.STATUS = 'ACTIVE'
+  AND (l.SQL_EXEC_ID IS NULL OR l.SQL_EXEC_ID = s.SQL_EXEC_ID)
+  AND (:inst = 0 OR l.INST_ID = :inst)
+  AND (:con  = 0 OR l.CON_ID  = :con)
+ORDER BY l.START_TIME, l.INST_ID, l.SID`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetLongOps: %w", err)
+	}
+	rows, err := conn.QueryContext(ctx, query, db.instance(ctx), container(ctx))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetLongOps: %w", err))
+	}
+	defer rows.Close()
+
+	var ops []models.LongOp
+	for rows.Next() {
+		var o models.LongOp
+		var elapsed, remaining int64
+		if err := rows.Scan(
+			&o.InstID, &o.ConID, &o.SID, &o.Serial, &o.Username, &o.SQLID,
+			&o.OpName, &o.Target, &o.SoFar, &o.TotalWork, &o.Units,
+			&o.StartTime, &elapsed, &remaining,
+		); err != nil {
+			return nil, fmt.Errorf("GetLongOps scan: %w", err)
+		}
+		o.Elapsed = time.Duration(elapsed) * time.Second
+		o.Remaining = time.Duration(remaining) * time.Second
+		if remaining < 0 {
+			o.Remaining = -1
+		}
+		ops = append(ops, o)
+	}
+	return ops, db.observe(rows.Err())
+}
+
+// GetExecutionPlan returns the execution plan rows for the given SQL ID
+// and child cursor. Without a child it uses the lowest child cursor number
+// (on the lowest-numbered instance in cluster mode). Each step carries the row source
+// statistics of the last execution where they were gathered, as
+// DBMS_XPLAN.DISPLAY_CURSOR's ALLSTATS LAST shows them.
+func (db *DB) GetExecutionPlan(ctx context.Context, sqlID string, child *models.ChildCursor) ([]models.PlanRow, error) {
+	const query = `
+WITH pick AS (
+    SELECT INST_ID, CON_ID, CHILD_NUMBER
+    FROM (
+        SELECT INST_ID, CON_ID, CHILD_NUMBER
+        FROM   GV$SQL_PLAN_STATISTICS_ALL
+        WHERE  SQL_ID = :sqlid
+          AND  (:inst = 0 OR INST_ID = :inst)
+          AND  (:con  = 0 OR CON_ID  = :con)
+          AND  (:child < 0 OR (INST_ID = :child_inst AND CON_ID = :child_con AND CHILD_NUMBER = :child))
+        ORDER BY INST_ID, CHILD_NUMBER
+    )
+    WHERE ROWNUM = 1
+)
+SELECT
+    p.INST_ID,
+    p.ID,
+    NVL(p.PARENT_ID,   0)  AS PARENT_ID,
+    p.DEPTH,
+    p.OPERATION,
+    NVL(p.OPTIONS,     '') AS OPTIONS,
+    NVL(p.OBJECT_NAME, '') AS OBJECT_NAME,
+    NVL(p.CARDINALITY, 0)  AS CARDINALITY,
+    NVL(p.BYTES,       0)  AS BYTES,
+    NVL(p.COST,        0)  AS COST,
+    NVL2(p.LAST_STARTS, 1, 0)         AS HAS_STATS,
+    NVL(p.LAST_STARTS,         0)     AS STARTS,
+    NVL(p.LAST_OUTPUT_ROWS,    0)     AS ACTUAL_ROWS,
+    NVL(p.LAST_CR_BUFFER_GETS, 0)
+      + NVL(p.LAST_CU_BUFFER_GETS, 0) AS BUFFER_GETS,
+    NVL(p.LAST_ELAPSED_TIME,   0)     AS ELAPSED_TIME
+FROM GV$SQL_PLAN_STATISTICS_ALL p
+JOIN pick
+  ON p.INST_ID      = pick.INST_ID
+ AND p.CON_ID       = pick.CON_ID
+ AND p.CHILD_NUMBER = pick.CHILD_NUMBER
+WHERE p.SQL_ID = :sqlid
+ORDER BY p.ID`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
 	if err != nil {
 		return nil, fmt.Errorf("GetExecutionPlan: %w", err)
 	}
+	pick := models.ChildCursor{ChildNumber: -1}
+	if child != nil {
+		pick = *child
+	}
+	rows, err := conn.QueryContext(ctx, query,
+		sql.Named("sqlid", sqlID), db.instance(ctx), container(ctx),
+		sql.Named("child", pick.ChildNumber), sql.Named("child_inst", pick.InstID), sql.Named("child_con", pick.ConID),
+	)
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetExecutionPlan: %w", err))
+	}
 	defer rows.Close()
 
 	var plan []models.PlanRow
 	for rows.Next() {
 		var r models.PlanRow
+		var hasStats int
 		if err := rows.Scan(
-			&r.ID, &r.ParentID, &r.Depth,
+			&r.InstID, &r.ID, &r.ParentID, &r.Depth,
 			&r.Operation, &r.Options, &r.ObjectName,
 			&r.Cardinality, &r.Bytes, &r.Cost,
+			&hasStats, &r.Starts, &r.ActualRows, &r.BufferGets, &r.ElapsedMicros,
 		); err != nil {
 			return nil, fmt.Errorf("GetExecutionPlan scan: %w", err)
 		}
+		r.HasStats = hasStats == 1
 		plan = append(plan, r)
 	}
-	return plan, rows.Err()
+	return plan, db.observe(rows.Err())
+}
+
+// GetChildCursors returns the child cursors of the given SQL ID with their
+// plan hash values, statistics and the reasons V$SQL_SHARED_CURSOR gives
+// for each not sharing an earlier child.
+func (db *DB) GetChildCursors(ctx context.Context, sqlID string) ([]models.ChildCursor, error) {
+	const query = `
+SELECT
+    q.INST_ID,
+    q.CON_ID,
+    q.CHILD_NUMBER,
+    q.PLAN_HASH_VALUE,
+    q.EXECUTIONS,
+    q.ELAPSED_TIME,
+    q.BUFFER_GETS,
+    -- REASON is a CLOB of XML, one ChildNode per reason.
+    NVL(DBMS_LOB.SUBSTR(sc.REASON, 4000, 1), '') AS REASON
+FROM GV$SQL q
+LEFT JOIN GV$SQL_SHARED_CURSOR sc
+       ON sc.INST_ID       = q.INST_ID
+      AND sc.CHILD_ADDRESS = q.CHILD_ADDRESS
+WHERE q.SQL_ID = :sqlid
+  AND (:inst = 0 OR q.INST_ID = :inst)
+  AND (:con  = 0 OR q.CON_ID  = :con)
+ORDER BY q.INST_ID, q.CON_ID, q.CHILD_NUMBER`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetChildCursors: %w", err)
+	}
+	rows, err := conn.QueryContext(ctx, query, sql.Named("sqlid", sqlID), db.instance(ctx), container(ctx))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetChildCursors: %w", err))
+	}
+	defer rows.Close()
+
+	var children []models.ChildCursor
+	for rows.Next() {
+		var c models.ChildCursor
+		var reason string
+		if err := rows.Scan(
+			&c.InstID, &c.ConID, &c.ChildNumber, &c.PlanHashValue,
+			&c.Executions, &c.ElapsedTimeMicros, &c.BufferGets, &reason,
+		); err != nil {
+			return nil, fmt.Errorf("GetChildCursors scan: %w", err)
+		}
+		c.Reasons = sharingReasons(reason)
+		children = append(children, c)
+	}
+	return children, db.observe(rows.Err())
+}
+
+// reasonElement matches a reason in V$SQL_SHARED_CURSOR.REASON, such as
+// <reason>Optimizer mismatch(12)</reason>.
+var reasonElement = regexp.MustCompile(`(?is)<reason>\s*(.*?)\s*</reason>`)
+
+// sharingReasons lists the distinct reasons in a REASON document, in order.
+func sharingReasons(doc string) []string {
+	var reasons []string
+	for _, m := range reasonElement.FindAllStringSubmatch(doc, -1) {
+		if m[1] != "" && !slices.Contains(reasons, m[1]) {
+			reasons = append(reasons, m[1])
+		}
+	}
+	return reasons
 }
 
 // GetSQLStats returns aggregated runtime statistics for the given SQL ID,
-// summing across all child cursors.
-func (db *DB) GetSQLStats(sqlID string) (*models.SQLStats, error) {
+// summing across all child cursors (and, for a whole-cluster scope, across
+// all instances).
+func (db *DB) GetSQLStats(ctx context.Context, sqlID string) (*models.SQLStats, error) {
 	const query = `
 SELECT
+    :inst                  AS INST_ID,
     SQL_ID,
     MIN(SQL_TEXT)          AS SQL_TEXT,
     SUM(EXECUTIONS)        AS EXECUTIONS,
@@ -141,13 +736,22 @@ SELECT
     SUM(BUFFER_GETS)       AS BUFFER_GETS,
     SUM(DISK_READS)        AS DISK_READS,
     SUM(ROWS_PROCESSED)    AS ROWS_PROCESSED
-FROM V$SQL
+FROM GV$SQL
 WHERE SQL_ID = :sqlid
+  AND (:inst = 0 OR INST_ID = :inst)
+  AND (:con  = 0 OR CON_ID  = :con)
 GROUP BY SQL_ID`
 
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetSQLStats: %w", err)
+	}
 	var s models.SQLStats
-	err := db.conn.QueryRow(query, sql.Named("sqlid", sqlID)).Scan(
-		&s.SQLID, &s.SQLText,
+	err = conn.QueryRowContext(ctx, query, sql.Named("sqlid", sqlID), db.instance(ctx), container(ctx)).Scan(
+		&s.InstID, &s.SQLID, &s.SQLText,
 		&s.Executions, &s.ElapsedTimeMicros, &s.CPUTimeMicros,
 		&s.BufferGets, &s.DiskReads, &s.Rows,
 	)
@@ -155,7 +759,281 @@ GROUP BY SQL_ID`
 		return nil, nil
 	}
 	if err != nil {
-		return nil, fmt.Errorf("GetSQLStats: %w", err)
+		return nil, db.observe(fmt.Errorf("GetSQLStats: %w", err))
 	}
 	return &s, nil
 }
+
+// GetTopSQL reads V$SQLSTATS, which keeps statistics per statement and
+// plan even after the cursors have aged out of the shared pool, and is
+// cheaper to query than V$SQL.
+func (db *DB) GetTopSQL(ctx context.Context, n int) ([]models.SQLStats, error) {
+	const query = `
+WITH s AS (
+    SELECT
+        SQL_ID,
+        PLAN_HASH_VALUE,
+        MIN(SQL_TEXT)          AS SQL_TEXT,
+        SUM(EXECUTIONS)        AS EXECUTIONS,
+        SUM(ELAPSED_TIME)      AS ELAPSED_TIME,
+        SUM(CPU_TIME)          AS CPU_TIME,
+        SUM(BUFFER_GETS)       AS BUFFER_GETS,
+        SUM(DISK_READS)        AS DISK_READS,
+        SUM(ROWS_PROCESSED)    AS ROWS_PROCESSED,
+        MAX(LAST_ACTIVE_TIME)  AS LAST_ACTIVE_TIME
+    FROM GV$SQLSTATS
+    WHERE (:inst = 0 OR INST_ID = :inst)
+      AND (:con  = 0 OR CON_ID  = :con)
+    GROUP BY SQL_ID, PLAN_HASH_VALUE
+), ranked AS (
+    SELECT
+        s.*,
+        LEAST(
+            ROW_NUMBER() OVER (ORDER BY ELAPSED_TIME DESC),
+            ROW_NUMBER() OVER (ORDER BY CPU_TIME DESC),
+            ROW_NUMBER() OVER (ORDER BY BUFFER_GETS DESC),
+            ROW_NUMBER() OVER (ORDER BY DISK_READS DESC),
+            ROW_NUMBER() OVER (ORDER BY EXECUTIONS DESC)
+        ) AS BEST_RANK
+    FROM s
+)
+SELECT
+    :inst AS INST_ID,
+    SQL_ID,
+    PLAN_HASH_VALUE,
+    SQL_TEXT,
+    EXECUTIONS,
+    ELAPSED_TIME,
+    CPU_TIME,
+    BUFFER_GETS,
+    DISK_READS,
+    ROWS_PROCESSED
+FROM ranked
+WHERE BEST_RANK <= :n
+   OR LAST_ACTIVE_TIME >= SYSDATE - 1/1440
+ORDER BY ELAPSED_TIME DESC`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetTopSQL: %w", err)
+	}
+	rows, err := conn.QueryContext(ctx, query, sql.Named("n", n), db.instance(ctx), container(ctx))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetTopSQL: %w", err))
+	}
+	defer rows.Close()
+
+	var out []models.SQLStats
+	for rows.Next() {
+		var s models.SQLStats
+		if err := rows.Scan(
+			&s.InstID, &s.SQLID, &s.PlanHashValue, &s.SQLText,
+			&s.Executions, &s.ElapsedTimeMicros, &s.CPUTimeMicros,
+			&s.BufferGets, &s.DiskReads, &s.Rows,
+		); err != nil {
+			return nil, fmt.Errorf("GetTopSQL scan: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, db.observe(rows.Err())
+}
+
+// GetSystemEvents reads V$SYSTEM_EVENT, or, scoped to a container,
+// V$CON_SYSTEM_EVENT, which splits the same totals by container.
+func (db *DB) GetSystemEvents(ctx context.Context) ([]models.SystemEvent, error) {
+	const query = `
+SELECT EVENT, WAIT_CLASS, SUM(TOTAL_WAITS) AS TOTAL_WAITS, SUM(TIME_WAITED_MICRO) AS TIME_WAITED_MICRO
+FROM (
+    SELECT INST_ID, EVENT, WAIT_CLASS, TOTAL_WAITS, TIME_WAITED_MICRO
+    FROM   GV$SYSTEM_EVENT
+    WHERE  :con = 0
+    UNION ALL
+    SELECT INST_ID, EVENT, WAIT_CLASS, TOTAL_WAITS, TIME_WAITED_MICRO
+    FROM   GV$CON_SYSTEM_EVENT
+    WHERE  :con <> 0
+      AND  CON_ID = :con
+)
+WHERE WAIT_CLASS <> 'Idle'
+  AND (:inst = 0 OR INST_ID = :inst)
+GROUP BY EVENT, WAIT_CLASS
+ORDER BY TIME_WAITED_MICRO DESC`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetSystemEvents: %w", err)
+	}
+	rows, err := conn.QueryContext(ctx, query, db.instance(ctx), container(ctx))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetSystemEvents: %w", err))
+	}
+	defer rows.Close()
+
+	var out []models.SystemEvent
+	for rows.Next() {
+		var e models.SystemEvent
+		if err := rows.Scan(&e.Event, &e.WaitClass, &e.TotalWaits, &e.TimeWaitedMicros); err != nil {
+			return nil, fmt.Errorf("GetSystemEvents scan: %w", err)
+		}
+		out = append(out, e)
+	}
+	return out, db.observe(rows.Err())
+}
+
+// GetSystemMetrics reads V$SYSMETRIC, or, scoped to a container,
+// V$CON_SYSMETRIC. Metrics computed over both a 15 and a 60 second interval
+// are taken from the shorter. Across instances, percentages are averaged
+// and everything else is added up.
+func (db *DB) GetSystemMetrics(ctx context.Context) ([]models.SystemMetric, error) {
+	const query = `
+SELECT
+    METRIC_NAME,
+    MIN(METRIC_UNIT) AS METRIC_UNIT,
+    CASE WHEN MIN(METRIC_UNIT) LIKE '%\%%' ESCAPE '\' THEN AVG(VALUE) ELSE SUM(VALUE) END AS VALUE,
+    MAX(END_TIME) AS END_TIME
+FROM (
+    SELECT m.*, ROW_NUMBER() OVER (PARTITION BY INST_ID, METRIC_NAME ORDER BY INTSIZE_CSEC) AS RN
+    FROM (
+        SELECT INST_ID, METRIC_NAME, METRIC_UNIT, VALUE, END_TIME, INTSIZE_CSEC
+        FROM   GV$SYSMETRIC
+        WHERE  :con = 0
+        UNION ALL
+        SELECT INST_ID, METRIC_NAME, METRIC_UNIT, VALUE, END_TIME, INTSIZE_CSEC
+        FROM   GV$CON_SYSMETRIC
+        WHERE  :con <> 0
+          AND  CON_ID = :con
+    ) m
+    WHERE (:inst = 0 OR INST_ID = :inst)
+)
+WHERE RN = 1
+GROUP BY METRIC_NAME`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetSystemMetrics: %w", err)
+	}
+	rows, err := conn.QueryContext(ctx, query, db.instance(ctx), container(ctx))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetSystemMetrics: %w", err))
+	}
+	defer rows.Close()
+
+	var out []models.SystemMetric
+	for rows.Next() {
+		var m models.SystemMetric
+		if err := rows.Scan(&m.Name, &m.Unit, &m.Value, &m.EndTime); err != nil {
+			return nil, fmt.Errorf("GetSystemMetrics scan: %w", err)
+		}
+		out = append(out, m)
+	}
+	return out, db.observe(rows.Err())
+}
+
+// GetSQLText returns the full text of the given SQL ID from SQL_FULLTEXT,
+// which unlike SQL_TEXT is not cut off at 1000 characters.
+func (db *DB) GetSQLText(ctx context.Context, sqlID string) (string, error) {
+	const query = `
+SELECT SQL_FULLTEXT
+FROM GV$SQL
+WHERE SQL_ID = :sqlid
+  AND (:inst = 0 OR INST_ID = :inst)
+  AND (:con  = 0 OR CON_ID  = :con)
+  AND ROWNUM = 1`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return "", fmt.Errorf("GetSQLText: %w", err)
+	}
+	// godror reads the CLOB into a string.
+	var text string
+	err = conn.QueryRowContext(ctx, query, sql.Named("sqlid", sqlID), db.instance(ctx), container(ctx)).Scan(&text)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", db.observe(fmt.Errorf("GetSQLText: %w", err))
+	}
+	return text, nil
+}
+
+// GetInstances returns the instances visible to the connection: every open
+// instance in cluster mode, otherwise just the local one.
+func (db *DB) GetInstances(ctx context.Context) ([]models.Instance, error) {
+	const query = `
+SELECT INST_ID, INSTANCE_NAME, HOST_NAME, STATUS
+FROM GV$INSTANCE
+WHERE (:inst = 0 OR INST_ID = :inst)
+ORDER BY INST_ID`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetInstances: %w", err)
+	}
+	// List every instance regardless of scope, so callers can offer the
+	// ones not currently selected.
+	rows, err := conn.QueryContext(ctx, query, db.instance(context.Background()))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetInstances: %w", err))
+	}
+	defer rows.Close()
+
+	var instances []models.Instance
+	for rows.Next() {
+		var i models.Instance
+		if err := rows.Scan(&i.InstID, &i.Name, &i.Host, &i.Status); err != nil {
+			return nil, fmt.Errorf("GetInstances scan: %w", err)
+		}
+		instances = append(instances, i)
+	}
+	return instances, db.observe(rows.Err())
+}
+
+// GetContainers returns the containers sessions can belong to: CDB$ROOT and
+// the open pluggable databases when connected to a CDB root, or a single
+// entry otherwise. PDB$SEED is omitted.
+func (db *DB) GetContainers(ctx context.Context) ([]models.Container, error) {
+	const query = `
+SELECT CON_ID, MIN(NAME) AS NAME, MIN(OPEN_MODE) AS OPEN_MODE
+FROM GV$CONTAINERS
+WHERE CON_ID <> 2
+  AND (:inst = 0 OR INST_ID = :inst)
+GROUP BY CON_ID
+ORDER BY CON_ID`
+
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetContainers: %w", err)
+	}
+	rows, err := conn.QueryContext(ctx, query, db.instance(ctx))
+	if err != nil {
+		return nil, db.observe(fmt.Errorf("GetContainers: %w", err))
+	}
+	defer rows.Close()
+
+	var containers []models.Container
+	for rows.Next() {
+		var c models.Container
+		if err := rows.Scan(&c.ConID, &c.Name, &c.OpenMode); err != nil {
+			return nil, fmt.Errorf("GetContainers scan: %w", err)
+		}
+		containers = append(containers, c)
+	}
+	return containers, db.observe(rows.Err())
+}
@@ -0,0 +1,35 @@
+package demo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// errNoSession is what Oracle reports for a session that no longer exists.
+var errNoSession = errors.New("KillSession: ORA-00030: User session ID does not exist.")
+
+// KillSession ends a simulated session: at once, or for
+// db.DisconnectPostTransaction once its current statement ends.
+func (s *Source) KillSession(ctx context.Context, target models.Session, mode db.KillMode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, ss := range s.sessions {
+		if ss.inst != target.InstID || ss.sid != target.SID || ss.serial != target.Serial {
+			continue
+		}
+		if mode == db.DisconnectPostTransaction && ss.active {
+			ss.disconnect = true
+		} else {
+			s.logoff(ss)
+		}
+		return nil
+	}
+	return errNoSession
+}
@@ -0,0 +1,264 @@
+package demo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// statement is a canned SQL statement the simulated workload runs.
+type statement struct {
+	sqlID string
+	text  string
+	plan  []models.PlanRow
+
+	// Per-execution cost profile used to grow the cumulative statistics.
+	cpuMicros int64
+	ioMicros  int64
+	gets      int64
+	reads     int64
+	rows      int64
+
+	// waits lists the wait events a session running this statement tends
+	// to sit in; the empty string means "on CPU".
+	waits []string
+
+	// locks is the table whose rows the statement locks, if any.
+	locks string
+
+	// longOps are the phases of an execution long enough to show in
+	// V$SESSION_LONGOPS, in the order they run.
+	longOps []longOpPhase
+
+	// binds are the statement's bind variables, with the values its first
+	// child cursor peeked at and last captured.
+	binds []models.Bind
+
+	// second is another child cursor of the statement, if it has one.
+	second *childCursor
+}
+
+// longOpPhase is one long-running step of an execution.
+type longOpPhase struct {
+	opName, target, units string
+	totalWork             int64
+	seconds               float64 // how long the phase usually takes
+}
+
+// childCursor is a child cursor beyond a statement's first: the plan it
+// was optimized to and why it could not share the first child.
+type childCursor struct {
+	plan   []models.PlanRow
+	reason string
+	share  float64       // fraction of the executions it runs
+	binds  []models.Bind // nil if the same as the first child's
+}
+
+// bindValue makes a peeked or captured bind value, NULL for "".
+func bindValue(text string) *models.BindValue {
+	if text == "" {
+		return &models.BindValue{Null: true}
+	}
+	return &models.BindValue{Text: text}
+}
+
+var catalog = []statement{
+	{
+		sqlID:     "7h35uxf5uhmm1",
+		text:      "SELECT o.order_id, o.status, c.name FROM orders o JOIN customers c ON c.customer_id = o.customer_id WHERE o.order_id = :1",
+		cpuMicros: 180, ioMicros: 420, gets: 9, reads: 1, rows: 1,
+		waits: []string{"", "db file sequential read"},
+		binds: []models.Bind{
+			{Position: 1, Name: ":1", Datatype: "NUMBER", Peeked: bindValue("1048576"), Captured: bindValue("1051233")},
+		},
+		plan: []models.PlanRow{
+			{ID: 0, Depth: 0, Operation: "SELECT STATEMENT", Cost: 4},
+			{ID: 1, ParentID: 0, Depth: 1, Operation: "NESTED LOOPS", Cardinality: 1, Bytes: 96, Cost: 4},
+			{ID: 2, ParentID: 1, Depth: 2, Operation: "TABLE ACCESS", Options: "BY INDEX ROWID", ObjectName: "ORDERS", Cardinality: 1, Bytes: 48, Cost: 2},
+			{ID: 3, ParentID: 2, Depth: 3, Operation: "INDEX", Options: "UNIQUE SCAN", ObjectName: "ORDERS_PK", Cardinality: 1, Cost: 1},
+			{ID: 4, ParentID: 1, Depth: 2, Operation: "TABLE ACCESS", Options: "BY INDEX ROWID", ObjectName: "CUSTOMERS", Cardinality: 1, Bytes: 48, Cost: 2},
+			{ID: 5, ParentID: 4, Depth: 3, Operation: "INDEX", Options: "UNIQUE SCAN", ObjectName: "CUSTOMERS_PK", Cardinality: 1, Cost: 1},
+		},
+	},
+	{
+		sqlID:     "3kq8d7m1wz0vb",
+		text:      "SELECT TRUNC(created_at), region, SUM(amount) FROM sales WHERE created_at >= ADD_MONTHS(SYSDATE, -12) GROUP BY TRUNC(created_at), region ORDER BY 1, 2",
+		cpuMicros: 4_200_000, ioMicros: 11_500_000, gets: 1_850_000, reads: 1_420_000, rows: 4380,
+		waits: []string{"direct path read", "direct path read", "db file scattered read", ""},
+		longOps: []longOpPhase{
+			{opName: "Table Scan", target: "APP.SALES", units: "Blocks", totalWork: 176_420, seconds: 30},
+			{opName: "Sort Output", units: "Blocks", totalWork: 9_380, seconds: 8},
+		},
+		plan: []models.PlanRow{
+			{ID: 0, Depth: 0, Operation: "SELECT STATEMENT", Cost: 48210},
+			{ID: 1, ParentID: 0, Depth: 1, Operation: "SORT", Options: "GROUP BY", Cardinality: 4380, Bytes: 131400, Cost: 48210},
+			{ID: 2, ParentID: 1, Depth: 2, Operation: "TABLE ACCESS", Options: "FULL", ObjectName: "SALES", Cardinality: 9_600_000, Bytes: 288_000_000, Cost: 47880},
+		},
+	},
+	{
+		sqlID:     "a1b2c3d4e5f6g",
+		text:      "UPDATE inventory SET qty_on_hand = qty_on_hand - :1 WHERE product_id = :2 AND warehouse_id = :3",
+		cpuMicros: 260, ioMicros: 900, gets: 14, reads: 1, rows: 1,
+		waits: []string{"", "enq: TX - row lock contention", "log file sync", "db file sequential read"},
+		locks: "APP.INVENTORY",
+		binds: []models.Bind{
+			{Position: 1, Name: ":1", Datatype: "NUMBER", Peeked: bindValue("1"), Captured: bindValue("2")},
+			{Position: 2, Name: ":2", Datatype: "NUMBER", Peeked: bindValue("40213"), Captured: bindValue("40877")},
+			{Position: 3, Name: ":3", Datatype: "NUMBER", Peeked: bindValue("3"), Captured: bindValue("3")},
+		},
+		plan: []models.PlanRow{
+			{ID: 0, Depth: 0, Operation: "UPDATE STATEMENT", Cost: 3},
+			{ID: 1, ParentID: 0, Depth: 1, Operation: "UPDATE", ObjectName: "INVENTORY"},
+			{ID: 2, ParentID: 1, Depth: 2, Operation: "INDEX", Options: "UNIQUE SCAN", ObjectName: "INVENTORY_PK", Cardinality: 1, Bytes: 22, Cost: 2},
+		},
+	},
+	{
+		sqlID:     "0w2qpuc6u2zsp",
+		text:      "INSERT INTO audit_events (event_id, event_ts, actor, payload) VALUES (audit_seq.NEXTVAL, SYSTIMESTAMP, :1, :2)",
+		cpuMicros: 140, ioMicros: 350, gets: 6, reads: 0, rows: 1,
+		waits: []string{"", "log file sync", "buffer busy waits"},
+		// LOB binds are never captured.
+		binds: []models.Bind{
+			{Position: 1, Name: ":1", Datatype: "VARCHAR2(128)", Peeked: bindValue("batch_loader"), Captured: bindValue("svc_orders")},
+			{Position: 2, Name: ":2", Datatype: "CLOB"},
+		},
+		plan: []models.PlanRow{
+			{ID: 0, Depth: 0, Operation: "INSERT STATEMENT", Cost: 1},
+			{ID: 1, ParentID: 0, Depth: 1, Operation: "LOAD TABLE CONVENTIONAL", ObjectName: "AUDIT_EVENTS"},
+			{ID: 2, ParentID: 1, Depth: 2, Operation: "SEQUENCE", ObjectName: "AUDIT_SEQ"},
+		},
+	},
+	{
+		sqlID:     "9mxk4hv2n8q7t",
+		text:      "SELECT p.product_id, p.name, SUM(l.qty) FROM order_lines l JOIN products p ON p.product_id = l.product_id WHERE l.order_date BETWEEN :1 AND :2 GROUP BY p.product_id, p.name ORDER BY 3 DESC FETCH FIRST 20 ROWS ONLY",
+		cpuMicros: 820_000, ioMicros: 1_900_000, gets: 310_000, reads: 42_000, rows: 20,
+		waits: []string{"", "db file sequential read", "db file scattered read"},
+		binds: []models.Bind{
+			{Position: 1, Name: ":1", Datatype: "DATE", Peeked: bindValue("2026-01-01 00:00:00"), Captured: bindValue("2026-04-01 00:00:00")},
+			{Position: 2, Name: ":2", Datatype: "DATE", Peeked: bindValue("2026-09-30 23:59:59"), Captured: bindValue("2026-09-30 23:59:59")},
+		},
+		// Last run with gather_plan_statistics: the date range matched far
+		// fewer lines than the optimizer expected.
+		plan: []models.PlanRow{
+			{ID: 0, Depth: 0, Operation: "SELECT STATEMENT", Cost: 9120, HasStats: true, Starts: 1, ActualRows: 20, BufferGets: 6850, ElapsedMicros: 61_200},
+			{ID: 1, ParentID: 0, Depth: 1, Operation: "VIEW", Cardinality: 20, Bytes: 1040, Cost: 9120, HasStats: true, Starts: 1, ActualRows: 20, BufferGets: 6850, ElapsedMicros: 61_190},
+			{ID: 2, ParentID: 1, Depth: 2, Operation: "WINDOW", Options: "SORT PUSHED RANK", Cardinality: 18_000, Bytes: 936_000, Cost: 9120, HasStats: true, Starts: 1, ActualRows: 20, BufferGets: 6850, ElapsedMicros: 61_180},
+			{ID: 3, ParentID: 2, Depth: 3, Operation: "HASH", Options: "GROUP BY", Cardinality: 18_000, Bytes: 936_000, Cost: 9120, HasStats: true, Starts: 1, ActualRows: 1240, BufferGets: 6850, ElapsedMicros: 60_900},
+			{ID: 4, ParentID: 3, Depth: 4, Operation: "HASH JOIN", Cardinality: 640_000, Bytes: 33_280_000, Cost: 8760, HasStats: true, Starts: 1, ActualRows: 5800, BufferGets: 6850, ElapsedMicros: 52_300},
+			{ID: 5, ParentID: 4, Depth: 5, Operation: "TABLE ACCESS", Options: "FULL", ObjectName: "PRODUCTS", Cardinality: 18_000, Bytes: 540_000, Cost: 96, HasStats: true, Starts: 1, ActualRows: 18_000, BufferGets: 340, ElapsedMicros: 4100},
+			{ID: 6, ParentID: 4, Depth: 5, Operation: "TABLE ACCESS", Options: "BY INDEX ROWID BATCHED", ObjectName: "ORDER_LINES", Cardinality: 640_000, Bytes: 14_080_000, Cost: 8610, HasStats: true, Starts: 1, ActualRows: 5800, BufferGets: 6510, ElapsedMicros: 41_800},
+			{ID: 7, ParentID: 6, Depth: 6, Operation: "INDEX", Options: "RANGE SCAN", ObjectName: "ORDER_LINES_DATE_IX", Cardinality: 640_000, Cost: 1720, HasStats: true, Starts: 1, ActualRows: 5800, BufferGets: 24, ElapsedMicros: 2900},
+		},
+		// Adaptive cursor sharing made the cursor bind aware: narrow date
+		// ranges get a plan of their own.
+		second: &childCursor{
+			reason: "Bind mismatch(33)",
+			share:  0.35,
+			binds: []models.Bind{
+				{Position: 1, Name: ":1", Datatype: "DATE", Peeked: bindValue("2026-10-12 00:00:00"), Captured: bindValue("2026-10-15 00:00:00")},
+				{Position: 2, Name: ":2", Datatype: "DATE", Peeked: bindValue("2026-10-16 23:59:59"), Captured: bindValue("2026-10-16 23:59:59")},
+			},
+			plan: []models.PlanRow{
+				{ID: 0, Depth: 0, Operation: "SELECT STATEMENT", Cost: 412},
+				{ID: 1, ParentID: 0, Depth: 1, Operation: "VIEW", Cardinality: 20, Bytes: 1040, Cost: 412},
+				{ID: 2, ParentID: 1, Depth: 2, Operation: "WINDOW", Options: "SORT PUSHED RANK", Cardinality: 1200, Bytes: 62_400, Cost: 412},
+				{ID: 3, ParentID: 2, Depth: 3, Operation: "HASH", Options: "GROUP BY", Cardinality: 1200, Bytes: 62_400, Cost: 412},
+				{ID: 4, ParentID: 3, Depth: 4, Operation: "NESTED LOOPS", Cardinality: 6000, Bytes: 312_000, Cost: 405},
+				{ID: 5, ParentID: 4, Depth: 5, Operation: "NESTED LOOPS", Cardinality: 6000, Bytes: 312_000, Cost: 405},
+				{ID: 6, ParentID: 5, Depth: 6, Operation: "TABLE ACCESS", Options: "BY INDEX ROWID BATCHED", ObjectName: "ORDER_LINES", Cardinality: 6000, Bytes: 132_000, Cost: 92},
+				{ID: 7, ParentID: 6, Depth: 7, Operation: "INDEX", Options: "RANGE SCAN", ObjectName: "ORDER_LINES_DATE_IX", Cardinality: 6000, Cost: 18},
+				{ID: 8, ParentID: 5, Depth: 6, Operation: "INDEX", Options: "UNIQUE SCAN", ObjectName: "PRODUCTS_PK", Cardinality: 1, Cost: 1},
+				{ID: 9, ParentID: 4, Depth: 5, Operation: "TABLE ACCESS", Options: "BY INDEX ROWID", ObjectName: "PRODUCTS", Cardinality: 1, Bytes: 30, Cost: 2},
+			},
+		},
+	},
+	{
+		sqlID:     "fz1p8t6y3c5jr",
+		text:      "DELETE FROM session_tokens WHERE expires_at < SYSTIMESTAMP - INTERVAL '1' DAY",
+		cpuMicros: 95_000, ioMicros: 410_000, gets: 52_000, reads: 6_100, rows: 1200,
+		waits: []string{"db file sequential read", "log file sync", "", "free buffer waits"},
+		plan: []models.PlanRow{
+			{ID: 0, Depth: 0, Operation: "DELETE STATEMENT", Cost: 812},
+			{ID: 1, ParentID: 0, Depth: 1, Operation: "DELETE", ObjectName: "SESSION_TOKENS"},
+			{ID: 2, ParentID: 1, Depth: 2, Operation: "INDEX", Options: "RANGE SCAN", ObjectName: "SESSION_TOKENS_EXP_IX", Cardinality: 1200, Bytes: 38_400, Cost: 812},
+		},
+	},
+	{
+		sqlID:     "5t8r2w0x7v4ns",
+		text:      "SELECT COUNT(*) FROM customers WHERE UPPER(email) = UPPER(:1)",
+		cpuMicros: 310_000, ioMicros: 120_000, gets: 64_000, reads: 900, rows: 1,
+		waits: []string{"", "", "db file scattered read"},
+		binds: []models.Bind{
+			{Position: 1, Name: ":1", Datatype: "VARCHAR2(128)", Peeked: bindValue("jane.o'neil@example.com"), Captured: bindValue("J.SMITH@EXAMPLE.ORG")},
+		},
+		// Last run with gather_plan_statistics: UPPER(email) defeats the
+		// column statistics, so the estimate is a guess.
+		plan: []models.PlanRow{
+			{ID: 0, Depth: 0, Operation: "SELECT STATEMENT", Cost: 1710, HasStats: true, Starts: 1, ActualRows: 1, BufferGets: 64_000, ElapsedMicros: 410_000},
+			{ID: 1, ParentID: 0, Depth: 1, Operation: "SORT", Options: "AGGREGATE", Cardinality: 1, Bytes: 28, Cost: 1710, HasStats: true, Starts: 1, ActualRows: 1, BufferGets: 64_000, ElapsedMicros: 410_000},
+			{ID: 2, ParentID: 1, Depth: 2, Operation: "TABLE ACCESS", Options: "FULL", ObjectName: "CUSTOMERS", Cardinality: 4200, Bytes: 117_600, Cost: 1710, HasStats: true, Starts: 1, ActualRows: 1, BufferGets: 64_000, ElapsedMicros: 409_800},
+		},
+		// Statistics feedback re-optimized the cursor with the row count
+		// actually seen; the plan stays the same.
+		second: &childCursor{
+			reason: "Auto Reoptimization Mismatch(1)",
+			share:  0.8,
+			plan: []models.PlanRow{
+				{ID: 0, Depth: 0, Operation: "SELECT STATEMENT", Cost: 1710},
+				{ID: 1, ParentID: 0, Depth: 1, Operation: "SORT", Options: "AGGREGATE", Cardinality: 1, Bytes: 28, Cost: 1710},
+				{ID: 2, ParentID: 1, Depth: 2, Operation: "TABLE ACCESS", Options: "FULL", ObjectName: "CUSTOMERS", Cardinality: 1, Bytes: 28, Cost: 1710},
+			},
+		},
+	},
+	{
+		sqlID:     "gq9x2m4bd7k1w",
+		text:      ormQuery(),
+		cpuMicros: 2400, ioMicros: 3100, gets: 140, reads: 4, rows: 25,
+		waits: []string{"", "db file sequential read"},
+		binds: []models.Bind{
+			{Position: 1, Name: ":1", Datatype: "NUMBER", Peeked: bindValue("88412"), Captured: bindValue("90127")},
+			{Position: 2, Name: ":2", Datatype: "VARCHAR2(32)", Peeked: bindValue("NEW"), Captured: bindValue("NEW")},
+			{Position: 3, Name: ":3", Datatype: "VARCHAR2(32)", Peeked: bindValue("PAID"), Captured: bindValue("PAID")},
+			{Position: 4, Name: ":4", Datatype: "VARCHAR2(32)", Peeked: bindValue(""), Captured: bindValue("")},
+			{Position: 5, Name: ":5", Datatype: "TIMESTAMP", Peeked: bindValue("2026-07-01 00:00:00.000000000"), Captured: bindValue("2026-07-01 00:00:00.000000000")},
+			{Position: 6, Name: ":6", Datatype: "TIMESTAMP", Peeked: bindValue("2026-09-30 23:59:59.999999000"), Captured: bindValue("2026-10-16 23:59:59.999999000")},
+		},
+		plan: []models.PlanRow{
+			{ID: 0, Depth: 0, Operation: "SELECT STATEMENT", Cost: 38},
+			{ID: 1, ParentID: 0, Depth: 1, Operation: "SORT", Options: "ORDER BY", Cardinality: 25, Bytes: 14_200, Cost: 38},
+			{ID: 2, ParentID: 1, Depth: 2, Operation: "HASH JOIN", Options: "OUTER", Cardinality: 25, Bytes: 14_200, Cost: 37},
+			{ID: 3, ParentID: 2, Depth: 3, Operation: "NESTED LOOPS", Options: "OUTER", Cardinality: 25, Bytes: 9_800, Cost: 29},
+			{ID: 4, ParentID: 3, Depth: 4, Operation: "TABLE ACCESS", Options: "BY INDEX ROWID BATCHED", ObjectName: "ORDERS", Cardinality: 25, Bytes: 4_600, Cost: 4},
+			{ID: 5, ParentID: 4, Depth: 5, Operation: "INDEX", Options: "RANGE SCAN", ObjectName: "ORDERS_CUSTOMER_IX", Cardinality: 25, Cost: 2},
+			{ID: 6, ParentID: 3, Depth: 4, Operation: "TABLE ACCESS", Options: "BY INDEX ROWID", ObjectName: "CUSTOMERS", Cardinality: 1, Bytes: 210, Cost: 1},
+			{ID: 7, ParentID: 6, Depth: 5, Operation: "INDEX", Options: "UNIQUE SCAN", ObjectName: "CUSTOMERS_PK", Cardinality: 1, Cost: 0},
+			{ID: 8, ParentID: 2, Depth: 3, Operation: "TABLE ACCESS", Options: "FULL", ObjectName: "ADDRESSES", Cardinality: 1800, Bytes: 329_400, Cost: 8},
+		},
+	},
+}
+
+// ormQuery generates a statement the way an ORM writes one: every column
+// of every entity aliased, so that it runs past V$SQL.SQL_TEXT's 1000
+// characters.
+func ormQuery() string {
+	entities := []struct {
+		table, alias string
+		columns      []string
+	}{
+		{"orders", "order0_", []string{"order_id", "customer_id", "status", "created_at", "updated_at", "total_amount", "currency", "channel", "shipping_method", "notes"}},
+		{"customers", "customer1_", []string{"customer_id", "name", "email", "phone", "segment", "created_at", "updated_at", "credit_limit"}},
+		{"addresses", "address2_", []string{"address_id", "customer_id", "street", "city", "postal_code", "country", "address_type"}},
+	}
+	var cols []string
+	for i, e := range entities {
+		for j, c := range e.columns {
+			cols = append(cols, fmt.Sprintf("%s.%s as %s%d_%d_", e.alias, c, c[:min(len(c), 8)], j+1, i))
+		}
+	}
+	return "select " + strings.Join(cols, ", ") +
+		" from orders order0_ inner join customers customer1_ on order0_.customer_id=customer1_.customer_id" +
+		" left outer join addresses address2_ on customer1_.customer_id=address2_.customer_id and address2_.address_type='SHIPPING'" +
+		" where order0_.customer_id=:1 and order0_.status in (:2, :3, :4) and order0_.created_at between :5 and :6" +
+		" order by order0_.created_at desc"
+}
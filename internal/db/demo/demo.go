@@ -0,0 +1,899 @@
+// Package demo provides a simulated Oracle instance that implements
+// db.Source, so otop can be run and developed without a database.
+package demo
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+)
+
+const idleEvent = "SQL*Net message from client"
+
+// waitClasses maps the simulated wait events to their V$EVENT_NAME class.
+var waitClasses = map[string]string{
+	"db file sequential read":       "User I/O",
+	"db file scattered read":        "User I/O",
+	"direct path read":              "User I/O",
+	"log file sync":                 "Commit",
+	"enq: TX - row lock contention": "Application",
+	"buffer busy waits":             "Concurrency",
+	"free buffer waits":             "Configuration",
+	"log file parallel write":       "System I/O",
+	"db file parallel write":        "System I/O",
+	"control file parallel write":   "System I/O",
+}
+
+// eventLatency is the average length of one wait on each simulated event.
+var eventLatency = map[string]time.Duration{
+	"db file sequential read":       600 * time.Microsecond,
+	"db file scattered read":        2 * time.Millisecond,
+	"direct path read":              1500 * time.Microsecond,
+	"log file sync":                 1200 * time.Microsecond,
+	"enq: TX - row lock contention": 80 * time.Millisecond,
+	"buffer busy waits":             300 * time.Microsecond,
+	"free buffer waits":             20 * time.Millisecond,
+	"log file parallel write":       800 * time.Microsecond,
+	"db file parallel write":        time.Millisecond,
+	"control file parallel write":   1500 * time.Microsecond,
+}
+
+// backgroundWaits are the seconds per second each instance's background
+// processes wait on the events no user session waits on.
+var backgroundWaits = map[string]float64{
+	"log file parallel write":     0.04,
+	"db file parallel write":      0.06,
+	"control file parallel write": 0.01,
+}
+
+// containers simulates a CDB root with two pluggable databases.
+var containers = []models.Container{
+	{ConID: 1, Name: "CDB$ROOT", OpenMode: "READ WRITE"},
+	{ConID: 3, Name: "SALESPDB", OpenMode: "READ WRITE"},
+	{ConID: 4, Name: "HRPDB", OpenMode: "READ WRITE"},
+}
+
+var users = []struct {
+	name, program, machine string
+	container              int // index into containers
+}{
+	{"APP_OLTP", "JDBC Thin Client", "app-01.example.com", 1},
+	{"APP_OLTP", "JDBC Thin Client", "app-02.example.com", 1},
+	{"APP_OLTP", "JDBC Thin Client", "app-03.example.com", 2},
+	{"REPORTING", "python3.11@bi-01 (TNS V1-V3)", "bi-01.example.com", 1},
+	{"BATCH", "sqlplus@batch-01 (TNS V1-V3)", "batch-01.example.com", 2},
+	{"SCOTT", "SQL Developer", "laptop-42", 0},
+}
+
+// session is the mutable simulated state behind a models.Session.
+type session struct {
+	inst        int
+	sid, serial int
+	user        int // index into users
+	active      bool
+	stmt        int // index into catalog, -1 when idle with no SQL
+	child       int // child cursor of stmt the session executes
+	event       string
+	since       time.Time // when the current wait started
+	blocker     *session  // whose row lock the session waits for
+	disconnect  bool      // log off once the current statement ends
+	longOp      *longOp   // the phase of a long execution in progress
+
+	// Cumulative V$SESSTAT / V$SESS_IO counters.
+	cpuMicros, dbMicros int64
+	gets, reads         int64
+}
+
+// metricInterval is how often the simulated V$SYSMETRIC is recomputed, as
+// often as Oracle's short-duration metrics.
+const metricInterval = 15 * time.Second
+
+// cpuCount is the number of CPUs of the simulated host.
+const cpuCount = 8
+
+// metricCounters are the cumulative counters the simulated metrics are
+// rates of.
+type metricCounters struct {
+	cpuMicros, dbMicros, gets, reads, execs, commits float64
+}
+
+// eventKey identifies a wait event's totals in one instance and container.
+type eventKey struct {
+	inst, con int
+	event     string
+}
+
+// eventTotals are the cumulative V$CON_SYSTEM_EVENT counters of an event.
+type eventTotals struct {
+	waits, micros float64
+}
+
+// Source is a simulated instance whose sessions and statistics evolve each
+// time they are observed. It is safe for concurrent use.
+type Source struct {
+	mu        sync.Mutex
+	rng       *rand.Rand
+	started   time.Time
+	last      time.Time
+	sessions  []*session
+	stats     []models.SQLStats // parallel with catalog
+	events    map[eventKey]*eventTotals
+	metrics   []models.SystemMetric // as of metricsAt
+	metricsAt time.Time
+	counters  metricCounters // as of metricsAt
+	nextSID   int
+	instances int
+}
+
+var (
+	_ db.Source         = (*Source)(nil)
+	_ db.HealthReporter = (*Source)(nil)
+	_ db.SessionKiller  = (*Source)(nil)
+	_ db.QueryRunner    = (*Source)(nil)
+)
+
+// New creates a simulated database with a small, mixed OLTP/reporting
+// workload already in flight. instances > 1 simulates a RAC cluster with
+// sessions spread across that many instances.
+func New(instances int) *Source {
+	now := time.Now()
+	s := &Source{
+		instances: max(instances, 1),
+		rng:       rand.New(rand.NewPCG(uint64(now.UnixNano()), 0x07_0b)),
+		started:   now,
+		last:      now,
+		stats:     make([]models.SQLStats, len(catalog)),
+		events:    make(map[eventKey]*eventTotals),
+		nextSID:   17,
+	}
+	for i, st := range catalog {
+		// Seed with some history so the statements look like they have
+		// been in the shared pool for a while.
+		execs := int64(50 + s.rng.IntN(5000))
+		if st.cpuMicros > 1_000_000 {
+			execs = int64(1 + s.rng.IntN(40))
+		}
+		s.stats[i] = models.SQLStats{SQLID: st.sqlID, SQLText: truncateSQL(st.text)}
+		s.execute(i, execs)
+	}
+	s.counters, s.metricsAt = s.countersNow(), now
+	for inst := 1; inst <= s.instances; inst++ {
+		for _, c := range containers {
+			for event := range eventLatency {
+				s.wait(inst, c.ConID, event, s.rng.Float64()*2000)
+			}
+		}
+	}
+	for range 24 {
+		s.spawn(now)
+	}
+	for _, ss := range s.sessions {
+		if s.rng.Float64() < 0.3 {
+			s.activate(ss, now)
+		}
+	}
+	return s
+}
+
+// Close is a no-op; the simulation holds no external resources.
+func (s *Source) Close() error { return nil }
+
+// Health reports the simulated connection, which is always up.
+func (s *Source) Health() db.Health {
+	return db.Health{State: db.Connected, Since: s.started}
+}
+
+// WatchHealth reports the (constant) health once.
+func (s *Source) WatchHealth(fn func(db.Health)) { fn(s.Health()) }
+
+// GetActiveSessions advances the simulation and returns the current sessions,
+// active first, ordered by SID like the real query.
+func (s *Source) GetActiveSessions(ctx context.Context) ([]models.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.advance(now)
+
+	scope := db.ScopeOf(ctx)
+	out := make([]models.Session, 0, len(s.sessions))
+	for _, ss := range s.sessions {
+		if scope.InstID != 0 && ss.inst != scope.InstID {
+			continue
+		}
+		if scope.ConID != 0 && containers[users[ss.user].container].ConID != scope.ConID {
+			continue
+		}
+		out = append(out, s.snapshot(ss, now))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ai, aj := out[i].Status == "ACTIVE", out[j].Status == "ACTIVE"
+		if ai != aj {
+			return ai
+		}
+		if out[i].InstID != out[j].InstID {
+			return out[i].InstID < out[j].InstID
+		}
+		return out[i].SID < out[j].SID
+	})
+	return out, nil
+}
+
+// SampleActiveSessions advances the simulation and samples the sessions
+// that are active, like an ASH sample would.
+func (s *Source) SampleActiveSessions(ctx context.Context) ([]models.ASHSample, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.advance(now)
+
+	scope := db.ScopeOf(ctx)
+	var out []models.ASHSample
+	for _, ss := range s.sessions {
+		u := users[ss.user]
+		if !ss.active || !scope.Includes(ss.inst, containers[u.container].ConID) {
+			continue
+		}
+		a := models.ASHSample{
+			SampleTime: now,
+			InstID:     ss.inst,
+			ConID:      containers[u.container].ConID,
+			SID:        ss.sid,
+			Serial:     ss.serial,
+			Username:   u.name,
+			State:      "ON CPU",
+			WaitClass:  "CPU",
+		}
+		if ss.event != "" {
+			a.State = "WAITING"
+			a.WaitClass = waitClasses[ss.event]
+			a.Event = ss.event
+		}
+		if ss.stmt >= 0 {
+			a.SQLID = s.stats[ss.stmt].SQLID
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// GetChildCursors lists sqlID's child cursors on every instance and in
+// every container in scope where a session has run it, splitting its
+// statistics between them.
+func (s *Source) GetChildCursors(ctx context.Context, sqlID string) ([]models.ChildCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	i := lookup(sqlID)
+	if i < 0 {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(time.Now())
+	st, second := s.stats[i], catalog[i].second
+
+	scope := db.ScopeOf(ctx)
+	var places [][2]int // instance and container of each parent cursor
+	for _, ss := range s.sessions {
+		place := [2]int{ss.inst, containers[users[ss.user].container].ConID}
+		if ss.stmt == i && scope.Includes(place[0], place[1]) && !slices.Contains(places, place) {
+			places = append(places, place)
+		}
+	}
+	sort.Slice(places, func(a, b int) bool {
+		if places[a][0] != places[b][0] {
+			return places[a][0] < places[b][0]
+		}
+		return places[a][1] < places[b][1]
+	})
+	hashes, shares, work := childSplit(i)
+	split := func(v int64, fraction float64) int64 {
+		return int64(float64(v) * fraction / float64(len(places)))
+	}
+
+	var out []models.ChildCursor
+	for _, place := range places {
+		for k, hash := range hashes {
+			c := models.ChildCursor{InstID: place[0], ConID: place[1], ChildNumber: k, PlanHashValue: hash}
+			if k == 1 {
+				c.Reasons = []string{second.reason}
+			}
+			c.Executions = split(st.Executions, shares[k])
+			c.ElapsedTimeMicros = split(st.ElapsedTimeMicros, work[k])
+			c.BufferGets = split(st.BufferGets, work[k])
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// childSplit returns the plan hash value of each of catalog[i]'s child
+// cursors and the fractions of the statement's executions, and of its
+// time and buffer gets, that each accounts for: executions by the child's
+// share of them, the rest also by the cost of its plan.
+func childSplit(i int) (hashes []int64, execs, work []float64) {
+	st := catalog[i]
+	if st.second == nil {
+		return []int64{planHash(st.plan)}, []float64{1}, []float64{1}
+	}
+	execs = []float64{1 - st.second.share, st.second.share}
+	work = []float64{execs[0] * float64(st.plan[0].Cost), execs[1] * float64(st.second.plan[0].Cost)}
+	total := work[0] + work[1]
+	work[0], work[1] = work[0]/total, work[1]/total
+	return []int64{planHash(st.plan), planHash(st.second.plan)}, execs, work
+}
+
+// GetTopSQL returns the statistics of every simulated statement that has
+// run, one row per plan; the catalog is small enough that n never cuts it
+// down.
+func (s *Source) GetTopSQL(ctx context.Context, n int) ([]models.SQLStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(time.Now())
+	inst := db.ScopeOf(ctx).InstID
+
+	var out []models.SQLStats
+	for i, st := range s.stats {
+		if st.Executions == 0 && st.ElapsedTimeMicros == 0 {
+			continue
+		}
+		hashes, execs, work := childSplit(i)
+		for k, hash := range hashes {
+			split := func(v int64, fraction float64) int64 { return int64(float64(v) * fraction) }
+			out = append(out, models.SQLStats{
+				InstID:            inst,
+				SQLID:             st.SQLID,
+				PlanHashValue:     hash,
+				SQLText:           st.SQLText,
+				Executions:        split(st.Executions, execs[k]),
+				ElapsedTimeMicros: split(st.ElapsedTimeMicros, work[k]),
+				CPUTimeMicros:     split(st.CPUTimeMicros, work[k]),
+				BufferGets:        split(st.BufferGets, work[k]),
+				DiskReads:         split(st.DiskReads, work[k]),
+				Rows:              split(st.Rows, execs[k]),
+			})
+		}
+	}
+	return out, nil
+}
+
+// GetSystemEvents advances the simulation and sums the wait events of the
+// instances and containers in scope.
+func (s *Source) GetSystemEvents(ctx context.Context) ([]models.SystemEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(time.Now())
+
+	scope := db.ScopeOf(ctx)
+	sums := map[string]*models.SystemEvent{}
+	for key, t := range s.events {
+		if !scope.Includes(key.inst, key.con) {
+			continue
+		}
+		e := sums[key.event]
+		if e == nil {
+			e = &models.SystemEvent{Event: key.event, WaitClass: waitClasses[key.event]}
+			sums[key.event] = e
+		}
+		e.TotalWaits += int64(t.waits)
+		e.TimeWaitedMicros += int64(t.micros)
+	}
+	out := make([]models.SystemEvent, 0, len(sums))
+	for _, e := range sums {
+		out = append(out, *e)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].TimeWaitedMicros > out[j].TimeWaitedMicros })
+	return out, nil
+}
+
+// GetSystemMetrics advances the simulation and returns metrics computed
+// from the statements' statistics over the last metricInterval, or since
+// the start on the first call a second or more after it. They cover the whole simulated database,
+// whatever the scope.
+func (s *Source) GetSystemMetrics(ctx context.Context) ([]models.SystemMetric, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.advance(now)
+
+	dt := now.Sub(s.metricsAt).Seconds()
+	// Give the first interval at least a second, for rates that mean
+	// something.
+	if dt < 1 || (s.metrics != nil && dt < metricInterval.Seconds()) {
+		return slices.Clone(s.metrics), nil
+	}
+	c := s.countersNow()
+	rate := func(now, prev float64) float64 { return (now - prev) / dt }
+	cpu := rate(c.cpuMicros, s.counters.cpuMicros) / 1e6
+	execs := rate(c.execs, s.counters.execs)
+	commits := rate(c.commits, s.counters.commits)
+	metric := func(name, unit string, v float64) models.SystemMetric {
+		return models.SystemMetric{Name: name, Unit: unit, Value: v, EndTime: now}
+	}
+	s.metrics = []models.SystemMetric{
+		metric("Host CPU Utilization (%)", "% Busy/(Idle+Busy)", min(100, 100*cpu/cpuCount+3+4*s.rng.Float64())),
+		metric("CPU Usage Per Sec", "CentiSeconds Per Second", 100*cpu),
+		metric("Average Active Sessions", "Active Sessions", rate(c.dbMicros, s.counters.dbMicros)/1e6),
+		metric("Logical Reads Per Sec", "Reads Per Second", rate(c.gets, s.counters.gets)),
+		metric("Physical Reads Per Sec", "Reads Per Second", rate(c.reads, s.counters.reads)),
+		metric("Redo Generated Per Sec", "Bytes Per Second", commits*(1500+1000*s.rng.Float64())+execs*40),
+		metric("User Calls Per Sec", "Calls Per Second", execs*(2+0.4*s.rng.Float64())),
+		metric("Executions Per Sec", "Executes Per Second", execs),
+		metric("Total Parse Count Per Sec", "Parses Per Second", execs*(0.5+0.2*s.rng.Float64())),
+		metric("Hard Parse Count Per Sec", "Parses Per Second", execs*0.005+s.rng.Float64()),
+		metric("User Commits Per Sec", "Commits Per Second", commits),
+	}
+	s.counters, s.metricsAt = c, now
+	return slices.Clone(s.metrics), nil
+}
+
+// countersNow sums the statements' statistics. Every execution of DML
+// commits. Callers must hold s.mu.
+func (s *Source) countersNow() metricCounters {
+	var c metricCounters
+	for i, st := range s.stats {
+		c.cpuMicros += float64(st.CPUTimeMicros)
+		c.dbMicros += float64(st.ElapsedTimeMicros)
+		c.gets += float64(st.BufferGets)
+		c.reads += float64(st.DiskReads)
+		c.execs += float64(st.Executions)
+		switch verb, _, _ := strings.Cut(catalog[i].text, " "); strings.ToUpper(verb) {
+		case "INSERT", "UPDATE", "DELETE", "MERGE":
+			c.commits += float64(st.Executions)
+		}
+	}
+	return c
+}
+
+// GetExecutionPlan returns the canned plan of sqlID's child cursor.
+func (s *Source) GetExecutionPlan(ctx context.Context, sqlID string, child *models.ChildCursor) ([]models.PlanRow, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	i := lookup(sqlID)
+	if i < 0 {
+		return nil, nil
+	}
+	inst, canned := max(db.ScopeOf(ctx).InstID, 1), catalog[i].plan
+	if child != nil {
+		inst = child.InstID
+		switch {
+		case child.ChildNumber == 1 && catalog[i].second != nil:
+			canned = catalog[i].second.plan
+		case child.ChildNumber != 0:
+			return nil, nil
+		}
+	}
+	plan := make([]models.PlanRow, len(canned))
+	copy(plan, canned)
+	for j := range plan {
+		plan[j].InstID = inst
+	}
+	return plan, nil
+}
+
+// planHash makes up a PLAN_HASH_VALUE from the shape of plan, so that
+// child cursors with the same plan share it.
+func planHash(plan []models.PlanRow) int64 {
+	h := fnv.New32a()
+	for _, row := range plan {
+		fmt.Fprintf(h, "%d %s %s %s\n", row.Depth, row.Operation, row.Options, row.ObjectName)
+	}
+	return int64(h.Sum32())
+}
+
+// GetSQLStats returns the simulated cumulative statistics for sqlID.
+func (s *Source) GetSQLStats(ctx context.Context, sqlID string) (*models.SQLStats, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	i := lookup(sqlID)
+	if i < 0 {
+		return nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(time.Now())
+	st := s.stats[i]
+	st.InstID = db.ScopeOf(ctx).InstID
+	return &st, nil
+}
+
+// GetSQLText returns the full text of sqlID.
+func (s *Source) GetSQLText(ctx context.Context, sqlID string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if i := lookup(sqlID); i >= 0 {
+		return catalog[i].text, nil
+	}
+	return "", nil
+}
+
+// GetBinds returns the canned bind variables of sqlID's child cursor, as
+// captured at the last 15-minute mark.
+func (s *Source) GetBinds(ctx context.Context, sqlID string, child *models.ChildCursor) ([]models.Bind, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	i := lookup(sqlID)
+	if i < 0 {
+		return nil, nil
+	}
+	canned := catalog[i].binds
+	if child != nil {
+		switch second := catalog[i].second; {
+		case child.ChildNumber == 1 && second != nil:
+			if second.binds != nil {
+				canned = second.binds
+			}
+		case child.ChildNumber != 0:
+			return nil, nil
+		}
+	}
+	binds := make([]models.Bind, len(canned))
+	copy(binds, canned)
+	for j := range binds {
+		if binds[j].Captured != nil {
+			binds[j].CapturedAt = time.Now().Truncate(15 * time.Minute)
+		}
+	}
+	return binds, nil
+}
+
+// truncateSQL cuts text off at 1000 characters, as V$SQL.SQL_TEXT does.
+func truncateSQL(text string) string {
+	if r := []rune(text); len(r) > 1000 {
+		return string(r[:1000])
+	}
+	return text
+}
+
+// GetContainers returns the simulated CDB root and its PDBs.
+func (s *Source) GetContainers(ctx context.Context) ([]models.Container, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]models.Container, len(containers))
+	copy(out, containers)
+	return out, nil
+}
+
+// GetCapabilities reports a fully licensed, fully granted 19c database.
+func (s *Source) GetCapabilities(ctx context.Context) (*models.Capabilities, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	caps := &models.Capabilities{
+		User:            "OTOP_DEMO",
+		Version:         "19.0.0.0.0",
+		Edition:         "Enterprise",
+		Banner:          "Oracle Database 19c Enterprise Edition Release 19.0.0.0.0 - Production (simulated)",
+		RAC:             s.instances > 1,
+		Multitenant:     true,
+		DiagnosticsPack: true,
+		Options:         []string{"Partitioning"},
+		Views:           make(map[string]bool, len(db.MonitoredViews)),
+	}
+	if caps.RAC {
+		caps.Options = append(caps.Options, "Real Application Clusters")
+	}
+	for _, v := range db.MonitoredViews {
+		caps.Views[v] = true
+	}
+	return caps, nil
+}
+
+// GetInstances returns the simulated instances, named like a RAC cluster.
+func (s *Source) GetInstances(ctx context.Context) ([]models.Instance, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	out := make([]models.Instance, s.instances)
+	for i := range out {
+		out[i] = models.Instance{
+			InstID: i + 1,
+			Name:   fmt.Sprintf("DEMO%d", i+1),
+			Host:   fmt.Sprintf("db-%02d.example.com", i+1),
+			Status: "OPEN",
+		}
+	}
+	return out, nil
+}
+
+// advance moves the simulation forward to now. Callers must hold s.mu.
+func (s *Source) advance(now time.Time) {
+	dt := now.Sub(s.last).Seconds()
+	if dt <= 0 {
+		return
+	}
+	s.last = now
+
+	for _, ss := range s.sessions {
+		if ss.active && ss.stmt >= 0 {
+			st := catalog[ss.stmt]
+			perExec := float64(st.cpuMicros+st.ioMicros) / 1e6
+			// Short statements run many times per second; long ones make
+			// fractional progress that still shows up in the counters.
+			execs := int64(dt / perExec * (0.2 + s.rng.Float64()*0.6))
+			if execs < 1 && s.rng.Float64() < dt/perExec {
+				execs = 1
+			}
+			d := s.execute(ss.stmt, execs)
+			// Even between executions an active session burns DB time.
+			ss.cpuMicros += d.CPUTimeMicros
+			ss.dbMicros += max(d.ElapsedTimeMicros, int64(dt*1e6))
+			ss.gets += d.BufferGets
+			ss.reads += d.DiskReads
+			s.progress(ss, now, dt)
+		}
+		if ss.active && ss.event != "" {
+			s.wait(ss.inst, containers[users[ss.user].container].ConID, ss.event, dt)
+		}
+		s.transition(ss, now, dt)
+	}
+	for inst := 1; inst <= s.instances; inst++ {
+		for event, share := range backgroundWaits {
+			s.wait(inst, containers[0].ConID, event, dt*share*(0.5+s.rng.Float64()))
+		}
+	}
+
+	// Occasional logon/logoff churn.
+	if s.rng.Float64() < 0.05*dt && len(s.sessions) < 40 {
+		s.spawn(now)
+	}
+	if s.rng.Float64() < 0.03*dt && len(s.sessions) > 12 {
+		if gone := s.sessions[s.rng.IntN(len(s.sessions))]; !gone.active {
+			s.logoff(gone)
+		}
+	}
+	// Sessions disconnected post-transaction go once their statement ends.
+	for i := len(s.sessions) - 1; i >= 0; i-- {
+		if ss := s.sessions[i]; ss.disconnect && !ss.active {
+			s.logoff(ss)
+		}
+	}
+}
+
+// wait adds secs spent waiting on event to its totals. Callers must hold
+// s.mu.
+func (s *Source) wait(inst, con int, event string, secs float64) {
+	key := eventKey{inst, con, event}
+	t := s.events[key]
+	if t == nil {
+		t = &eventTotals{}
+		s.events[key] = t
+	}
+	t.micros += secs * 1e6
+	t.waits += secs / eventLatency[event].Seconds()
+}
+
+// logoff removes gone from the instance. Logging off commits, releasing
+// the session's locks. Callers must hold s.mu.
+func (s *Source) logoff(gone *session) {
+	for i, ss := range s.sessions {
+		if ss == gone {
+			s.sessions = append(s.sessions[:i], s.sessions[i+1:]...)
+			break
+		}
+	}
+	for _, ss := range s.sessions {
+		if ss.blocker == gone {
+			ss.blocker = nil
+		}
+	}
+}
+
+// transition randomly flips a session between ACTIVE and INACTIVE and moves
+// active sessions between wait events. Callers must hold s.mu.
+func (s *Source) transition(ss *session, now time.Time, dt float64) {
+	if ss.active {
+		st := catalog[ss.stmt]
+		long := st.cpuMicros > 1_000_000
+		// Long-running statements stay active for a while.
+		pDone := 0.35
+		if long {
+			pDone = 0.04
+		}
+		if s.rng.Float64() < pDone*dt {
+			ss.active = false
+			ss.longOp = nil
+			ss.event = idleEvent
+			ss.since = now
+			s.block(ss)
+			return
+		}
+		if s.rng.Float64() < 0.5*dt {
+			if ev := st.waits[s.rng.IntN(len(st.waits))]; ev != ss.event {
+				ss.event = ev
+				ss.since = now
+				s.block(ss)
+			}
+		}
+		return
+	}
+
+	if s.rng.Float64() < s.busyness(ss)*dt {
+		s.activate(ss, now)
+	}
+}
+
+// activate starts a new statement on an idle session.
+func (s *Source) activate(ss *session, now time.Time) {
+	ss.active = true
+	ss.stmt = pickStatement(ss.user, s.rng)
+	ss.child = s.pickChild(ss.stmt)
+	st := catalog[ss.stmt]
+	ss.event = st.waits[s.rng.IntN(len(st.waits))]
+	ss.since = now
+	ss.longOp = nil
+	if len(st.longOps) > 0 {
+		ss.longOp = &longOp{start: now}
+	}
+	s.block(ss)
+}
+
+// busyness is the per-second probability that an idle session starts work.
+func (s *Source) busyness(ss *session) float64 {
+	switch users[ss.user].name {
+	case "APP_OLTP":
+		return 0.25
+	case "BATCH":
+		return 0.08
+	case "REPORTING":
+		return 0.05
+	default:
+		return 0.02
+	}
+}
+
+// pickStatement chooses a statement that fits user (an index into users).
+func pickStatement(user int, rng *rand.Rand) int {
+	var choices []int
+	switch users[user].name {
+	case "APP_OLTP":
+		choices = []int{0, 0, 0, 2, 2, 3, 3, 6, 7}
+	case "REPORTING":
+		choices = []int{1, 4, 4}
+	case "BATCH":
+		choices = []int{5, 1, 2}
+	default:
+		choices = []int{0, 4, 6}
+	}
+	return choices[rng.IntN(len(choices))]
+}
+
+// pickChild chooses which of catalog[stmt]'s child cursors an execution
+// uses, in proportion to the executions each runs.
+func (s *Source) pickChild(stmt int) int {
+	if second := catalog[stmt].second; second != nil && s.rng.Float64() < second.share {
+		return 1
+	}
+	return 0
+}
+
+// spawn adds a new idle session. Callers must hold s.mu.
+func (s *Source) spawn(now time.Time) {
+	ss := &session{
+		inst:   1 + s.rng.IntN(s.instances),
+		sid:    s.nextSID,
+		serial: 1000 + s.rng.IntN(60000),
+		user:   s.rng.IntN(len(users)),
+		stmt:   -1,
+		event:  idleEvent,
+		since:  now.Add(-time.Duration(s.rng.IntN(600)) * time.Second),
+	}
+	s.nextSID += 1 + s.rng.IntN(40)
+	// Sessions arrive with some history behind them.
+	ss.cpuMicros = int64(s.rng.IntN(30_000_000))
+	ss.dbMicros = ss.cpuMicros + int64(s.rng.IntN(60_000_000))
+	ss.gets = int64(s.rng.IntN(2_000_000))
+	ss.reads = ss.gets / int64(20+s.rng.IntN(200))
+	// Most idle sessions still report the last statement they ran.
+	if s.rng.Float64() < 0.7 {
+		ss.stmt = pickStatement(ss.user, s.rng)
+		ss.child = s.pickChild(ss.stmt)
+	}
+	s.sessions = append(s.sessions, ss)
+}
+
+// execute records n executions of catalog[i] in the cumulative statistics
+// and returns the increments. Callers must hold s.mu.
+func (s *Source) execute(i int, n int64) models.SQLStats {
+	if n <= 0 {
+		return models.SQLStats{}
+	}
+	st := catalog[i]
+	jitter := func(v int64) int64 {
+		return int64(float64(v*n) * (0.8 + s.rng.Float64()*0.4))
+	}
+	cpu := jitter(st.cpuMicros)
+	d := models.SQLStats{
+		Executions:        n,
+		CPUTimeMicros:     cpu,
+		ElapsedTimeMicros: cpu + jitter(st.ioMicros),
+		BufferGets:        jitter(st.gets),
+		DiskReads:         jitter(st.reads),
+		Rows:              st.rows * n,
+	}
+	s.stats[i].Executions += d.Executions
+	s.stats[i].CPUTimeMicros += d.CPUTimeMicros
+	s.stats[i].ElapsedTimeMicros += d.ElapsedTimeMicros
+	s.stats[i].BufferGets += d.BufferGets
+	s.stats[i].DiskReads += d.DiskReads
+	s.stats[i].Rows += d.Rows
+	return d
+}
+
+// snapshot renders the simulated session as V$SESSION would report it.
+func (s *Source) snapshot(ss *session, now time.Time) models.Session {
+	u := users[ss.user]
+	out := models.Session{
+		InstID:         ss.inst,
+		ConID:          containers[u.container].ConID,
+		PDBName:        containers[u.container].Name,
+		SID:            ss.sid,
+		Serial:         ss.serial,
+		Username:       u.name,
+		Status:         "INACTIVE",
+		Program:        u.program,
+		Machine:        u.machine,
+		WaitEvent:      ss.event,
+		WaitSeconds:    now.Sub(ss.since).Truncate(time.Second).Seconds(),
+		CPUTime:        float64(ss.cpuMicros) / 1e6,
+		DBTime:         float64(ss.dbMicros) / 1e6,
+		PhysicalReads:  ss.reads,
+		LogicalReads:   ss.gets,
+		SQLChildNumber: -1,
+	}
+	if ss.active {
+		out.Status = "ACTIVE"
+		if ss.event == "" {
+			// Report CPU time the way ASH does rather than a stale wait.
+			out.WaitEvent = "ON CPU"
+			out.WaitSeconds = 0
+		}
+	}
+	if ss.stmt >= 0 {
+		st := s.stats[ss.stmt]
+		out.SQLID = st.SQLID
+		out.SQLChildNumber = ss.child
+		out.SQLText = st.SQLText
+	}
+	return out
+}
+
+func lookup(sqlID string) int {
+	for i, st := range catalog {
+		if st.sqlID == sqlID {
+			return i
+		}
+	}
+	return -1
+}
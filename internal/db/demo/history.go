@@ -0,0 +1,116 @@
+package demo
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// historySessions is how many distinct sessions the synthetic history draws
+// its samples from, so that some of them stand out as top sessions.
+const historySessions = 30
+
+// GetActiveSessionHistory synthesises ASH samples for any time range: a
+// workload that follows the time of day, with a burst of row lock
+// contention at the top of every third hour. The history is a pure
+// function of time, so the same range always looks the same. Like Oracle,
+// it samples every second for the last hour and every ten seconds before.
+func (s *Source) GetActiveSessionHistory(ctx context.Context, from, to time.Time, step time.Duration) ([]models.ASHSample, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	step = max(step, time.Second)
+	now := time.Now()
+	inMemory := now.Add(-time.Hour)
+	scope := db.ScopeOf(ctx)
+
+	type key struct {
+		bucket int64
+		sample models.ASHSample // without SampleTime and Interval
+	}
+	index := map[key]int{}
+	var out []models.ASHSample
+	t := from.Truncate(time.Second)
+	for t.Before(to) && t.Before(now) {
+		interval := time.Second
+		if t.Before(inMemory) {
+			interval = 10 * time.Second
+			t = t.Truncate(interval)
+		}
+		for _, a := range s.historyAt(t) {
+			if !scope.Includes(a.InstID, a.ConID) || t.Before(from) {
+				continue
+			}
+			k := key{bucket: int64(t.Sub(from) / step), sample: a}
+			i, ok := index[k]
+			if !ok {
+				i = len(out)
+				index[k] = i
+				a.SampleTime = from.Add(time.Duration(k.bucket) * step)
+				out = append(out, a)
+			}
+			out[i].Interval += interval
+		}
+		t = t.Add(interval)
+	}
+	return out, nil
+}
+
+// historyAt returns the sessions that were active at t.
+func (s *Source) historyAt(t time.Time) []models.ASHSample {
+	rng := rand.New(rand.NewPCG(uint64(t.Unix()), 0xa5_4a))
+	hour := float64(t.Hour()) + float64(t.Minute())/60
+	// Busiest mid-afternoon, quietest in the small hours.
+	load := 3 + 2.5*math.Sin(2*math.Pi*(hour-9)/24)
+	n := min(int(load*(0.5+rng.Float64()))+s.instances-1, historySessions)
+
+	// Each session is sampled at most once.
+	sessions := rng.Perm(historySessions)
+	out := make([]models.ASHSample, 0, n)
+	for _, i := range sessions[:n] {
+		out = append(out, s.historySample(i, rng))
+	}
+	if t.Hour()%3 == 0 && t.Minute() < 10 {
+		// Batch locks inventory rows that the OLTP sessions queue behind.
+		queued := 2 + rng.IntN(4)
+		for _, i := range sessions[n:] {
+			if queued == 0 {
+				break
+			}
+			if users[i%len(users)].name != "APP_OLTP" {
+				continue
+			}
+			a := s.historySample(i, rng)
+			a.SQLID = catalog[2].sqlID
+			a.State, a.WaitClass, a.Event = "WAITING", waitClasses["enq: TX - row lock contention"], "enq: TX - row lock contention"
+			out = append(out, a)
+			queued--
+		}
+	}
+	return out
+}
+
+// historySample returns the session numbered i of the synthetic history
+// running a statement that fits its user.
+func (s *Source) historySample(i int, rng *rand.Rand) models.ASHSample {
+	user := i % len(users)
+	stmt := catalog[pickStatement(user, rng)]
+	a := models.ASHSample{
+		InstID:    1 + i%s.instances,
+		ConID:     containers[users[user].container].ConID,
+		SID:       17 + 23*i,
+		Serial:    1000 + 7919*i%60000,
+		Username:  users[user].name,
+		SQLID:     stmt.sqlID,
+		State:     "ON CPU",
+		WaitClass: "CPU",
+	}
+	if ev := stmt.waits[rng.IntN(len(stmt.waits))]; ev != "" {
+		a.State, a.WaitClass, a.Event = "WAITING", waitClasses[ev], ev
+	}
+	return a
+}
@@ -0,0 +1,125 @@
+package demo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// lockEvent is the wait of a session queued behind another's row lock.
+const lockEvent = "enq: TX - row lock contention"
+
+// block picks the session ss queues behind if it has just started waiting
+// for a row lock: usually an idle session sitting on an uncommitted change,
+// sometimes another waiter, which makes a longer chain. Callers must hold
+// s.mu.
+func (s *Source) block(ss *session) {
+	ss.blocker = nil
+	if ss.event != lockEvent {
+		return
+	}
+	var idle, waiting []*session
+	for _, o := range s.sessions {
+		if o == ss || users[o.user].container != users[ss.user].container || s.waitsFor(o, ss) {
+			continue
+		}
+		switch {
+		case !o.active:
+			idle = append(idle, o)
+		case o.blocker != nil:
+			waiting = append(waiting, o)
+		}
+	}
+	switch {
+	case len(waiting) > 0 && s.rng.Float64() < 0.4:
+		ss.blocker = waiting[s.rng.IntN(len(waiting))]
+	case len(idle) > 0:
+		ss.blocker = idle[s.rng.IntN(len(idle))]
+	}
+}
+
+// waitsFor reports whether ss is somewhere in the chain of sessions o waits
+// for, so that blocking o on ss would make a deadlock.
+func (s *Source) waitsFor(o, ss *session) bool {
+	for b := o.blocker; b != nil; b = b.blocker {
+		if b == ss {
+			return true
+		}
+	}
+	return false
+}
+
+// GetLockWaits returns the simulated sessions waiting for row locks in the
+// scope of ctx, and every session in their blocking chains.
+func (s *Source) GetLockWaits(ctx context.Context) ([]models.LockWait, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.advance(now)
+
+	scope := db.ScopeOf(ctx)
+	involved := map[*session]bool{}
+	blocking := map[*session]bool{}
+	for _, ss := range s.sessions {
+		if ss.blocker == nil || !scope.Includes(ss.inst, containers[users[ss.user].container].ConID) {
+			continue
+		}
+		for b := ss; b != nil; b = b.blocker {
+			involved[b] = true
+			if b.blocker != nil {
+				blocking[b.blocker] = true
+			}
+		}
+	}
+
+	out := make([]models.LockWait, 0, len(involved))
+	for ss := range involved {
+		u := users[ss.user]
+		w := models.LockWait{
+			InstID:      ss.inst,
+			ConID:       containers[u.container].ConID,
+			SID:         ss.sid,
+			Serial:      ss.serial,
+			Username:    u.name,
+			Program:     u.program,
+			Status:      "INACTIVE",
+			Event:       ss.event,
+			WaitSeconds: now.Sub(ss.since).Truncate(time.Second).Seconds(),
+			LockType:    "TX",
+		}
+		if ss.active {
+			w.Status = "ACTIVE"
+		}
+		if ss.stmt >= 0 {
+			w.SQLID = catalog[ss.stmt].sqlID
+		}
+		if blocking[ss] {
+			w.ModeHeld = "X"
+		}
+		if ss.blocker != nil {
+			w.BlockingInstID, w.BlockingSID = ss.blocker.inst, ss.blocker.sid
+			final := ss.blocker
+			for final.blocker != nil {
+				final = final.blocker
+			}
+			w.FinalBlockingInstID, w.FinalBlockingSID = final.inst, final.sid
+			w.ModeRequested = "X"
+			w.Object = catalog[ss.stmt].locks
+		}
+		out = append(out, w)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].InstID != out[j].InstID {
+			return out[i].InstID < out[j].InstID
+		}
+		return out[i].SID < out[j].SID
+	})
+	return out, nil
+}
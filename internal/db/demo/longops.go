@@ -0,0 +1,88 @@
+package demo
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// longOpThreshold is how long an operation runs before Oracle lists it in
+// V$SESSION_LONGOPS.
+const longOpThreshold = 6 * time.Second
+
+// longOp is a session's progress through the phases of a long execution.
+type longOp struct {
+	phase int // index into the statement's longOps
+	start time.Time
+	soFar float64
+}
+
+// progress moves ss's long operation on by dt seconds of work, starting
+// the next phase once one is done. Callers must hold s.mu.
+func (s *Source) progress(ss *session, now time.Time, dt float64) {
+	op := ss.longOp
+	if op == nil {
+		return
+	}
+	phases := catalog[ss.stmt].longOps
+	p := phases[op.phase]
+	op.soFar += float64(p.totalWork) / p.seconds * dt * (0.6 + 0.8*s.rng.Float64())
+	if op.soFar < float64(p.totalWork) {
+		return
+	}
+	if op.phase+1 == len(phases) {
+		ss.longOp = nil
+		return
+	}
+	ss.longOp = &longOp{phase: op.phase + 1, start: now}
+}
+
+// GetLongOps returns the phases in progress, in the scope of ctx, that
+// have run long enough to be listed.
+func (s *Source) GetLongOps(ctx context.Context) ([]models.LongOp, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.advance(now)
+
+	scope := db.ScopeOf(ctx)
+	var out []models.LongOp
+	for _, ss := range s.sessions {
+		u := users[ss.user]
+		op := ss.longOp
+		if op == nil || now.Sub(op.start) < longOpThreshold || !scope.Includes(ss.inst, containers[u.container].ConID) {
+			continue
+		}
+		p := catalog[ss.stmt].longOps[op.phase]
+		elapsed := now.Sub(op.start).Truncate(time.Second)
+		lo := models.LongOp{
+			InstID:    ss.inst,
+			ConID:     containers[u.container].ConID,
+			SID:       ss.sid,
+			Serial:    ss.serial,
+			Username:  u.name,
+			SQLID:     s.stats[ss.stmt].SQLID,
+			OpName:    p.opName,
+			Target:    p.target,
+			SoFar:     int64(op.soFar),
+			TotalWork: p.totalWork,
+			Units:     p.units,
+			StartTime: op.start,
+			Elapsed:   elapsed,
+			Remaining: -1,
+		}
+		// Oracle extrapolates from the rate so far.
+		if lo.SoFar > 0 {
+			lo.Remaining = time.Duration(float64(elapsed) * float64(lo.TotalWork-lo.SoFar) / float64(lo.SoFar)).Truncate(time.Second)
+		}
+		out = append(out, lo)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].StartTime.Before(out[j].StartTime) })
+	return out, nil
+}
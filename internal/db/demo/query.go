@@ -0,0 +1,152 @@
+package demo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mdoeren/otop/internal/db"
+)
+
+// errNoTable is what Oracle reports for a table the user cannot see.
+var errNoTable = errors.New("RunQuery: ORA-00942: table or view does not exist")
+
+// fromClause finds the first table a SELECT reads.
+var fromClause = regexp.MustCompile(`(?is)^\s*(?:with\b.*?\)\s*)?select\b.*?\bfrom\s+([a-z0-9_$#.]+)`)
+
+// objectRows is how many rows the simulated DBA_OBJECTS has: enough that a
+// full fetch is slow, can be cancelled and runs into the row cap.
+const objectRows = 60_000
+
+// RunQuery answers SELECTs on a few simulated views — DUAL, V$SESSION,
+// V$SQL and DBA_OBJECTS — ignoring the select list and WHERE clause. Any
+// other statement succeeds without changing anything, unless opts.ReadOnly
+// refuses it.
+func (s *Source) RunQuery(ctx context.Context, query string, opts db.QueryOptions, columns func([]string), rows func([][]string)) (db.QueryResult, error) {
+	if err := ctx.Err(); err != nil {
+		return db.QueryResult{}, err
+	}
+	if opts.ReadOnly {
+		if err := db.CheckReadOnly(query); err != nil {
+			return db.QueryResult{}, err
+		}
+	}
+	maxRows := opts.MaxRows
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	m := fromClause.FindStringSubmatch(query)
+	if m == nil {
+		if strings.HasPrefix(strings.ToUpper(query), "SELECT") {
+			return db.QueryResult{}, errors.New("RunQuery: ORA-00923: FROM keyword not found where expected")
+		}
+		return db.QueryResult{}, nil
+	}
+
+	table := strings.ToUpper(m[1])
+	table = table[strings.LastIndex(table, ".")+1:]
+	var names []string
+	var data [][]string
+	switch strings.TrimPrefix(table, "G") {
+	case "DUAL":
+		names, data = []string{"DUMMY"}, [][]string{{"X"}}
+	case "V$SESSION":
+		names, data = s.sessionRows()
+	case "V$SQL":
+		names, data = s.sqlRows()
+	case "DBA_OBJECTS", "ALL_OBJECTS", "USER_OBJECTS", "CDB_OBJECTS":
+		return streamObjects(ctx, maxRows, columns, rows)
+	default:
+		return db.QueryResult{}, errNoTable
+	}
+
+	columns(names)
+	var result db.QueryResult
+	if len(data) > maxRows {
+		data, result.Truncated = data[:maxRows], true
+	}
+	result.Rows = len(data)
+	if len(data) > 0 {
+		rows(data)
+	}
+	return result, nil
+}
+
+// sessionRows lists the simulated sessions like V$SESSION.
+func (s *Source) sessionRows() ([]string, [][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(time.Now())
+	names := []string{"INST_ID", "SID", "SERIAL#", "USERNAME", "STATUS", "SQL_ID", "EVENT", "PROGRAM", "MACHINE"}
+	var data [][]string
+	for _, ss := range s.sessions {
+		u := users[ss.user]
+		status, sqlID := "INACTIVE", ""
+		if ss.active {
+			status = "ACTIVE"
+		}
+		if ss.stmt >= 0 {
+			sqlID = catalog[ss.stmt].sqlID
+		}
+		data = append(data, []string{
+			fmt.Sprint(ss.inst), fmt.Sprint(ss.sid), fmt.Sprint(ss.serial),
+			u.name, status, sqlID, ss.event, u.program, u.machine,
+		})
+	}
+	return names, data
+}
+
+// sqlRows lists the simulated statements like V$SQL.
+func (s *Source) sqlRows() ([]string, [][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(time.Now())
+	names := []string{"SQL_ID", "EXECUTIONS", "ELAPSED_TIME", "CPU_TIME", "BUFFER_GETS", "DISK_READS", "ROWS_PROCESSED", "SQL_TEXT"}
+	var data [][]string
+	for _, st := range s.stats {
+		data = append(data, []string{
+			st.SQLID, fmt.Sprint(st.Executions), fmt.Sprint(st.ElapsedTimeMicros), fmt.Sprint(st.CPUTimeMicros),
+			fmt.Sprint(st.BufferGets), fmt.Sprint(st.DiskReads), fmt.Sprint(st.Rows), st.SQLText,
+		})
+	}
+	return names, data
+}
+
+// streamObjects fetches a large made-up DBA_OBJECTS slowly, in batches, the
+// way a big result set arrives over the network.
+func streamObjects(ctx context.Context, maxRows int, columns func([]string), rows func([][]string)) (db.QueryResult, error) {
+	columns([]string{"OWNER", "OBJECT_NAME", "OBJECT_TYPE", "OBJECT_ID", "CREATED", "STATUS"})
+	owners := []string{"SYS", "SYSTEM", "APP", "REPORTING", "BATCH"}
+	types := []string{"TABLE", "INDEX", "VIEW", "PACKAGE", "PACKAGE BODY", "SEQUENCE", "SYNONYM"}
+	created := time.Date(2019, 3, 14, 9, 0, 0, 0, time.UTC)
+
+	var result db.QueryResult
+	for result.Rows < objectRows {
+		if result.Rows == maxRows {
+			result.Truncated = true
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return result, fmt.Errorf("RunQuery: ORA-01013: user requested cancel of current operation: %w", ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+		n := min(50, objectRows-result.Rows, maxRows-result.Rows)
+		batch := make([][]string, n)
+		for i := range batch {
+			id := result.Rows + i + 1
+			batch[i] = []string{
+				owners[id%len(owners)],
+				fmt.Sprintf("%s_%05d", types[id%len(types)][:3], id),
+				types[id%len(types)],
+				fmt.Sprint(id),
+				created.Add(time.Duration(id) * 37 * time.Minute).Format("2006-01-02 15:04:05"),
+				"VALID",
+			}
+		}
+		rows(batch)
+		result.Rows += n
+	}
+	return result, nil
+}
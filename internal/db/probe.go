@@ -0,0 +1,126 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// MonitoredViews lists every view otop queries. The capability probe checks
+// each one so panels can be disabled up front instead of failing with a raw
+// ORA-00942 later.
+var MonitoredViews = []string{
+	"GV$SESSION",
+	"GV$SQL",
+	"GV$SQL_PLAN_STATISTICS_ALL",
+	"GV$SQL_SHARED_CURSOR",
+	"GV$SQL_BIND_CAPTURE",
+	"GV$SQLSTATS",
+	"GV$SESSION_WAIT",
+	"GV$SYSTEM_EVENT",
+	"GV$CON_SYSTEM_EVENT",
+	"GV$SYSMETRIC",
+	"GV$CON_SYSMETRIC",
+	"GV$SESSTAT",
+	"GV$STATNAME",
+	"GV$SESS_IO",
+	"GV$ACTIVE_SESSION_HISTORY",
+	"DBA_HIST_ACTIVE_SESS_HISTORY",
+	"CDB_USERS",
+	"GV$LOCK",
+	"GV$SESSION_LONGOPS",
+	"CDB_OBJECTS",
+	"GV$INSTANCE",
+	"GV$CONTAINERS",
+	"V$PARAMETER",
+	"V$DATABASE",
+	"V$OPTION",
+}
+
+// GetCapabilities probes the database version, edition, options and the
+// views the connecting user can read. Individual probes that fail leave
+// their field at its zero value; only cancellation is returned as an error.
+func (db *DB) GetCapabilities(ctx context.Context) (*models.Capabilities, error) {
+	conn, err := db.pool()
+	if err != nil {
+		return nil, fmt.Errorf("GetCapabilities: %w", err)
+	}
+	caps := &models.Capabilities{Views: make(map[string]bool)}
+
+	scan := func(query string, dest ...any) bool {
+		ctx, cancel := db.withTimeout(ctx)
+		defer cancel()
+		return conn.QueryRowContext(ctx, query).Scan(dest...) == nil
+	}
+
+	for _, view := range MonitoredViews {
+		caps.Views[view] = db.canRead(ctx, conn, view)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	scan(`SELECT USER FROM DUAL`, &caps.User)
+	scan(`SELECT BANNER FROM V$VERSION WHERE ROWNUM = 1`, &caps.Banner)
+	caps.Edition = editionFromBanner(caps.Banner)
+	scan(`SELECT VERSION FROM V$INSTANCE`, &caps.Version)
+
+	var value string
+	if scan(`SELECT VALUE FROM V$PARAMETER WHERE NAME = 'cluster_database'`, &value) {
+		caps.RAC = strings.EqualFold(value, "TRUE")
+	}
+	if scan(`SELECT NVL(VALUE, 'NONE') FROM V$PARAMETER WHERE NAME = 'control_management_pack_access'`, &value) {
+		caps.DiagnosticsPack = strings.Contains(strings.ToUpper(value), "DIAGNOSTIC")
+	}
+	if scan(`SELECT CDB FROM V$DATABASE`, &value) {
+		caps.Multitenant = value == "YES"
+	}
+
+	func() {
+		ctx, cancel := db.withTimeout(ctx)
+		defer cancel()
+		rows, err := conn.QueryContext(ctx, `SELECT PARAMETER FROM V$OPTION WHERE VALUE = 'TRUE' ORDER BY PARAMETER`)
+		if err != nil {
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var opt string
+			if rows.Scan(&opt) == nil {
+				caps.Options = append(caps.Options, opt)
+			}
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return caps, nil
+}
+
+// canRead reports whether the connecting user can select from view.
+func (db *DB) canRead(ctx context.Context, conn *sql.DB, view string) bool {
+	ctx, cancel := db.withTimeout(ctx)
+	defer cancel()
+	// view comes from MonitoredViews, never from user input.
+	rows, err := conn.QueryContext(ctx, "SELECT 1 FROM "+view+" WHERE 1 = 0")
+	if err != nil {
+		return false
+	}
+	rows.Close()
+	return true
+}
+
+// editionFromBanner extracts the edition from a V$VERSION banner such as
+// "Oracle Database 19c Enterprise Edition Release 19.0.0.0.0 - Production".
+func editionFromBanner(banner string) string {
+	for _, e := range []string{"Enterprise", "Standard", "Express", "Personal", "Free"} {
+		if strings.Contains(banner, e+" Edition") || strings.Contains(banner, " "+e+" ") {
+			return e
+		}
+	}
+	return ""
+}
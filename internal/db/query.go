@@ -0,0 +1,334 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// queryBatch is how many fetched rows RunQuery hands over at a time.
+const queryBatch = 100
+
+// ErrNotQuery is returned by RunQuery in read-only mode for a statement that
+// is not a query.
+var ErrNotQuery = errors.New("read-only: only queries (SELECT, WITH) may run; DML and DDL need a read-write editor")
+
+// ErrInlinePLSQL is returned by RunQuery in read-only mode for a query whose
+// WITH clause declares a PL/SQL function or procedure.
+var ErrInlinePLSQL = errors.New("read-only: a WITH clause may not declare PL/SQL functions or procedures; they need a read-write editor")
+
+// ErrForUpdate is returned by RunQuery in read-only mode for a query that
+// locks the rows it selects.
+var ErrForUpdate = errors.New("read-only: SELECT ... FOR UPDATE locks rows; it needs a read-write editor")
+
+// QueryOptions control how RunQuery runs a statement.
+type QueryOptions struct {
+	// MaxRows caps the rows fetched.
+	MaxRows int
+
+	// ReadOnly refuses any statement but a plain query, as CheckReadOnly
+	// does, and runs queries in a read-only transaction. That stops the
+	// statement itself changing the database, but not a stored function it
+	// calls that runs an autonomous transaction: it guards against
+	// mistakes, and is no substitute for a user without write privileges.
+	ReadOnly bool
+
+	// Binds are passed to the statement, as from ParseBinds.
+	Binds []any
+}
+
+// QueryResult summarises a statement run by RunQuery.
+type QueryResult struct {
+	Rows         int   // rows fetched
+	Truncated    bool  // fetching stopped at the row cap with more rows left
+	RowsAffected int64 // rows changed, for statements that return none
+}
+
+// QueryRunner is implemented by sources that can run SQL typed by the user,
+// such as the query editor's.
+type QueryRunner interface {
+	// RunQuery executes query as opts say. If it returns rows, columns is
+	// called once with their names and rows with each batch fetched, as
+	// strings, until opts.MaxRows have been fetched. Cancelling ctx aborts
+	// the statement.
+	RunQuery(ctx context.Context, query string, opts QueryOptions, columns func([]string), rows func([][]string)) (QueryResult, error)
+}
+
+var _ QueryRunner = (*DB)(nil)
+
+// RunQuery runs query on the connection pool, outside the monitoring
+// queries' timeout: a statement runs until it finishes or ctx is cancelled.
+// A trailing semicolon or SQL*Plus slash is dropped, except after a PL/SQL
+// block's END. In read-only mode the query runs under SET TRANSACTION READ
+// ONLY, which is rolled back afterwards.
+func (db *DB) RunQuery(ctx context.Context, query string, opts QueryOptions, columns func([]string), rows func([][]string)) (QueryResult, error) {
+	query = trimStatement(query)
+	if opts.ReadOnly {
+		if err := CheckReadOnly(query); err != nil {
+			return QueryResult{}, err
+		}
+	}
+	pool, err := db.pool()
+	if err != nil {
+		return QueryResult{}, fmt.Errorf("RunQuery: %w", err)
+	}
+	var conn queryer = pool
+	if opts.ReadOnly {
+		tx, err := pool.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return QueryResult{}, db.observe(fmt.Errorf("RunQuery: read-only transaction: %w", err))
+		}
+		defer tx.Rollback()
+		conn = tx
+	}
+	if !IsQuery(query) {
+		res, err := conn.ExecContext(ctx, query, opts.Binds...)
+		if err != nil {
+			return QueryResult{}, db.observe(fmt.Errorf("RunQuery: %w", err))
+		}
+		n, _ := res.RowsAffected()
+		return QueryResult{RowsAffected: n}, nil
+	}
+
+	rs, err := conn.QueryContext(ctx, query, opts.Binds...)
+	if err != nil {
+		return QueryResult{}, db.observe(fmt.Errorf("RunQuery: %w", err))
+	}
+	defer rs.Close()
+	names, err := rs.Columns()
+	if err != nil {
+		return QueryResult{}, fmt.Errorf("RunQuery columns: %w", err)
+	}
+	columns(names)
+
+	var result QueryResult
+	values := make([]any, len(names))
+	dest := make([]any, len(names))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	batch := make([][]string, 0, queryBatch)
+	for rs.Next() {
+		if result.Rows == opts.MaxRows {
+			result.Truncated = true
+			break
+		}
+		if err := rs.Scan(dest...); err != nil {
+			return result, fmt.Errorf("RunQuery scan: %w", err)
+		}
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = formatValue(v)
+		}
+		batch = append(batch, row)
+		result.Rows++
+		if len(batch) == queryBatch {
+			rows(batch)
+			batch = make([][]string, 0, queryBatch)
+		}
+	}
+	if len(batch) > 0 {
+		rows(batch)
+	}
+	return result, db.observe(rs.Err())
+}
+
+// queryer is what RunQuery needs of a connection pool or transaction.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// trimStatement drops surrounding blanks and the terminator SQL*Plus
+// scripts put after a statement.
+func trimStatement(query string) string {
+	query = strings.TrimSpace(query)
+	if i := strings.LastIndex(query, "\n"); i >= 0 && strings.TrimSpace(query[i:]) == "/" {
+		query = strings.TrimSpace(query[:i])
+	}
+	upper := strings.ToUpper(query)
+	if strings.HasSuffix(upper, "END;") || !strings.HasSuffix(query, ";") {
+		return query
+	}
+	return strings.TrimSpace(strings.TrimSuffix(query, ";"))
+}
+
+// ParseBinds splits the SQL*Plus bind variable declarations that lead
+// script off the statement after them, returning the variables as named
+// binds. It understands
+//
+//	VAR[IABLE] name type
+//	EXEC[UTE] :name := value;
+//
+// where value is a quoted string, a number or NULL. A declared variable that
+// is never assigned is NULL.
+func ParseBinds(script string) (string, []any, error) {
+	var names []string
+	values := map[string]any{}
+	rest := script
+	for {
+		line, after, _ := strings.Cut(rest, "\n")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			if after == "" {
+				break
+			}
+			rest = after
+			continue
+		}
+		switch keyword := strings.ToUpper(fields[0]); {
+		case keyword == "VAR" || keyword == "VARIABLE":
+			if len(fields) < 3 {
+				return "", nil, fmt.Errorf("ParseBinds: %q: want VARIABLE name type", strings.TrimSpace(line))
+			}
+			name := strings.ToUpper(fields[1])
+			if _, ok := values[name]; !ok {
+				names = append(names, name)
+			}
+			values[name] = nil
+		case (keyword == "EXEC" || keyword == "EXECUTE") && len(fields) > 1 && strings.HasPrefix(fields[1], ":"):
+			name, value, ok := strings.Cut(strings.TrimSpace(line[len(fields[0]):]), ":=")
+			name = strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(name), ":"))
+			if _, declared := values[name]; !ok || !declared {
+				return "", nil, fmt.Errorf("ParseBinds: %q: want EXEC :name := value for a declared variable", strings.TrimSpace(line))
+			}
+			v, err := bindLiteral(strings.TrimSuffix(strings.TrimSpace(value), ";"))
+			if err != nil {
+				return "", nil, fmt.Errorf("ParseBinds: %q: %w", strings.TrimSpace(line), err)
+			}
+			values[name] = v
+		default:
+			binds := make([]any, len(names))
+			for i, name := range names {
+				binds[i] = sql.Named(name, values[name])
+			}
+			return rest, binds, nil
+		}
+		rest = after
+	}
+	return "", nil, errors.New("ParseBinds: no statement after the bind variables")
+}
+
+// bindLiteral parses the value assigned to a bind variable.
+func bindLiteral(v string) (any, error) {
+	v = strings.TrimSpace(v)
+	switch {
+	case strings.EqualFold(v, "NULL"):
+		return nil, nil
+	case len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'':
+		return strings.ReplaceAll(v[1:len(v)-1], "''", "'"), nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return n, nil
+	}
+	if _, err := strconv.ParseFloat(v, 64); err == nil {
+		// Oracle converts the text exactly; a float64 might not.
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s is not a quoted string, number or NULL", v)
+}
+
+// IsQuery reports whether query is a SELECT, which returns rows, rather
+// than DML, DDL or PL/SQL.
+func IsQuery(query string) bool {
+	switch firstKeyword(query) {
+	case "SELECT", "WITH":
+		return true
+	}
+	return false
+}
+
+// CheckReadOnly returns nil if query is a plain query, safe to run in
+// read-only mode, or else why it is not: ErrNotQuery for anything but a
+// SELECT, ErrInlinePLSQL for a WITH clause declaring PL/SQL, which could do
+// anything, and ErrForUpdate for a SELECT that locks rows.
+func CheckReadOnly(query string) error {
+	if !IsQuery(query) {
+		return ErrNotQuery
+	}
+	w := words(query)
+	for i := 1; i < len(w); i++ {
+		switch {
+		case w[i-1] == "WITH" && (w[i] == "FUNCTION" || w[i] == "PROCEDURE"):
+			return ErrInlinePLSQL
+		case w[i-1] == "FOR" && w[i] == "UPDATE":
+			return ErrForUpdate
+		}
+	}
+	return nil
+}
+
+// words returns the upper-cased words of query, leaving out comments,
+// string literals and quoted identifiers.
+func words(query string) []string {
+	var out []string
+	for len(query) > 0 {
+		switch c := query[0]; {
+		case strings.HasPrefix(query, "--"):
+			_, query, _ = strings.Cut(query, "\n")
+		case strings.HasPrefix(query, "/*"):
+			_, query, _ = strings.Cut(query, "*/")
+		case c == '\'' || c == '"':
+			// A doubled quote inside a literal reads as the end of one
+			// literal and the start of the next, which is as good.
+			_, query, _ = strings.Cut(query[1:], string(c))
+		case c == '_' || unicode.IsLetter(rune(c)) || c >= 0x80:
+			end := strings.IndexFunc(query, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '$' && r != '#'
+			})
+			if end < 0 {
+				end = len(query)
+			}
+			out = append(out, strings.ToUpper(query[:end]))
+			query = query[end:]
+		default:
+			query = query[1:]
+		}
+	}
+	return out
+}
+
+// firstKeyword returns the upper-cased first word of query, skipping
+// comments and opening parentheses.
+func firstKeyword(query string) string {
+	for {
+		query = strings.TrimLeft(query, " \t\r\n(")
+		switch {
+		case strings.HasPrefix(query, "--"):
+			_, query, _ = strings.Cut(query, "\n")
+		case strings.HasPrefix(query, "/*"):
+			_, query, _ = strings.Cut(query, "*/")
+		default:
+			end := strings.IndexFunc(query, func(r rune) bool {
+				return !unicode.IsLetter(r) && r != '_'
+			})
+			if end < 0 {
+				end = len(query)
+			}
+			return strings.ToUpper(query[:end])
+		}
+	}
+}
+
+// formatValue renders a value scanned from a result set the way SQL*Plus
+// would show it: NULL as the empty string, dates to the second, RAW as hex.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if v.Nanosecond() != 0 {
+			return v.Format("2006-01-02 15:04:05.000000")
+		}
+		return v.Format("2006-01-02 15:04:05")
+	case []byte:
+		return strings.ToUpper(hex.EncodeToString(v))
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTrimStatement(t *testing.T) {
+	tests := []struct {
+		name, query, want string
+	}{
+		{"bare", "SELECT 1 FROM dual", "SELECT 1 FROM dual"},
+		{"blanks", "\n  SELECT 1 FROM dual \t\n", "SELECT 1 FROM dual"},
+		{"semicolon", "SELECT 1 FROM dual;", "SELECT 1 FROM dual"},
+		{"semicolon and blanks", "SELECT 1 FROM dual ;  \n", "SELECT 1 FROM dual"},
+		{"slash", "SELECT 1 FROM dual\n/", "SELECT 1 FROM dual"},
+		{"slash with blanks", "SELECT 1 FROM dual\n  /  \n", "SELECT 1 FROM dual"},
+		{"semicolon and slash", "UPDATE t SET x = 1;\n/", "UPDATE t SET x = 1"},
+		{"block keeps END;", "BEGIN\n  NULL;\nEND;", "BEGIN\n  NULL;\nEND;"},
+		{"block with slash", "begin\n  null;\nend;\n/", "begin\n  null;\nend;"},
+		{"division is not a slash line", "SELECT 4 / 2 FROM dual", "SELECT 4 / 2 FROM dual"},
+		{"empty", "  \n ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimStatement(tt.query); got != tt.want {
+				t.Errorf("trimStatement(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFirstKeyword(t *testing.T) {
+	tests := []struct {
+		name, query, want string
+	}{
+		{"select", "select * from dual", "SELECT"},
+		{"leading blanks", "\n\t  Update t set x = 1", "UPDATE"},
+		{"line comment", "-- who is blocking\nSELECT 1 FROM dual", "SELECT"},
+		{"block comment", "/* DELETE */ select 1 from dual", "SELECT"},
+		{"comments in a row", "-- a\n/* b */\n-- c\nwith q as (select 1 from dual) select * from q", "WITH"},
+		{"parentheses", "((SELECT 1 FROM dual) UNION (SELECT 2 FROM dual))", "SELECT"},
+		{"comment inside parentheses", "( /* x */ SELECT 1 FROM dual)", "SELECT"},
+		{"word ends at punctuation", "BEGIN:x := 1; END;", "BEGIN"},
+		{"underscore", "sys_context", "SYS_CONTEXT"},
+		{"unterminated comment", "/* SELECT", ""},
+		{"only a comment", "-- SELECT", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstKeyword(tt.query); got != tt.want {
+				t.Errorf("firstKeyword(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  bool
+	}{
+		{"select", "SELECT * FROM v$session", true},
+		{"with", "WITH q AS (SELECT 1 x FROM dual) SELECT x FROM q", true},
+		{"parenthesised", "(SELECT 1 FROM dual)", true},
+		{"after comments", "-- note\n/* more */ select 1 from dual", true},
+		{"for update", "SELECT * FROM t FOR UPDATE", true},
+		{"with function", "WITH FUNCTION f RETURN NUMBER IS BEGIN RETURN 1; END;\nSELECT f FROM dual", true},
+		{"update", "UPDATE t SET x = 1", false},
+		{"delete behind a comment", "/* SELECT */ DELETE FROM t", false},
+		{"insert select", "INSERT INTO t SELECT * FROM u", false},
+		{"merge", "MERGE INTO t USING u ON (t.id = u.id) WHEN MATCHED THEN UPDATE SET t.x = u.x", false},
+		{"block", "BEGIN DELETE FROM t; END;", false},
+		{"declare", "DECLARE n NUMBER; BEGIN NULL; END;", false},
+		{"ddl", "DROP TABLE t", false},
+		{"call", "CALL p()", false},
+		{"selected as a prefix", "SELECTED", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsQuery(tt.query); got != tt.want {
+				t.Errorf("IsQuery(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckReadOnly(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  error
+	}{
+		{"select", "SELECT * FROM v$session", nil},
+		{"subquery factoring", "WITH q AS (SELECT 1 x FROM dual) SELECT x FROM q", nil},
+		{"parenthesised", "((SELECT 1 FROM dual))", nil},
+		{"update", "UPDATE t SET x = 1", ErrNotQuery},
+		{"block", "BEGIN DELETE FROM t; END;", ErrNotQuery},
+		{"with function", "WITH FUNCTION f RETURN NUMBER IS BEGIN DELETE FROM t; RETURN 1; END;\nSELECT f FROM dual", ErrInlinePLSQL},
+		{"with procedure", "with procedure p is begin null; end;\nfunction f return number is begin p; return 1; end;\nselect f from dual", ErrInlinePLSQL},
+		{"with function behind a comment", "/* report */\nWITH\n  FUNCTION f RETURN NUMBER IS BEGIN RETURN 1; END;\nSELECT f FROM dual", ErrInlinePLSQL},
+		{"with function split by a comment", "WITH /* x */ FUNCTION f RETURN NUMBER IS BEGIN RETURN 1; END;\nSELECT f FROM dual", ErrInlinePLSQL},
+		{"with function in a subquery", "SELECT /*+ WITH_PLSQL */ * FROM (WITH FUNCTION f RETURN NUMBER IS BEGIN RETURN 1; END; SELECT f FROM dual)", ErrInlinePLSQL},
+		{"function as a column", "WITH q AS (SELECT 1 function FROM dual) SELECT * FROM q", nil},
+		{"with function in a literal", "SELECT 'with function' FROM dual", nil},
+		{"with function in a comment", "SELECT 1 FROM dual -- WITH FUNCTION\n", nil},
+		{"with function in a quoted identifier", `SELECT 1 AS "WITH FUNCTION" FROM dual`, nil},
+		{"for update", "SELECT * FROM t WHERE id = 1 FOR UPDATE", ErrForUpdate},
+		{"for update nowait", "select * from t for  update nowait", ErrForUpdate},
+		{"for update of", "SELECT * FROM t FOR\nUPDATE OF x SKIP LOCKED", ErrForUpdate},
+		{"for update in a literal", "SELECT 'for update' FROM dual", nil},
+		{"trailing slash", trimStatement("SELECT 1 FROM dual\n/"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckReadOnly(tt.query); !errors.Is(got, tt.want) {
+				t.Errorf("CheckReadOnly(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package db
+
+import "context"
+
+// Scope restricts monitoring queries to part of the database.
+// The zero Scope means "everything the connection can see".
+type Scope struct {
+	// InstID limits cluster-mode queries to one RAC instance; 0 means
+	// the whole cluster.
+	InstID int
+
+	// ConID limits queries to one container (PDB or CDB$ROOT) when
+	// connected to a CDB root; 0 means every container.
+	ConID int
+}
+
+type scopeKey struct{}
+
+// WithScope returns a context whose queries are restricted by fn. fn is
+// consulted on every query, so a long-lived context follows scope changes
+// made after it was created. fn must be safe for concurrent use.
+func WithScope(ctx context.Context, fn func() Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, fn)
+}
+
+// ScopeOf returns the scope carried by ctx, or the zero Scope.
+func ScopeOf(ctx context.Context) Scope {
+	if fn, ok := ctx.Value(scopeKey{}).(func() Scope); ok {
+		return fn()
+	}
+	return Scope{}
+}
+
+// Includes reports whether a row from instance instID and container conID
+// falls within s.
+func (s Scope) Includes(instID, conID int) bool {
+	return (s.InstID == 0 || s.InstID == instID) && (s.ConID == 0 || s.ConID == conID)
+}
@@ -0,0 +1,88 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// Source is the read interface the UI uses to fetch monitoring data.
+// DB is the godror-backed implementation; alternative backends (fakes,
+// replayers, caching decorators) only need to satisfy this interface.
+//
+// Every method honours ctx: implementations must return promptly with
+// ctx.Err() once it is cancelled, and restrict their results to the Scope
+// it carries (see WithScope).
+type Source interface {
+	// GetActiveSessions returns user sessions, active sessions first.
+	GetActiveSessions(ctx context.Context) ([]models.Session, error)
+
+	// SampleActiveSessions returns one sample of every session that is
+	// active in a non-idle wait or on CPU, excluding otop's own.
+	SampleActiveSessions(ctx context.Context) ([]models.ASHSample, error)
+
+	// GetActiveSessionHistory returns Oracle's own ASH samples taken
+	// between from and to. Identical samples within each step-long bucket
+	// are merged into one dated at the bucket's start whose Interval is
+	// their combined DB time. Needs the Diagnostics Pack.
+	GetActiveSessionHistory(ctx context.Context, from, to time.Time, step time.Duration) ([]models.ASHSample, error)
+
+	// GetLockWaits returns every session that is blocked by another
+	// session or blocks one, so callers can build the blocking tree.
+	GetLockWaits(ctx context.Context) ([]models.LockWait, error)
+
+	// GetLongOps returns the long-running operations still in progress,
+	// oldest first.
+	GetLongOps(ctx context.Context) ([]models.LongOp, error)
+
+	// GetChildCursors returns sqlID's child cursors, ordered by instance,
+	// container and child number.
+	GetChildCursors(ctx context.Context, sqlID string) ([]models.ChildCursor, error)
+
+	// GetExecutionPlan returns the plan steps of sqlID's child cursor
+	// child, identified by its instance, container and child number, or
+	// of its first child cursor if child is nil.
+	GetExecutionPlan(ctx context.Context, sqlID string, child *models.ChildCursor) ([]models.PlanRow, error)
+
+	// GetBinds returns the bind variables of sqlID's child cursor child, or
+	// of its first child cursor if child is nil, ordered by position.
+	GetBinds(ctx context.Context, sqlID string, child *models.ChildCursor) ([]models.Bind, error)
+
+	// GetSQLStats returns runtime statistics for sqlID, or nil if the
+	// statement is no longer in the shared pool.
+	GetSQLStats(ctx context.Context, sqlID string) (*models.SQLStats, error)
+
+	// GetTopSQL returns runtime statistics per statement and plan: those of
+	// the n statements with the most elapsed time, CPU time, buffer gets,
+	// disk reads and executions each, and of every statement run in the
+	// last minute.
+	GetTopSQL(ctx context.Context, n int) ([]models.SQLStats, error)
+
+	// GetSystemEvents returns the totals of every non-idle wait event,
+	// most time waited first.
+	GetSystemEvents(ctx context.Context) ([]models.SystemEvent, error)
+
+	// GetSystemMetrics returns the latest value of every system metric.
+	GetSystemMetrics(ctx context.Context) ([]models.SystemMetric, error)
+
+	// GetSQLText returns the full text of sqlID, which models.Session and
+	// models.SQLStats carry cut off at 1000 characters, or "" if the
+	// statement is no longer in the shared pool.
+	GetSQLText(ctx context.Context, sqlID string) (string, error)
+
+	// GetInstances returns the database instances being monitored.
+	GetInstances(ctx context.Context) ([]models.Instance, error)
+
+	// GetContainers returns the multitenant containers sessions belong to.
+	GetContainers(ctx context.Context) ([]models.Container, error)
+
+	// GetCapabilities probes what the database offers and what the
+	// connecting user may read.
+	GetCapabilities(ctx context.Context) (*models.Capabilities, error)
+
+	// Close releases any resources held by the source.
+	Close() error
+}
+
+var _ Source = (*DB)(nil)
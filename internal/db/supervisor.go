@@ -0,0 +1,285 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/godror/godror"
+)
+
+const (
+	// DefaultHealthInterval is how often a connection is pinged when
+	// Options.HealthInterval is zero.
+	DefaultHealthInterval = 10 * time.Second
+
+	// DefaultDegradedLatency is the ping round trip above which a
+	// connection is degraded when Options.DegradedLatency is zero.
+	DefaultDegradedLatency = 500 * time.Millisecond
+
+	maxBackoff = 30 * time.Second
+)
+
+// ErrReconnecting is returned by queries issued while the supervisor is
+// replacing a broken connection. The connection indicator already reports
+// this state, so callers need not surface it again.
+var ErrReconnecting = errors.New("database connection lost, reconnecting")
+
+// ErrLoginRefused is returned by queries issued after the database refused
+// to let the supervisor log back in. otop does not try again, so as not to
+// lock the account with the old password; it must be restarted.
+var ErrLoginRefused = errors.New("database refused the login; restart otop to reconnect")
+
+// loginErrors are the ORA- codes for a login that will fail however often
+// it is retried: a wrong or expired password, a locked account, or a user
+// without CREATE SESSION.
+var loginErrors = map[int]bool{1005: true, 1017: true, 1045: true, 28000: true, 28001: true}
+
+// isLoginError reports whether err is a refused login.
+func isLoginError(err error) bool {
+	oerr, ok := godror.AsOraErr(err)
+	return ok && loginErrors[oerr.Code()]
+}
+
+// State is the coarse health of a supervised connection.
+type State int
+
+const (
+	Connected    State = iota // pings are fast and queries succeed
+	Degraded                  // reachable, but slow or timing out
+	Reconnecting              // connection lost; reopening with backoff
+	LoginRefused              // the database refused to log back in; no more attempts
+)
+
+func (s State) String() string {
+	switch s {
+	case Connected:
+		return "connected"
+	case Degraded:
+		return "degraded"
+	case Reconnecting:
+		return "reconnecting"
+	case LoginRefused:
+		return "login refused"
+	}
+	return "unknown"
+}
+
+// Health is a snapshot of a supervised connection.
+type Health struct {
+	State   State
+	Latency time.Duration // round trip of the last successful ping
+	Err     error         // most recent failure while degraded, reconnecting or refused
+	Since   time.Time     // when State was entered
+}
+
+// HealthReporter is implemented by sources that supervise a live connection.
+type HealthReporter interface {
+	// Health returns the current connection health.
+	Health() Health
+
+	// WatchHealth calls fn with the current health and again on every
+	// change. fn is called from the supervisor goroutine and must not block.
+	WatchHealth(fn func(Health))
+}
+
+var _ HealthReporter = (*DB)(nil)
+
+// Health returns the current connection health.
+func (db *DB) Health() Health { return db.sup.Health() }
+
+// WatchHealth calls fn with the current health and again on every change.
+func (db *DB) WatchHealth(fn func(Health)) { db.sup.watch(fn) }
+
+// observe inspects a query error: a broken connection wakes the supervisor
+// to reconnect, and a timeout marks the connection degraded. err is returned
+// unchanged.
+func (db *DB) observe(err error) error {
+	switch {
+	case err == nil:
+	case godror.IsBadConn(err):
+		db.sup.wake()
+	case errors.Is(err, context.DeadlineExceeded):
+		db.sup.timedOut(err)
+	}
+	return err
+}
+
+// supervisor pings the database on an interval and reopens the pool with
+// exponential backoff when the connection breaks. It gives up for good when
+// a login is refused.
+type supervisor struct {
+	db *DB
+
+	mu          sync.Mutex
+	health      Health
+	lastTimeout time.Time
+	watchers    []func(Health)
+
+	kick     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+func newSupervisor(db *DB, latency time.Duration) *supervisor {
+	return &supervisor{
+		db:     db,
+		health: Health{State: Connected, Latency: latency, Since: time.Now()},
+		kick:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+func (s *supervisor) Health() Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.health
+}
+
+func (s *supervisor) watch(fn func(Health)) {
+	s.mu.Lock()
+	s.watchers = append(s.watchers, fn)
+	h := s.health
+	s.mu.Unlock()
+	fn(h)
+}
+
+// wake asks the supervisor to check the connection now.
+func (s *supervisor) wake() {
+	select {
+	case s.kick <- struct{}{}:
+	default:
+	}
+}
+
+// timedOut records a query timeout; the connection stays degraded until
+// a full health interval passes without another one.
+func (s *supervisor) timedOut(err error) {
+	s.mu.Lock()
+	s.lastTimeout = time.Now()
+	h := s.health
+	s.mu.Unlock()
+	if h.State == Connected {
+		s.set(Degraded, h.Latency, err)
+	}
+}
+
+// stop ends supervision. It may be called more than once.
+func (s *supervisor) stop() {
+	s.stopOnce.Do(func() { close(s.done) })
+}
+
+func (s *supervisor) run() {
+	ticker := time.NewTicker(s.db.opts.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		case <-s.kick:
+		}
+		if !s.check() {
+			return
+		}
+	}
+}
+
+// check pings the current pool and either updates latency-based health or
+// hands over to reconnect. It returns false once supervision has given up.
+func (s *supervisor) check() bool {
+	s.db.mu.RLock()
+	conn := s.db.conn
+	s.db.mu.RUnlock()
+
+	latency, err := s.db.ping(context.Background(), conn)
+	if err != nil {
+		return s.reconnect(err)
+	}
+
+	s.mu.Lock()
+	recentTimeout := time.Since(s.lastTimeout) < s.db.opts.HealthInterval
+	s.mu.Unlock()
+	switch {
+	case latency > s.db.opts.DegradedLatency:
+		s.set(Degraded, latency, nil)
+	case recentTimeout:
+		s.set(Degraded, latency, context.DeadlineExceeded)
+	default:
+		s.set(Connected, latency, nil)
+	}
+	return true
+}
+
+// reconnect reopens the pool until it succeeds or the DB is closed,
+// backing off exponentially between attempts. A refused login is not
+// retried: the account's password may have changed, and logging in again
+// and again with the old one would lock it. reconnect returns false if it
+// gave up or the DB was closed.
+func (s *supervisor) reconnect(cause error) bool {
+	backoff := time.Second
+	for {
+		if isLoginError(cause) {
+			s.set(LoginRefused, 0, cause)
+			return false
+		}
+		s.set(Reconnecting, 0, cause)
+		select {
+		case <-s.done:
+			return false
+		case <-time.After(backoff):
+		}
+
+		conn, latency, err := s.db.open(context.Background())
+		var inst int
+		if err == nil {
+			// SCAN may land the new pool on a different RAC node.
+			if inst, err = s.db.lookupInstance(context.Background(), conn); err != nil {
+				conn.Close()
+			}
+		}
+		if err == nil {
+			s.db.mu.Lock()
+			select {
+			case <-s.done:
+				// Closed while we were dialling; don't resurrect the pool.
+				s.db.mu.Unlock()
+				conn.Close()
+				return false
+			default:
+			}
+			old := s.db.conn
+			s.db.conn = conn
+			s.db.localInst = inst
+			s.db.mu.Unlock()
+			old.Close()
+			s.set(Connected, latency, nil)
+			return true
+		}
+		cause = err
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// set records the new health and notifies watchers if it changed in a way
+// worth redrawing for.
+func (s *supervisor) set(state State, latency time.Duration, err error) {
+	s.mu.Lock()
+	prev := s.health
+	h := Health{State: state, Latency: latency, Err: err, Since: prev.Since}
+	if state != prev.State {
+		h.Since = time.Now()
+	}
+	s.health = h
+	watchers := make([]func(Health), len(s.watchers))
+	copy(watchers, s.watchers)
+	s.mu.Unlock()
+
+	if state == prev.State && latency == prev.Latency {
+		return
+	}
+	for _, fn := range watchers {
+		fn(h)
+	}
+}
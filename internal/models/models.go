@@ -1,25 +1,46 @@
 package models
 
-// Session represents a row from V$SESSION joined with V$SQL.
+import "time"
+
+// Session represents a row from V$SESSION joined with V$SQL, with the
+// session's cumulative counters from V$SESSTAT and V$SESS_IO.
 type Session struct {
-	SID          int
-	Serial       int
-	Username     string
-	Status       string
-	SQLID        string
-	SQLText      string
-	Program      string
-	Machine      string
-	WaitEvent    string
-	WaitSeconds  float64
-	CPUTime      float64
-	ElapsedTime  float64
-	PhysicalReads int64
-	LogicalReads  int64
+	InstID         int    // RAC instance number (1 on single-instance databases)
+	ConID          int    // container ID (0 on a non-CDB)
+	PDBName        string // container name, e.g. CDB$ROOT or the PDB name
+	SID            int
+	Serial         int
+	Username       string
+	Status         string
+	SQLID          string
+	SQLChildNumber int    // child cursor being executed; -1 when unknown
+	SQLText        string // first 1000 characters, from V$SQL.SQL_TEXT
+	Program        string
+	Machine        string
+	WaitEvent      string  // the current wait, or "ON CPU" when not waiting
+	WaitSeconds    float64 // time in the current wait
+	CPUTime        float64 // seconds of CPU used by the session
+	DBTime         float64 // seconds of DB time
+	PhysicalReads  int64
+	LogicalReads   int64
+
+	// Rates holds per-second rates of the counters above over the last
+	// sampling interval; it is filled in by the sampler.
+	Rates SessionRates
+}
+
+// SessionRates are per-second rates of a session's cumulative counters.
+type SessionRates struct {
+	Interval      time.Duration // zero until the session has been sampled twice
+	CPU           float64       // CPU seconds per second
+	DBTime        float64       // DB time seconds per second (active sessions)
+	PhysicalReads float64
+	LogicalReads  float64
 }
 
 // PlanRow represents a single step in an execution plan from V$SQL_PLAN.
 type PlanRow struct {
+	InstID      int // instance whose cursor the plan was read from
 	ID          int
 	ParentID    int
 	Depth       int
@@ -29,16 +50,210 @@ type PlanRow struct {
 	Cardinality int64
 	Bytes       int64
 	Cost        int64
+
+	// Row source statistics of the cursor's last execution, from
+	// V$SQL_PLAN_STATISTICS_ALL. They exist only when it ran with
+	// STATISTICS_LEVEL=ALL or the gather_plan_statistics hint.
+	HasStats      bool
+	Starts        int64
+	ActualRows    int64
+	BufferGets    int64
+	ElapsedMicros int64 // including the step's children
+}
+
+// ChildCursor is one child cursor of a SQL statement from V$SQL. A
+// statement gets more than one when a later parse could not share the
+// existing children, for the reasons V$SQL_SHARED_CURSOR gives.
+type ChildCursor struct {
+	InstID            int
+	ConID             int
+	ChildNumber       int
+	PlanHashValue     int64
+	Executions        int64
+	ElapsedTimeMicros int64
+	BufferGets        int64
+	Reasons           []string // why the child could not share an earlier one, e.g. "Bind mismatch(33)"
+}
+
+// Bind is a bind variable of a child cursor, with the value the optimizer
+// peeked at when it built the plan, from V$SQL_PLAN.OTHER_XML, and the
+// value last sampled from an execution, from V$SQL_BIND_CAPTURE.
+type Bind struct {
+	Position   int
+	Name       string // as in the statement, e.g. ":1" or ":CUSTOMER_ID"
+	Datatype   string // e.g. NUMBER or VARCHAR2(32)
+	Peeked     *BindValue
+	Captured   *BindValue
+	CapturedAt time.Time // zero when no value was captured
 }
 
-// SQLStats holds runtime statistics for a SQL statement from V$SQL.
+// BindValue is a bind variable's value as text: dates as YYYY-MM-DD
+// HH24:MI:SS, timestamps with .FF after that and, if they have a time zone,
+// TZH:TZM, numbers in full.
+type BindValue struct {
+	Text string
+	Null bool
+	Raw  bool // Text is Oracle's internal format in hex, which could not be decoded
+}
+
+// SQLStats holds runtime statistics for a SQL statement from V$SQL, or,
+// per plan, from V$SQLSTATS.
 type SQLStats struct {
-	SQLID          string
-	SQLText        string
-	Executions     int64
+	InstID            int // 0 when summed across the whole cluster
+	SQLID             string
+	PlanHashValue     int64  // 0 when summed across every plan
+	SQLText           string // first 1000 characters, from V$SQL.SQL_TEXT
+	Executions        int64
 	ElapsedTimeMicros int64
-	CPUTimeMicros  int64
-	BufferGets     int64
-	DiskReads      int64
-	Rows           int64
+	CPUTimeMicros     int64
+	BufferGets        int64
+	DiskReads         int64
+	Rows              int64
+
+	// Rates holds per-second rates of the counters above over the last
+	// sampling interval; it is filled in by the sampler.
+	Rates SQLRates
+}
+
+// SQLRates are per-second rates of a statement's cumulative counters.
+type SQLRates struct {
+	Interval          time.Duration // zero until the statement has been sampled twice
+	Executions        float64
+	ElapsedTimeMicros float64
+	CPUTimeMicros     float64
+	BufferGets        float64
+	DiskReads         float64
+	Rows              float64
+}
+
+// SystemEvent is a wait event's cumulative totals since instance startup,
+// from V$SYSTEM_EVENT, summed over the instances in scope.
+type SystemEvent struct {
+	Event            string
+	WaitClass        string
+	TotalWaits       int64
+	TimeWaitedMicros int64
+
+	// Rates holds per-second rates of the counters above over the last
+	// sampling interval; it is filled in by the sampler.
+	Rates SystemEventRates
+}
+
+// SystemEventRates are per-second rates of a wait event's totals.
+type SystemEventRates struct {
+	Interval         time.Duration // zero until the event has been sampled twice
+	Waits            float64
+	TimeWaitedMicros float64 // also the average number of sessions waiting, in millionths
+}
+
+// SystemMetric is one of V$SYSMETRIC's metrics over its latest interval,
+// combined over the instances in scope.
+type SystemMetric struct {
+	Name    string
+	Unit    string // e.g. "Reads Per Second"
+	Value   float64
+	EndTime time.Time // end of the interval Value covers
+}
+
+// LongOp is an operation from V$SESSION_LONGOPS: a step of an execution
+// that has run for more than six seconds, such as a full table scan, a
+// sort or an index rebuild.
+type LongOp struct {
+	InstID    int
+	ConID     int
+	SID       int
+	Serial    int
+	Username  string
+	SQLID     string
+	OpName    string // e.g. "Table Scan"
+	Target    string // the object worked on, e.g. APP.SALES, or a description
+	SoFar     int64
+	TotalWork int64
+	Units     string // what SoFar and TotalWork count, e.g. Blocks
+	StartTime time.Time
+	Elapsed   time.Duration
+	Remaining time.Duration // Oracle's estimate, or -1 when it has none
+}
+
+// ASHSample is one active session observed at one moment, in the manner of
+// a row of V$ACTIVE_SESSION_HISTORY.
+type ASHSample struct {
+	SampleTime time.Time
+	InstID     int
+	ConID      int
+	SID        int
+	Serial     int
+	Username   string
+	SQLID      string
+	State      string // "ON CPU" or "WAITING"
+	WaitClass  string // "CPU" when on CPU
+	Event      string // empty when on CPU
+
+	// Interval is the DB time the sample stands for: the sampling interval,
+	// or several samples' worth when a source has merged identical ones.
+	Interval time.Duration
+}
+
+// LockWait is a session involved in lock contention, from GV$SESSION and
+// GV$LOCK: either waiting for a lock, or holding one another session waits
+// for, or both.
+type LockWait struct {
+	InstID      int
+	ConID       int
+	SID         int
+	Serial      int
+	Username    string
+	Program     string
+	Status      string
+	SQLID       string
+	Event       string
+	WaitSeconds float64
+
+	// The session this one waits for, and the one at the head of the
+	// chain; zero when the session is not blocked.
+	BlockingInstID      int
+	BlockingSID         int
+	FinalBlockingInstID int
+	FinalBlockingSID    int
+
+	LockType      string // e.g. TX, TM
+	ModeHeld      string // e.g. X, SX; empty for none
+	ModeRequested string // empty when not waiting for a lock
+	Object        string // OWNER.NAME of the object waited on, if known
+}
+
+// Instance represents a database instance from GV$INSTANCE.
+type Instance struct {
+	InstID int
+	Name   string
+	Host   string
+	Status string
+}
+
+// Container represents a multitenant container from V$CONTAINERS.
+type Container struct {
+	ConID    int
+	Name     string
+	OpenMode string
+}
+
+// Capabilities describes what the connected database offers and what the
+// connecting user is allowed to read.
+type Capabilities struct {
+	User            string
+	Version         string
+	Edition         string // Enterprise, Standard, Express, ...
+	Banner          string
+	RAC             bool     // cluster_database = TRUE
+	Multitenant     bool     // connected to a CDB
+	DiagnosticsPack bool     // control_management_pack_access includes DIAGNOSTIC
+	Options         []string // enabled options from V$OPTION
+	Views           map[string]bool
+}
+
+// CanRead reports whether the connecting user can select from view.
+// Views that were not probed are assumed readable.
+func (c *Capabilities) CanRead(view string) bool {
+	ok, probed := c.Views[view]
+	return ok || !probed
 }
@@ -0,0 +1,63 @@
+// Package rate turns cumulative counters, such as those reported by
+// Oracle's V$ views, into per-second rates between successive samples.
+package rate
+
+import (
+	"sync"
+	"time"
+)
+
+// Tracker remembers the latest sample of each key's counters. It is safe
+// for concurrent use.
+type Tracker[K comparable] struct {
+	mu   sync.Mutex
+	prev map[K]sample
+}
+
+type sample struct {
+	at     time.Time
+	values []float64
+}
+
+// NewTracker returns an empty Tracker.
+func NewTracker[K comparable]() *Tracker[K] {
+	return &Tracker[K]{prev: make(map[K]sample)}
+}
+
+// Rates records values, sampled at at, as the latest counters for key and
+// returns the per-second rate of each since key's previous sample, along
+// with the interval between the two. The interval is zero and the rates nil
+// for a key's first sample, and whenever a counter went backwards, as it
+// does when a cursor is reloaded or a session's counters are reset.
+func (t *Tracker[K]) Rates(key K, at time.Time, values ...float64) ([]float64, time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	prev, ok := t.prev[key]
+	t.prev[key] = sample{at: at, values: values}
+
+	interval := at.Sub(prev.at)
+	if !ok || interval <= 0 || len(prev.values) != len(values) {
+		return nil, 0
+	}
+	rates := make([]float64, len(values))
+	for i, v := range values {
+		d := v - prev.values[i]
+		if d < 0 {
+			return nil, 0
+		}
+		rates[i] = d / interval.Seconds()
+	}
+	return rates, interval
+}
+
+// Sweep forgets every key last sampled before since, such as sessions that
+// have logged off.
+func (t *Tracker[K]) Sweep(since time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k, s := range t.prev {
+		if s.at.Before(since) {
+			delete(t.prev, k)
+		}
+	}
+}
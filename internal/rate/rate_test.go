@@ -0,0 +1,68 @@
+package rate
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRates(t *testing.T) {
+	t0 := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	tr := NewTracker[string]()
+
+	// The first sample of a key has nothing to compare with.
+	if r, iv := tr.Rates("a", t0, 100, 10); r != nil || iv != 0 {
+		t.Fatalf("first sample = %v, %v, want nil, 0", r, iv)
+	}
+
+	r, iv := tr.Rates("a", t0.Add(5*time.Second), 150, 10)
+	if iv != 5*time.Second || len(r) != 2 || r[0] != 10 || r[1] != 0 {
+		t.Fatalf("second sample = %v, %v, want [10 0], 5s", r, iv)
+	}
+
+	// Keys are independent.
+	if r, iv := tr.Rates("b", t0.Add(5*time.Second), 1); r != nil || iv != 0 {
+		t.Fatalf("first sample of b = %v, %v, want nil, 0", r, iv)
+	}
+
+	// A counter going backwards is a reset: no rate, and the next sample
+	// is measured from the reset.
+	if r, iv := tr.Rates("a", t0.Add(10*time.Second), 20, 12); r != nil || iv != 0 {
+		t.Fatalf("after reset = %v, %v, want nil, 0", r, iv)
+	}
+	r, iv = tr.Rates("a", t0.Add(12*time.Second), 30, 16)
+	if iv != 2*time.Second || r[0] != 5 || r[1] != 2 {
+		t.Fatalf("after reset, next sample = %v, %v, want [5 2], 2s", r, iv)
+	}
+
+	// A sample at the same time, or earlier, gives no rate.
+	if r, iv := tr.Rates("a", t0.Add(12*time.Second), 40, 16); r != nil || iv != 0 {
+		t.Fatalf("same time = %v, %v, want nil, 0", r, iv)
+	}
+	if r, iv := tr.Rates("a", t0.Add(11*time.Second), 50, 16); r != nil || iv != 0 {
+		t.Fatalf("earlier time = %v, %v, want nil, 0", r, iv)
+	}
+
+	// A different number of counters cannot be compared.
+	tr.Rates("c", t0, 1, 2)
+	if r, iv := tr.Rates("c", t0.Add(time.Second), 1, 2, 3); r != nil || iv != 0 {
+		t.Fatalf("more counters = %v, %v, want nil, 0", r, iv)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	t0 := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	tr := NewTracker[int]()
+	tr.Rates(1, t0, 1)
+	tr.Rates(2, t0, 1)
+	tr.Rates(2, t0.Add(5*time.Second), 2)
+
+	tr.Sweep(t0.Add(5 * time.Second))
+
+	// Key 1 was forgotten, so its next sample is a first one again.
+	if r, iv := tr.Rates(1, t0.Add(10*time.Second), 5); r != nil || iv != 0 {
+		t.Errorf("swept key = %v, %v, want nil, 0", r, iv)
+	}
+	if r, iv := tr.Rates(2, t0.Add(10*time.Second), 7); iv != 5*time.Second || r[0] != 1 {
+		t.Errorf("kept key = %v, %v, want [1], 5s", r, iv)
+	}
+}
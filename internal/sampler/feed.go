@@ -0,0 +1,121 @@
+package sampler
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Snapshot is one sample of a feed: the data a query returned and when.
+type Snapshot[T any] struct {
+	At   time.Time
+	Data T
+	Err  error
+}
+
+// Feed polls one query on an interval while it has subscribers and fans
+// each result out to all of them. It is safe for concurrent use.
+type Feed[T any] struct {
+	fetch    func(context.Context) (T, error)
+	interval time.Duration
+	base     context.Context
+
+	mu     sync.Mutex
+	subs   map[int]func(Snapshot[T])
+	nextID int
+	last   Snapshot[T]        // most recent successful sample
+	cancel context.CancelFunc // stops the poller; nil while idle
+	idle   func()             // called when the last subscriber leaves, if set
+}
+
+func newFeed[T any](base context.Context, interval time.Duration, fetch func(context.Context) (T, error)) *Feed[T] {
+	return &Feed[T]{
+		fetch:    fetch,
+		interval: interval,
+		base:     base,
+		subs:     make(map[int]func(Snapshot[T])),
+	}
+}
+
+// Subscribe calls fn with every new snapshot until the returned function is
+// called. The first subscriber starts the poller; a later one is sent the
+// latest snapshot straight away. fn is called from the poller goroutine and
+// must not block; push UI updates with QueueUpdateDraw.
+func (f *Feed[T]) Subscribe(fn func(Snapshot[T])) (unsubscribe func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.nextID
+	f.nextID++
+	f.subs[id] = fn
+	if f.cancel == nil {
+		var ctx context.Context
+		ctx, f.cancel = context.WithCancel(f.base)
+		go f.run(ctx)
+	} else if !f.last.At.IsZero() {
+		go fn(f.last)
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subs, id)
+			idle := len(f.subs) == 0 && f.cancel != nil
+			if idle {
+				f.cancel()
+				f.cancel = nil
+			}
+			f.mu.Unlock()
+			if idle && f.idle != nil {
+				f.idle()
+			}
+		})
+	}
+}
+
+// subscribed reports whether the feed has any subscribers.
+func (f *Feed[T]) subscribed() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.subs) > 0
+}
+
+// Last returns the most recent successful snapshot, which is zero if the
+// feed has not been sampled yet.
+func (f *Feed[T]) Last() Snapshot[T] {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.last
+}
+
+// run samples immediately and then once per interval until ctx is done.
+func (f *Feed[T]) run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		data, err := f.fetch(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		snap := Snapshot[T]{At: time.Now(), Data: data, Err: err}
+
+		f.mu.Lock()
+		if err == nil {
+			f.last = snap
+		}
+		subs := make([]func(Snapshot[T]), 0, len(f.subs))
+		for _, fn := range f.subs {
+			subs = append(subs, fn)
+		}
+		f.mu.Unlock()
+		for _, fn := range subs {
+			fn(snap)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,293 @@
+// Package sampler runs the monitoring queries of one database on a fixed
+// interval and shares each result with every panel that displays it, so
+// that opening more panels does not add load on the monitored database and
+// panels showing the same view always agree.
+package sampler
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/rate"
+)
+
+// DefaultInterval is how often each feed is sampled.
+const DefaultInterval = 5 * time.Second
+
+// TopSQLPerMetric is how many of the top statements by each resource the
+// top SQL feed fetches.
+const TopSQLPerMetric = 50
+
+// Sampler owns the feeds of one database and turns their cumulative
+// counters into per-second rates between samples.
+type Sampler struct {
+	src      db.Source
+	interval time.Duration
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	// Sessions samples GetActiveSessions across the whole database (every
+	// instance and container the connection can see); subscribers narrow
+	// each snapshot to their own db.Scope.
+	Sessions *Feed[[]models.Session]
+
+	sessionRates *rate.Tracker[sessionKey]
+
+	// The feeds below are created on first use and dropped when their last
+	// subscriber leaves, along with the rates they track.
+	mu       sync.Mutex
+	sqlStats map[sqlKey]*Feed[*models.SQLStats]
+	topSQL   map[db.Scope]*Feed[[]models.SQLStats]
+	events   map[db.Scope]*Feed[[]models.SystemEvent]
+	metrics  map[db.Scope]*Feed[[]models.SystemMetric]
+	locks    map[db.Scope]*Feed[[]models.LockWait]
+	longOps  map[db.Scope]*Feed[[]models.LongOp]
+}
+
+// sessionKey identifies a session; the serial number tells a reused SID
+// apart from the session that held it before.
+type sessionKey struct {
+	inst, sid, serial int
+}
+
+// sqlKey identifies a statement's statistics within a scope.
+type sqlKey struct {
+	sqlID string
+	scope db.Scope
+}
+
+// topSQLKey identifies a statement's plan in the top SQL.
+type topSQLKey struct {
+	sqlID string
+	plan  int64
+}
+
+// New creates a Sampler for src. Feeds only poll while subscribed.
+func New(src db.Source, interval time.Duration) *Sampler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Sampler{
+		src:          src,
+		interval:     interval,
+		ctx:          ctx,
+		cancel:       cancel,
+		sessionRates: rate.NewTracker[sessionKey](),
+		sqlStats:     make(map[sqlKey]*Feed[*models.SQLStats]),
+		topSQL:       make(map[db.Scope]*Feed[[]models.SQLStats]),
+		events:       make(map[db.Scope]*Feed[[]models.SystemEvent]),
+		metrics:      make(map[db.Scope]*Feed[[]models.SystemMetric]),
+		locks:        make(map[db.Scope]*Feed[[]models.LockWait]),
+		longOps:      make(map[db.Scope]*Feed[[]models.LongOp]),
+	}
+	s.Sessions = newFeed(ctx, interval, s.sessions)
+	return s
+}
+
+// SQLStats returns the feed of sqlID's statistics within scope, creating it
+// on first use. Feeds are shared, so panels watching the same statement in
+// the same scope see the same samples and rates.
+func (s *Sampler) SQLStats(sqlID string, scope db.Scope) *Feed[*models.SQLStats] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := sqlKey{sqlID: sqlID, scope: scope}
+	if f, ok := s.sqlStats[key]; ok {
+		return f
+	}
+	rates := rate.NewTracker[sqlKey]()
+	f := newFeed(s.ctx, s.interval, func(ctx context.Context) (*models.SQLStats, error) {
+		return s.sqlStatsOf(ctx, key, rates)
+	})
+	dropWhenIdle(&s.mu, s.sqlStats, key, f)
+	return f
+}
+
+// TopSQL returns the feed of the top statements within scope, per plan,
+// creating it on first use.
+func (s *Sampler) TopSQL(scope db.Scope) *Feed[[]models.SQLStats] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.topSQL[scope]; ok {
+		return f
+	}
+	rates := rate.NewTracker[topSQLKey]()
+	f := newFeed(s.ctx, s.interval, func(ctx context.Context) ([]models.SQLStats, error) {
+		return s.topSQLOf(ctx, scope, rates)
+	})
+	dropWhenIdle(&s.mu, s.topSQL, scope, f)
+	return f
+}
+
+// SystemEvents returns the feed of the wait events within scope, creating
+// it on first use.
+func (s *Sampler) SystemEvents(scope db.Scope) *Feed[[]models.SystemEvent] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.events[scope]; ok {
+		return f
+	}
+	rates := rate.NewTracker[string]()
+	f := newFeed(s.ctx, s.interval, func(ctx context.Context) ([]models.SystemEvent, error) {
+		return s.systemEventsOf(ctx, scope, rates)
+	})
+	dropWhenIdle(&s.mu, s.events, scope, f)
+	return f
+}
+
+// SystemMetrics returns the feed of the system metrics within scope,
+// creating it on first use. Oracle computes them itself, so unlike the
+// other feeds it carries no rates.
+func (s *Sampler) SystemMetrics(scope db.Scope) *Feed[[]models.SystemMetric] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.metrics[scope]; ok {
+		return f
+	}
+	f := newFeed(s.ctx, s.interval, func(ctx context.Context) ([]models.SystemMetric, error) {
+		return s.src.GetSystemMetrics(db.WithScope(ctx, func() db.Scope { return scope }))
+	})
+	dropWhenIdle(&s.mu, s.metrics, scope, f)
+	return f
+}
+
+// LockWaits returns the feed of the sessions blocked within scope, creating
+// it on first use.
+func (s *Sampler) LockWaits(scope db.Scope) *Feed[[]models.LockWait] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.locks[scope]; ok {
+		return f
+	}
+	f := newFeed(s.ctx, s.interval, func(ctx context.Context) ([]models.LockWait, error) {
+		return s.src.GetLockWaits(db.WithScope(ctx, func() db.Scope { return scope }))
+	})
+	dropWhenIdle(&s.mu, s.locks, scope, f)
+	return f
+}
+
+// LongOps returns the feed of the long operations in progress within
+// scope, creating it on first use.
+func (s *Sampler) LongOps(scope db.Scope) *Feed[[]models.LongOp] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f, ok := s.longOps[scope]; ok {
+		return f
+	}
+	f := newFeed(s.ctx, s.interval, func(ctx context.Context) ([]models.LongOp, error) {
+		return s.src.GetLongOps(db.WithScope(ctx, func() db.Scope { return scope }))
+	})
+	dropWhenIdle(&s.mu, s.longOps, scope, f)
+	return f
+}
+
+// dropWhenIdle adds f to feeds under key, and removes it again once its
+// last subscriber has left. Feeds of statements and scopes no longer
+// watched do not pile up, and one watched again later starts afresh rather
+// than taking its rates against a sample minutes old. The caller must hold
+// mu.
+func dropWhenIdle[K comparable, T any](mu *sync.Mutex, feeds map[K]*Feed[T], key K, f *Feed[T]) {
+	feeds[key] = f
+	f.idle = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		// A subscriber may have come along since.
+		if feeds[key] == f && !f.subscribed() {
+			delete(feeds, key)
+		}
+	}
+}
+
+// Close stops every feed.
+func (s *Sampler) Close() {
+	s.cancel()
+}
+
+func (s *Sampler) sessions(ctx context.Context) ([]models.Session, error) {
+	sessions, err := s.src.GetActiveSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range sessions {
+		se := &sessions[i]
+		r, interval := s.sessionRates.Rates(sessionKey{se.InstID, se.SID, se.Serial}, now,
+			se.CPUTime, se.DBTime, float64(se.PhysicalReads), float64(se.LogicalReads))
+		if interval > 0 {
+			se.Rates = models.SessionRates{
+				Interval:      interval,
+				CPU:           r[0],
+				DBTime:        r[1],
+				PhysicalReads: r[2],
+				LogicalReads:  r[3],
+			}
+		}
+	}
+	// Forget sessions that logged off.
+	s.sessionRates.Sweep(now)
+	return sessions, nil
+}
+
+func (s *Sampler) sqlStatsOf(ctx context.Context, key sqlKey, rates *rate.Tracker[sqlKey]) (*models.SQLStats, error) {
+	ctx = db.WithScope(ctx, func() db.Scope { return key.scope })
+	stats, err := s.src.GetSQLStats(ctx, key.sqlID)
+	if err != nil || stats == nil {
+		return stats, err
+	}
+	stats.Rates = sqlRates(rates, key, time.Now(), stats)
+	return stats, nil
+}
+
+func (s *Sampler) topSQLOf(ctx context.Context, scope db.Scope, rates *rate.Tracker[topSQLKey]) ([]models.SQLStats, error) {
+	ctx = db.WithScope(ctx, func() db.Scope { return scope })
+	top, err := s.src.GetTopSQL(ctx, TopSQLPerMetric)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range top {
+		stats := &top[i]
+		stats.Rates = sqlRates(rates, topSQLKey{stats.SQLID, stats.PlanHashValue}, now, stats)
+	}
+	// Forget statements that dropped out of the top.
+	rates.Sweep(now)
+	return top, nil
+}
+
+func (s *Sampler) systemEventsOf(ctx context.Context, scope db.Scope, rates *rate.Tracker[string]) ([]models.SystemEvent, error) {
+	ctx = db.WithScope(ctx, func() db.Scope { return scope })
+	events, err := s.src.GetSystemEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range events {
+		e := &events[i]
+		r, interval := rates.Rates(e.Event, now, float64(e.TotalWaits), float64(e.TimeWaitedMicros))
+		if interval > 0 {
+			e.Rates = models.SystemEventRates{Interval: interval, Waits: r[0], TimeWaitedMicros: r[1]}
+		}
+	}
+	rates.Sweep(now)
+	return events, nil
+}
+
+// sqlRates records stats as key's latest sample in t and returns the rates
+// since its previous one.
+func sqlRates[K comparable](t *rate.Tracker[K], key K, at time.Time, stats *models.SQLStats) models.SQLRates {
+	r, interval := t.Rates(key, at,
+		float64(stats.Executions), float64(stats.ElapsedTimeMicros), float64(stats.CPUTimeMicros),
+		float64(stats.BufferGets), float64(stats.DiskReads), float64(stats.Rows))
+	if interval == 0 {
+		return models.SQLRates{}
+	}
+	return models.SQLRates{
+		Interval:          interval,
+		Executions:        r[0],
+		ElapsedTimeMicros: r[1],
+		CPUTimeMicros:     r[2],
+		BufferGets:        r[3],
+		DiskReads:         r[4],
+		Rows:              r[5],
+	}
+}
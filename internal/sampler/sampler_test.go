@@ -0,0 +1,47 @@
+package sampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/db/demo"
+	"github.com/mdoeren/otop/internal/models"
+)
+
+func TestFeedsAreDroppedWhenIdle(t *testing.T) {
+	s := New(demo.New(1), time.Hour)
+	defer s.Close()
+	scope := db.Scope{InstID: 1}
+
+	f := s.SQLStats("3kq8d7m1wz0vb", scope)
+	if s.SQLStats("3kq8d7m1wz0vb", scope) != f {
+		t.Fatal("SQLStats returned a second feed for the same statement and scope")
+	}
+	unsub1 := f.Subscribe(func(Snapshot[*models.SQLStats]) {})
+	unsub2 := s.SQLStats("3kq8d7m1wz0vb", scope).Subscribe(func(Snapshot[*models.SQLStats]) {})
+
+	unsub1()
+	if s.SQLStats("3kq8d7m1wz0vb", scope) != f {
+		t.Fatal("feed dropped while it still had a subscriber")
+	}
+	unsub2()
+	s.mu.Lock()
+	n := len(s.sqlStats)
+	s.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("%d SQL feeds left after the last subscriber left, want 0", n)
+	}
+	if s.SQLStats("3kq8d7m1wz0vb", scope) == f {
+		t.Fatal("an idle feed was reused")
+	}
+
+	unsub := s.TopSQL(scope).Subscribe(func(Snapshot[[]models.SQLStats]) {})
+	unsub()
+	s.mu.Lock()
+	n = len(s.topSQL)
+	s.mu.Unlock()
+	if n != 0 {
+		t.Fatalf("%d top SQL feeds left after the last subscriber left, want 0", n)
+	}
+}
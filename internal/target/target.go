@@ -0,0 +1,80 @@
+// Package target describes the databases otop is connected to.
+package target
+
+import (
+	"github.com/mdoeren/otop/internal/ash"
+	"github.com/mdoeren/otop/internal/audit"
+	"github.com/mdoeren/otop/internal/config"
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/sampler"
+)
+
+// Target is one monitored database connection. Every workflow is bound to
+// a Target, and its panels query only that Target's Source.
+type Target struct {
+	// Name identifies the target in the tab bar and palette, e.g. the
+	// profile it was opened from.
+	Name string
+
+	Source db.Source
+
+	// Sampler shares periodic queries between all panels showing this
+	// target, whichever workflow they are in.
+	Sampler *sampler.Sampler
+
+	// ASH records the target's active sessions for the local ASH panel,
+	// or is nil if recording is disabled.
+	ASH *ash.Recorder
+
+	// Audit guards and records actions that change the database, such as
+	// killing a session. It is shared by every target.
+	Audit *audit.Log
+
+	// AllowDML is the connection's opt-in to running DML and DDL from the
+	// query editor, which is otherwise read-only.
+	AllowDML bool
+
+	// Metrics are the V$SYSMETRIC metrics the metrics panel shows, with
+	// their thresholds.
+	Metrics []config.Metric
+
+	// Caps is the result of the capability probe, or nil if it failed.
+	Caps *models.Capabilities
+}
+
+// Options configures the background work done for a Target.
+type Options struct {
+	ASH      ash.Options
+	Audit    *audit.Log
+	AllowDML bool
+	Metrics  []config.Metric
+}
+
+// New creates a Target named name for src, sampled every
+// sampler.DefaultInterval, recording active sessions as opts.ASH says and
+// acting on the database through opts.Audit.
+func New(name string, src db.Source, opts Options) (*Target, error) {
+	rec, err := ash.Start(src, opts.ASH)
+	if err != nil {
+		return nil, err
+	}
+	return &Target{
+		Name:     name,
+		Source:   src,
+		Sampler:  sampler.New(src, sampler.DefaultInterval),
+		ASH:      rec,
+		Audit:    opts.Audit,
+		AllowDML: opts.AllowDML,
+		Metrics:  opts.Metrics,
+	}, nil
+}
+
+// Close stops the target's background sampling and closes its connection.
+func (t *Target) Close() error {
+	t.Sampler.Close()
+	if t.ASH != nil {
+		t.ASH.Close()
+	}
+	return t.Source.Close()
+}
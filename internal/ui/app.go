@@ -1,13 +1,20 @@
 package ui
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gdamore/tcell/v2"
 	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/target"
+	"github.com/mdoeren/otop/internal/ui/dialog"
 	"github.com/mdoeren/otop/internal/ui/layout"
 	"github.com/mdoeren/otop/internal/ui/palette"
 	"github.com/mdoeren/otop/internal/ui/panel"
+	"github.com/mdoeren/otop/internal/ui/statusbar"
 	"github.com/mdoeren/otop/internal/ui/workflow"
 
 	// Blank import triggers all panel init() registrations.
@@ -24,35 +31,63 @@ type App struct {
 }
 
 // NewApp creates and wires up the extensible panel-based TUI.
-// main.go calls this with the open database handle.
-func NewApp(database *db.DB) *App {
+// main.go calls this with the connected databases to monitor, each carrying
+// the result of its capability probe. One workflow is opened per target;
+// more can be opened from the command palette.
+func NewApp(targets []*target.Target) *App {
 	tapp := tview.NewApplication()
 
-	// Root Pages: holds the workflow manager UI and the palette overlay.
+	// Root Pages: holds the workflow manager UI and the palette and dialog
+	// overlays.
 	rootPages := tview.NewPages()
+	dlg := dialog.New(tapp, rootPages)
 
 	manager := workflow.NewManager(tapp)
+	manager.SetConfirmFn(dlg.Show)
+	sb := manager.StatusBar()
+
+	// Permanent connection indicator in the status bar, following the
+	// active workflow's target.
+	ind := &indicator{sb: sb, multi: len(targets) > 1, health: map[*target.Target]db.Health{}}
+	manager.SetOnSwitch(func(w *workflow.Workflow) { ind.show(w.Target()) })
+	for _, t := range targets {
+		if hr, ok := t.Source.(db.HealthReporter); ok {
+			hr.WatchHealth(func(h db.Health) { ind.set(t, h) })
+		}
+	}
 
-	// Create the default "Sessions" workflow.
-	w := workflow.New("Sessions", tapp, database, refreshInterval)
-
-	// Seed with a SessionList panel.
-	if entry, ok := panel.Global.Get("SessionList"); ok {
-		sessionPanel := entry.Factory(tapp, database)
-		w.AddPanel(sessionPanel, nil, layout.Horizontal)
+	openWorkflow := func(t *target.Target) {
+		manager.AddWorkflow(newWorkflow(tapp, t))
+		manager.SwitchTo(manager.Count() - 1)
+	}
+	for _, t := range targets {
+		manager.AddWorkflow(newWorkflow(tapp, t))
 	}
 
-	manager.AddWorkflow(w)
+	var unavailable []string
+	for _, t := range targets {
+		if n := unavailablePanels(t.Caps); n > 0 {
+			unavailable = append(unavailable, fmt.Sprintf("%s: %d panel(s) unavailable with this user's grants", t.Name, n))
+		}
+	}
+	if len(unavailable) > 0 {
+		sb.Error(strings.Join(unavailable, "; ") + " — open Capabilities (Ctrl+P) for details")
+	}
 
 	// Register the workflow manager as the main page.
 	rootPages.AddPage("main", manager.RootPrimitive(), true, true)
 
 	// Create and register the command palette overlay (initially hidden).
-	pal := palette.New(tapp, database, rootPages, manager)
+	pal := palette.New(tapp, targets, openWorkflow, rootPages, manager)
 	rootPages.AddPage("palette", pal.Primitive(), true, false)
+	rootPages.AddPage("dialog", dlg.Primitive(), true, false)
 
 	// Global keybindings.
 	tapp.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		// An open dialog keeps every key until it is answered.
+		if dlg.Visible() {
+			return event
+		}
 		switch {
 		case event.Key() == tcell.KeyCtrlP:
 			pal.Show()
@@ -119,9 +154,97 @@ func NewApp(database *db.DB) *App {
 	})
 
 	tapp.SetRoot(rootPages, true)
+	// SetRoot focuses the root itself; hand focus back to the first panel.
+	if aw := manager.ActiveWorkflow(); aw != nil && aw.FocusedPrimitive() != nil {
+		tapp.SetFocus(aw.FocusedPrimitive())
+	}
 	return &App{tview: tapp}
 }
 
+// newWorkflow creates a "Sessions" workflow against t, seeded with a
+// SessionList panel, or with the capability report if the connecting user
+// cannot even read the session views.
+func newWorkflow(tapp *tview.Application, t *target.Target) *workflow.Workflow {
+	w := workflow.New("Sessions", tapp, t, refreshInterval)
+	seed := "SessionList"
+	if entry, ok := panel.Global.Get(seed); ok && len(entry.Unmet(t.Caps)) > 0 {
+		seed = "Capabilities"
+	}
+	if entry, ok := panel.Global.Get(seed); ok {
+		w.AddPanel(entry.Factory(tapp, t), nil, layout.Horizontal)
+	}
+	return w
+}
+
+// unavailablePanels counts registered panels whose requirements caps does
+// not meet.
+func unavailablePanels(caps *models.Capabilities) int {
+	n := 0
+	for _, e := range panel.Global.All() {
+		if len(e.Unmet(caps)) > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// indicator keeps the latest health of every target and shows the one the
+// active workflow is bound to. Health updates arrive on supervisor
+// goroutines; switches arrive on the main goroutine.
+type indicator struct {
+	sb     *statusbar.StatusBar
+	multi  bool // prefix the target name when there is more than one
+	mu     sync.Mutex
+	health map[*target.Target]db.Health
+	active *target.Target
+}
+
+func (i *indicator) set(t *target.Target, h db.Health) {
+	i.mu.Lock()
+	i.health[t] = h
+	active := i.active
+	i.mu.Unlock()
+	if t == active {
+		i.render(t, h)
+	}
+}
+
+func (i *indicator) show(t *target.Target) {
+	i.mu.Lock()
+	i.active = t
+	h, ok := i.health[t]
+	i.mu.Unlock()
+	if !ok {
+		// Sources without supervision (e.g. demo) report no health.
+		i.sb.SetIndicator("")
+		return
+	}
+	i.render(t, h)
+}
+
+func (i *indicator) render(t *target.Target, h db.Health) {
+	text := healthIndicator(h)
+	if i.multi {
+		text = tview.Escape(t.Name) + " " + text
+	}
+	i.sb.SetIndicator(text)
+}
+
+// healthIndicator formats h for the status bar's connection indicator.
+func healthIndicator(h db.Health) string {
+	switch h.State {
+	case db.Connected:
+		return fmt.Sprintf("[green]● connected[-] %dms ", h.Latency.Milliseconds())
+	case db.Degraded:
+		return fmt.Sprintf("[yellow]● degraded[-] %dms ", h.Latency.Milliseconds())
+	case db.Reconnecting:
+		return "[red]● reconnecting…[-] "
+	case db.LoginRefused:
+		return "[red]● login refused[-] "
+	}
+	return ""
+}
+
 // Run starts the TUI event loop.
 func (a *App) Run() error {
 	return a.tview.Run()
@@ -60,7 +60,7 @@ func (b *Bus) Emit(ctx Context) {
 }
 
 // Subscribe is a generic helper that avoids string literals at call sites.
-// T must be a type defined in this package (SessionContext or SQLContext).
+// T must be a type defined in this package (e.g. SessionContext or SQLContext).
 func Subscribe[T Context](b *Bus, h func(T)) func() {
 	var zero T
 	typeName := zero.contextType()
@@ -22,3 +22,29 @@ type SQLContext struct {
 }
 
 func (SQLContext) contextType() string { return "SQLContext" }
+
+// EditorContext carries a statement for the query editor to load as is,
+// such as one with its bind variables filled in.
+type EditorContext struct {
+	SQLID string
+	Text  string
+}
+
+func (EditorContext) contextType() string { return "EditorContext" }
+
+// InstanceContext selects the RAC instance the workflow is scoped to.
+// InstID 0 means the whole cluster.
+type InstanceContext struct {
+	InstID int
+}
+
+func (InstanceContext) contextType() string { return "InstanceContext" }
+
+// PDBContext selects the pluggable database (container) the workflow is
+// scoped to. ConID 0 means every container.
+type PDBContext struct {
+	ConID int
+	Name  string
+}
+
+func (PDBContext) contextType() string { return "PDBContext" }
@@ -0,0 +1,66 @@
+package dialog
+
+import (
+	"github.com/rivo/tview"
+)
+
+// Dialog is a modal overlay asking the user to choose between a few
+// buttons, e.g. to confirm an action that changes the database. Like the
+// palette, it lives as a permanent (but initially hidden) page in the root
+// tview.Pages.
+type Dialog struct {
+	app        *tview.Application
+	rootPages  *tview.Pages
+	modal      *tview.Modal
+	priorFocus tview.Primitive
+	visible    bool
+}
+
+// New creates a Dialog. rootPages is the application-level Pages widget
+// the dialog is shown over.
+func New(app *tview.Application, rootPages *tview.Pages) *Dialog {
+	return &Dialog{
+		app:       app,
+		rootPages: rootPages,
+		modal:     tview.NewModal(),
+	}
+}
+
+// Primitive returns the overlay primitive to be registered as a Pages page.
+func (d *Dialog) Primitive() tview.Primitive {
+	return d.modal
+}
+
+// Visible reports whether the dialog is showing.
+func (d *Dialog) Visible() bool {
+	return d.visible
+}
+
+// Show displays text with the given buttons, replacing any dialog already
+// showing, and calls done with the index of the button chosen, or -1 if
+// the user pressed Esc. The first button has the focus, so it should be the
+// safe choice. It must be called on the main goroutine.
+func (d *Dialog) Show(text string, buttons []string, done func(button int)) {
+	if !d.visible {
+		d.priorFocus = d.app.GetFocus()
+	}
+	d.modal.SetText(text).ClearButtons().AddButtons(buttons).SetFocus(0)
+	d.modal.SetDoneFunc(func(button int, _ string) {
+		d.Hide()
+		if done != nil {
+			done(button)
+		}
+	})
+	d.visible = true
+	d.rootPages.ShowPage("dialog")
+	d.app.SetFocus(d.modal)
+}
+
+// Hide dismisses the dialog and restores the previous focus.
+func (d *Dialog) Hide() {
+	d.visible = false
+	d.rootPages.HidePage("dialog")
+	if d.priorFocus != nil {
+		d.app.SetFocus(d.priorFocus)
+	}
+}
@@ -1,39 +1,47 @@
 package palette
 
 import (
-	"github.com/mdoeren/otop/internal/db"
+	"strings"
+
+	"github.com/mdoeren/otop/internal/target"
 	"github.com/mdoeren/otop/internal/ui/layout"
 	"github.com/mdoeren/otop/internal/ui/panel"
 	"github.com/mdoeren/otop/internal/ui/workflow"
 	"github.com/rivo/tview"
 )
 
-// Palette is a command palette modal overlay for opening new panels.
+// Palette is a command palette modal overlay for opening new panels in the
+// active workflow, or new workflows against any connected target.
 // It lives as a permanent (but initially hidden) page in the root tview.Pages.
 type Palette struct {
-	app        *tview.Application
-	db         *db.DB
-	rootPages  *tview.Pages
-	manager    *workflow.Manager
-	list       *tview.List
-	overlay    tview.Primitive
-	priorFocus tview.Primitive
+	app          *tview.Application
+	targets      []*target.Target
+	openWorkflow func(*target.Target)
+	rootPages    *tview.Pages
+	manager      *workflow.Manager
+	list         *tview.List
+	overlay      tview.Primitive
+	priorFocus   tview.Primitive
 }
 
 // New creates a Palette. rootPages is the application-level Pages widget
-// so the palette can be shown as a full-screen overlay.
-func New(app *tview.Application, database *db.DB, rootPages *tview.Pages, manager *workflow.Manager) *Palette {
+// so the palette can be shown as a full-screen overlay. Panels whose
+// requirements the active workflow's target does not meet are listed but
+// disabled. openWorkflow is called to open a new workflow against one of
+// targets.
+func New(app *tview.Application, targets []*target.Target, openWorkflow func(*target.Target), rootPages *tview.Pages, manager *workflow.Manager) *Palette {
 	p := &Palette{
-		app:       app,
-		db:        database,
-		rootPages: rootPages,
-		manager:   manager,
-		list:      tview.NewList(),
+		app:          app,
+		targets:      targets,
+		openWorkflow: openWorkflow,
+		rootPages:    rootPages,
+		manager:      manager,
+		list:         tview.NewList(),
 	}
 
 	p.list.
 		SetBorder(true).
-		SetTitle(" Open Panel (Enter to select, Esc to cancel) ")
+		SetTitle(" Open Panel or Workflow (Enter to select, Esc to cancel) ")
 	p.list.SetDoneFunc(func() { p.Hide() })
 
 	// Center the list with proportional spacers
@@ -61,16 +69,38 @@ func (p *Palette) Show() {
 	p.priorFocus = p.app.GetFocus()
 
 	p.list.Clear()
+	if w := p.manager.ActiveWorkflow(); w != nil {
+		p.addPanels(w.Target())
+	}
+	for _, t := range p.targets {
+		t := t // capture
+		p.list.AddItem("New workflow: "+t.Name, "Sessions workflow against "+t.Name, 0, func() {
+			p.Hide()
+			p.openWorkflow(t)
+		})
+	}
+
+	p.rootPages.ShowPage("palette")
+	p.app.SetFocus(p.list)
+}
+
+// addPanels lists every registered panel type, disabling those whose
+// requirements t does not meet.
+func (p *Palette) addPanels(t *target.Target) {
 	for _, entry := range panel.Global.All() {
 		entry := entry // capture
+		if reasons := entry.Unmet(t.Caps); len(reasons) > 0 {
+			p.list.AddItem("[gray]"+entry.TypeName+" (unavailable)[-]", reasons[0], 0, func() {
+				p.Hide()
+				p.manager.StatusBar().Error(entry.TypeName + " unavailable: " + strings.Join(reasons, "; "))
+			})
+			continue
+		}
 		p.list.AddItem(entry.TypeName, entry.Description, 0, func() {
 			p.Hide()
 			p.openPanel(entry)
 		})
 	}
-
-	p.rootPages.ShowPage("palette")
-	p.app.SetFocus(p.list)
 }
 
 // Hide dismisses the palette and restores the previous focus.
@@ -88,7 +118,7 @@ func (p *Palette) openPanel(entry panel.Entry) {
 	if w == nil {
 		return
 	}
-	newPanel := entry.Factory(p.app, p.db)
+	newPanel := entry.Factory(p.app, w.Target())
 	target := w.FocusedPrimitive()
 	w.AddPanel(newPanel, target, layout.Vertical)
 }
@@ -1,7 +1,9 @@
 package panel
 
 import (
-	"github.com/mdoeren/otop/internal/db"
+	"context"
+
+	"github.com/mdoeren/otop/internal/target"
 	uictx "github.com/mdoeren/otop/internal/ui/context"
 	"github.com/rivo/tview"
 )
@@ -24,10 +26,13 @@ type Panel interface {
 	// Refresh is called periodically inside QueueUpdateDraw.
 	Refresh()
 
-	// Mount is called once after the panel is added to a workflow.
-	Mount()
+	// Mount is called when the panel goes live: after it is added to the
+	// active workflow, or when its workflow tab is started. ctx is cancelled
+	// when the tab stops; run all DB I/O under a context derived from it.
+	Mount(ctx context.Context)
 
-	// Unmount is called before the panel is removed; cancel goroutines here.
+	// Unmount is called when the panel is removed or its workflow tab is
+	// stopped; cancel the context from Mount and any goroutines here.
 	Unmount()
 }
 
@@ -43,5 +48,18 @@ type Reporter interface {
 	SetStatusFn(fn func(error))
 }
 
-// Factory creates a new Panel instance.
-type Factory func(app *tview.Application, db *db.DB) Panel
+// Confirmer is an optional interface. If a Panel also implements Confirmer,
+// the workflow wires up a function the panel can call to ask the user, in a
+// dialog over the whole screen, before it acts on the database.
+type Confirmer interface {
+	SetConfirmFn(fn ConfirmFunc)
+}
+
+// ConfirmFunc shows text in a dialog with the given buttons and calls done
+// with the index of the one chosen, or -1 if the user cancelled. The first
+// button has the focus, so it should be the safe choice. It must be called
+// on the main goroutine.
+type ConfirmFunc func(text string, buttons []string, done func(button int))
+
+// Factory creates a new Panel instance showing the target database t.
+type Factory func(app *tview.Application, t *target.Target) Panel
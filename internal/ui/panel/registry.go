@@ -1,12 +1,22 @@
 package panel
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/mdoeren/otop/internal/models"
+)
 
 // Entry describes a panel type available in the command palette.
 type Entry struct {
 	TypeName    string
 	Description string
 	Factory     Factory
+	Requires    Requirement
+}
+
+// Unmet returns why the panel cannot run against caps, or nil if it can.
+func (e Entry) Unmet(caps *models.Capabilities) []string {
+	return e.Requires.Unmet(caps)
 }
 
 // Registry is a thread-safe collection of panel factories.
@@ -0,0 +1,51 @@
+package panel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// Requirement describes what a panel needs from the database. The zero
+// Requirement is always met.
+type Requirement struct {
+	// Views lists the views the panel queries, e.g. "GV$SQL_PLAN".
+	Views []string
+
+	// DiagnosticsPack is set for panels that read licensed AWR/ASH data.
+	DiagnosticsPack bool
+}
+
+// Unmet returns a human-readable reason for every part of r that caps does
+// not satisfy, or nil when the panel can run. A nil caps (not probed) is
+// treated as satisfying everything.
+func (r Requirement) Unmet(caps *models.Capabilities) []string {
+	if caps == nil {
+		return nil
+	}
+	var reasons []string
+	for _, v := range r.Views {
+		if !caps.CanRead(v) {
+			reasons = append(reasons, fmt.Sprintf("cannot read %s (%s)", v, GrantFor(v, caps.User)))
+		}
+	}
+	if r.DiagnosticsPack && !caps.DiagnosticsPack {
+		reasons = append(reasons, "Diagnostics Pack not enabled (control_management_pack_access)")
+	}
+	return reasons
+}
+
+// GrantFor returns the statement a DBA would run to let user read view.
+// Dynamic performance views are granted through their underlying V_$/GV_$
+// synonyms.
+func GrantFor(view, user string) string {
+	if user == "" {
+		user = "<user>"
+	}
+	obj := view
+	if i := strings.Index(view, "$"); i > 0 && strings.HasSuffix(view[:i], "V") {
+		obj = view[:i] + "_" + view[i:]
+	}
+	return fmt.Sprintf("GRANT SELECT ON SYS.%s TO %s", obj, user)
+}
@@ -0,0 +1,327 @@
+package panels
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/rivo/tview"
+)
+
+// aasDimension is what an AAS chart breaks active sessions down by.
+type aasDimension int
+
+const (
+	byWaitClass aasDimension = iota
+	bySQLID
+	byUser
+	aasDimensions // number of dimensions
+)
+
+func (d aasDimension) String() string {
+	switch d {
+	case bySQLID:
+		return "SQL_ID"
+	case byUser:
+		return "user"
+	default:
+		return "wait class"
+	}
+}
+
+// key returns the series sample a belongs to.
+func (d aasDimension) key(a models.ASHSample) string {
+	switch d {
+	case bySQLID:
+		if a.SQLID == "" {
+			return "(no SQL)"
+		}
+		return a.SQLID
+	case byUser:
+		return a.Username
+	default:
+		if a.WaitClass == "" {
+			return aasOther
+		}
+		return a.WaitClass
+	}
+}
+
+// aasTopN is how many series a chart shows before folding the rest into
+// "Other".
+const aasTopN = 8
+
+const aasOther = "Other"
+
+// waitClassColors follows the colours Enterprise Manager uses for wait
+// classes, so the chart reads like the one DBAs already know.
+var waitClassColors = map[string]tcell.Color{
+	"CPU":            tcell.ColorGreen,
+	"Scheduler":      tcell.ColorLightGreen,
+	"User I/O":       tcell.ColorRoyalBlue,
+	"System I/O":     tcell.ColorDeepSkyBlue,
+	"Concurrency":    tcell.ColorMaroon,
+	"Application":    tcell.ColorRed,
+	"Commit":         tcell.ColorOrange,
+	"Configuration":  tcell.ColorOlive,
+	"Administrative": tcell.ColorSlateGray,
+	"Network":        tcell.ColorSienna,
+	"Cluster":        tcell.ColorWheat,
+	"Queueing":       tcell.ColorTan,
+	aasOther:         tcell.ColorHotPink,
+}
+
+// seriesColors colours the series of the other dimensions, in rank order.
+var seriesColors = []tcell.Color{
+	tcell.ColorGreen, tcell.ColorRoyalBlue, tcell.ColorOrange, tcell.ColorRed,
+	tcell.ColorTeal, tcell.ColorPurple, tcell.ColorOlive, tcell.ColorSienna,
+}
+
+// partialBlocks draws the top of a column to an eighth of a cell.
+var partialBlocks = []rune{' ', '▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+// aasChart draws average active sessions over a time window as stacked
+// columns, one series per value of the chosen dimension, with a legend
+// below. Each sample stands for its Interval of DB time, so a column's
+// height is the DB time of the samples in it over the column's duration.
+// The chart is laid out in Draw, so it adapts to the space it is given.
+type aasChart struct {
+	*tview.Box
+	samples  []models.ASHSample
+	from, to time.Time
+	step     time.Duration // shortest column; the samples' time resolution
+	dim      aasDimension
+	message  string // shown instead of the chart when set
+}
+
+func newAASChart() *aasChart {
+	return &aasChart{Box: tview.NewBox()}
+}
+
+// set replaces the chart's data: samples taken between from and to, at
+// most one per session every step.
+func (c *aasChart) set(samples []models.ASHSample, from, to time.Time, step time.Duration) {
+	c.samples, c.from, c.to, c.step = samples, from, to, step
+	c.message = ""
+}
+
+// series is one stacked layer of the chart.
+type series struct {
+	key   string
+	color tcell.Color
+	avg   float64 // average active sessions over the window
+}
+
+// rank returns the chart's series, busiest first, with everything beyond
+// aasTopN folded into "Other", and the series index of every sample key.
+func (c *aasChart) rank() ([]series, map[string]int) {
+	counts := map[string]time.Duration{}
+	for _, a := range c.samples {
+		counts[c.dim.key(a)] += a.Interval
+	}
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	var out []series
+	index := make(map[string]int, len(keys))
+	for _, k := range keys {
+		if len(out) < aasTopN && k != aasOther {
+			index[k] = len(out)
+			out = append(out, series{key: k})
+		}
+	}
+	other := -1
+	for _, k := range keys {
+		if _, ok := index[k]; ok {
+			continue
+		}
+		if other < 0 {
+			other = len(out)
+			out = append(out, series{key: aasOther})
+		}
+		index[k] = other
+	}
+	window := c.to.Sub(c.from).Seconds()
+	for k, d := range counts {
+		out[index[k]].avg += d.Seconds() / window
+	}
+	for i := range out {
+		out[i].color = c.color(out[i].key, i)
+	}
+	return out, index
+}
+
+func (c *aasChart) color(key string, rank int) tcell.Color {
+	if c.dim == byWaitClass {
+		if col, ok := waitClassColors[key]; ok {
+			return col
+		}
+		return waitClassColors[aasOther]
+	}
+	if key == aasOther {
+		return tcell.ColorGray
+	}
+	return seriesColors[rank%len(seriesColors)]
+}
+
+// Draw draws the chart within the box's inner rectangle.
+func (c *aasChart) Draw(screen tcell.Screen) {
+	c.Box.DrawForSubclass(screen, c)
+	x, y, width, height := c.GetInnerRect()
+	if width <= 0 || height <= 0 {
+		return
+	}
+	if c.message != "" {
+		tview.Print(screen, c.message, x+1, y, width-2, tview.AlignLeft, tcell.ColorGray)
+		return
+	}
+	window := c.to.Sub(c.from)
+	if window <= 0 {
+		return
+	}
+
+	ranked, index := c.rank()
+	legend := seriesLegend(ranked, width, "no active sessions in this window")
+
+	const axisWidth = 7 // "  12.5┤"
+	rows := height - len(legend) - 1
+	cols := width - axisWidth
+	if rows < 2 || cols < 2 {
+		return
+	}
+	bucket := window / time.Duration(cols)
+	if c.step > 0 {
+		// Make every column a whole number of steps: a column shorter than
+		// a step would flicker between zero and a spike, and one that
+		// straddles steps unevenly would show a comb.
+		steps := (window/c.step + time.Duration(cols) - 1) / time.Duration(cols)
+		bucket = max(steps, 1) * c.step
+		cols = int((window + bucket - 1) / bucket)
+	}
+
+	// Stack the samples into columns.
+	values := make([][]float64, cols)
+	for i := range values {
+		values[i] = make([]float64, len(ranked))
+	}
+	for _, a := range c.samples {
+		col := int(a.SampleTime.Sub(c.from) / bucket)
+		if col < 0 || col >= cols {
+			continue
+		}
+		values[col][index[c.dim.key(a)]] += a.Interval.Seconds() / bucket.Seconds()
+	}
+	peak := 0.0
+	for _, v := range values {
+		total := 0.0
+		for _, s := range v {
+			total += s
+		}
+		peak = max(peak, total)
+	}
+	top := math.Max(1, math.Ceil(peak))
+
+	// Axis.
+	axis := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	for r := range rows {
+		screen.SetContent(x+axisWidth-1, y+r, '│', nil, axis)
+	}
+	label := func(v float64, r int) {
+		tview.Print(screen, fmt.Sprintf("%5.1f┤", v), x, y+r, axisWidth, tview.AlignRight, tcell.ColorGray)
+	}
+	label(top, 0)
+	label(top/2, rows/2)
+	label(0, rows-1)
+	timeRow := y + rows
+	tview.Print(screen, "-"+formatWindow(window), x+axisWidth, timeRow, cols, tview.AlignLeft, tcell.ColorGray)
+	tview.Print(screen, "-"+formatWindow(window/2), x+axisWidth, timeRow, cols, tview.AlignCenter, tcell.ColorGray)
+	tview.Print(screen, "now", x+axisWidth, timeRow, cols, tview.AlignRight, tcell.ColorGray)
+
+	// Columns, drawn bottom up. A cell takes the colour of the series at
+	// its middle; the topmost cell is drawn as a partial block.
+	scale := float64(rows) / top
+	for col, v := range values {
+		cx := x + axisWidth + col
+		var total float64
+		for _, s := range v {
+			total += s
+		}
+		h := total * scale
+		for r := 0; r < rows && float64(r) < h; r++ {
+			ch := '█'
+			mid := float64(r) + 0.5
+			if float64(r)+1 > h {
+				ch = partialBlocks[int((h-float64(r))*8)]
+				mid = h - 1e-9 // the topmost series
+			}
+			cum, color := 0.0, tcell.ColorDefault
+			for i, s := range v {
+				cum += s * scale
+				if s > 0 {
+					color = ranked[i].color
+				}
+				if cum > mid {
+					break
+				}
+			}
+			screen.SetContent(cx, y+rows-1-r, ch, nil, tcell.StyleDefault.Foreground(color))
+		}
+	}
+
+	for i, line := range legend {
+		tview.Print(screen, line, x+1, timeRow+1+i, width-1, tview.AlignLeft, tcell.ColorDefault)
+	}
+}
+
+// seriesLegend lays out one "█ key avg (pct%)" entry per series, wrapping
+// entries onto as many lines as width requires. It shows empty, greyed,
+// when the series add up to nothing.
+func seriesLegend(ranked []series, width int, empty string) []string {
+	total := 0.0
+	for _, s := range ranked {
+		total += s.avg
+	}
+	if total == 0 {
+		return []string{"[gray]" + empty + "[-]"}
+	}
+	var lines []string
+	line, lineWidth := "", 0
+	for _, s := range ranked {
+		text := fmt.Sprintf("%s %.2f (%.0f%%)", s.key, s.avg, 100*s.avg/total)
+		w := len([]rune(text)) + 2
+		if lineWidth > 0 && lineWidth+w+3 > width-1 {
+			lines = append(lines, line)
+			line, lineWidth = "", 0
+		}
+		if lineWidth > 0 {
+			line += "   "
+			lineWidth += 3
+		}
+		line += fmt.Sprintf("[#%06x]█[-] %s", s.color.Hex(), tview.Escape(text))
+		lineWidth += w
+	}
+	return append(lines, line)
+}
+
+// formatWindow renders a chart window such as 15m or 1h.
+func formatWindow(d time.Duration) string {
+	switch {
+	case d >= time.Hour && d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
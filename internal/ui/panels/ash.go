@@ -0,0 +1,378 @@
+package panels
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/target"
+	uictx "github.com/mdoeren/otop/internal/ui/context"
+	"github.com/mdoeren/otop/internal/ui/panel"
+	"github.com/rivo/tview"
+)
+
+// ashWindows are the time range lengths 'w' cycles through.
+var ashWindows = []time.Duration{15 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// ashColumns is roughly how many points in time a range is fetched at;
+// finer detail would not fit on screen.
+const ashColumns = 300
+
+// ashTopN is how many rows the breakdown table lists.
+const ashTopN = 20
+
+// ashBreakdown is what the table below the chart ranks.
+type ashBreakdown int
+
+const (
+	topSQL ashBreakdown = iota
+	topEvents
+	topSessions
+	ashBreakdowns // number of breakdowns
+)
+
+func (b ashBreakdown) String() string {
+	switch b {
+	case topEvents:
+		return "Top events"
+	case topSessions:
+		return "Top sessions"
+	default:
+		return "Top SQL"
+	}
+}
+
+// topRow is one ranked row of the breakdown table.
+type topRow struct {
+	sample models.ASHSample // identifies the row; Interval is its DB time
+	events map[string]time.Duration
+	sqlIDs map[string]time.Duration
+}
+
+// ASHPanel charts Oracle's Active Session History over a selectable time
+// range and ranks the SQL, wait events or sessions behind it. Selecting a
+// statement or session emits SQLContext or SessionContext, so the rest of
+// the workflow follows. It needs the Diagnostics Pack.
+type ASHPanel struct {
+	app         *tview.Application
+	src         db.Source
+	chart       *aasChart
+	table       *tview.Table
+	flex        *tview.Flex
+	emitFn      func(uictx.Context)
+	statusFn    func(error)
+	ctx         context.Context
+	cancel      context.CancelFunc
+	fetchCancel context.CancelFunc
+
+	window    int       // index into ashWindows
+	end       time.Time // end of the range; zero follows the present
+	breakdown ashBreakdown
+	samples   []models.ASHSample
+	from, to  time.Time
+	fetched   time.Time
+	rows      []topRow
+}
+
+func newASHPanel(app *tview.Application, t *target.Target) panel.Panel {
+	p := &ASHPanel{
+		app:   app,
+		src:   t.Source,
+		chart: newAASChart(),
+		table: tview.NewTable().SetBorders(false).SetSelectable(true, false).SetFixed(1, 0),
+	}
+	p.chart.SetBorder(true)
+	p.table.SetBorder(true)
+	p.flex = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(p.chart, 0, 3, false).
+		AddItem(p.table, 0, 2, true)
+
+	p.table.SetSelectedFunc(func(row, _ int) {
+		// row 0 is the header
+		idx := row - 1
+		if p.emitFn == nil || idx < 0 || idx >= len(p.rows) {
+			return
+		}
+		r := p.rows[idx]
+		switch p.breakdown {
+		case topSQL:
+			if r.sample.SQLID != "" {
+				p.emitFn(uictx.SQLContext{SQLID: r.sample.SQLID})
+			}
+		case topSessions:
+			s := models.Session{
+				InstID:         r.sample.InstID,
+				ConID:          r.sample.ConID,
+				SID:            r.sample.SID,
+				Serial:         r.sample.Serial,
+				Username:       r.sample.Username,
+				SQLID:          busiest(r.sqlIDs),
+				SQLChildNumber: -1,
+				WaitEvent:      busiest(r.events),
+			}
+			p.emitFn(uictx.SessionContext{Session: s})
+			if s.SQLID != "" {
+				p.emitFn(uictx.SQLContext{SQLID: s.SQLID})
+			}
+		}
+	})
+	p.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'w':
+			p.window = (p.window + 1) % len(ashWindows)
+			p.fetch()
+			return nil
+		case '[':
+			p.end = p.rangeEnd().Add(-ashWindows[p.window] / 2)
+			p.fetch()
+			return nil
+		case ']':
+			if p.end.IsZero() {
+				return nil
+			}
+			p.end = p.end.Add(ashWindows[p.window] / 2)
+			if !p.end.Before(time.Now()) {
+				p.end = time.Time{}
+			}
+			p.fetch()
+			return nil
+		case 'd':
+			p.chart.dim = (p.chart.dim + 1) % aasDimensions
+			p.renderTitle()
+			return nil
+		case 'b':
+			p.breakdown = (p.breakdown + 1) % ashBreakdowns
+			p.renderTable()
+			return nil
+		}
+		return event
+	})
+	p.renderTitle()
+	return p
+}
+
+func (p *ASHPanel) Name() string                     { return "ASH" }
+func (p *ASHPanel) Primitive() tview.Primitive       { return p.flex }
+func (p *ASHPanel) Subscriptions() []string          { return []string{"InstanceContext", "PDBContext"} }
+func (p *ASHPanel) SetEmitFn(fn func(uictx.Context)) { p.emitFn = fn }
+func (p *ASHPanel) SetStatusFn(fn func(error))       { p.statusFn = fn }
+
+func (p *ASHPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.fetch()
+}
+
+func (p *ASHPanel) Unmount() {
+	p.cancel()
+}
+
+// Refresh refetches a range that follows the present, less often for long
+// ranges, where a few seconds more make no visible difference.
+func (p *ASHPanel) Refresh() {
+	if p.end.IsZero() && time.Since(p.fetched) >= ashWindows[p.window]/60 {
+		p.fetch()
+	}
+}
+
+// OnContext refetches the range in the workflow's new scope.
+func (p *ASHPanel) OnContext(ctx uictx.Context) {
+	switch ctx.(type) {
+	case uictx.InstanceContext, uictx.PDBContext:
+		p.fetch()
+	}
+}
+
+// rangeEnd returns the end of the selected range.
+func (p *ASHPanel) rangeEnd() time.Time {
+	if p.end.IsZero() {
+		return time.Now()
+	}
+	return p.end
+}
+
+// fetch cancels any fetch still in flight and loads the selected range.
+func (p *ASHPanel) fetch() {
+	if p.ctx == nil {
+		return // not mounted yet
+	}
+	if p.fetchCancel != nil {
+		p.fetchCancel()
+	}
+	var ctx context.Context
+	ctx, p.fetchCancel = context.WithCancel(p.ctx)
+	to := p.rangeEnd()
+	from := to.Add(-ashWindows[p.window])
+	step := max(ashWindows[p.window]/ashColumns, time.Second)
+	if from.Before(time.Now().Add(-time.Hour)) {
+		// Older samples come from AWR, which keeps one every ten seconds.
+		step = max(step, 10*time.Second)
+	}
+	p.fetched = time.Now()
+	p.renderTitle()
+
+	go func() {
+		samples, err := p.src.GetActiveSessionHistory(ctx, from, to, step)
+		if err != nil {
+			if p.statusFn != nil && ctx.Err() == nil {
+				p.statusFn(err)
+			}
+			return
+		}
+		p.app.QueueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			p.samples, p.from, p.to = samples, from, to
+			p.chart.set(samples, from, to, step)
+			p.renderTitle()
+			p.renderTable()
+		})
+	}()
+}
+
+func (p *ASHPanel) renderTitle() {
+	window := ashWindows[p.window]
+	span := "last " + formatWindow(window)
+	if !p.end.IsZero() {
+		layout := "15:04"
+		if window >= 6*time.Hour || p.end.YearDay() != time.Now().YearDay() {
+			layout = "Jan 2 15:04"
+		}
+		span = p.end.Add(-window).Format(layout) + "–" + p.end.Format(layout)
+	}
+	p.chart.SetTitle(fmt.Sprintf(" ASH · %s · by %s ", span, p.chart.dim))
+	p.table.SetTitle(" " + p.breakdown.String() + " ")
+}
+
+// rank sums the samples' DB time per row of the current breakdown, busiest
+// first.
+func (p *ASHPanel) rank() []topRow {
+	byKey := map[models.ASHSample]*topRow{}
+	for _, a := range p.samples {
+		var k models.ASHSample
+		switch p.breakdown {
+		case topSQL:
+			k.SQLID = a.SQLID
+		case topEvents:
+			k.WaitClass, k.Event = a.WaitClass, a.Event
+		case topSessions:
+			k.InstID, k.ConID, k.SID, k.Serial, k.Username = a.InstID, a.ConID, a.SID, a.Serial, a.Username
+		}
+		r, ok := byKey[k]
+		if !ok {
+			r = &topRow{sample: k, events: map[string]time.Duration{}, sqlIDs: map[string]time.Duration{}}
+			byKey[k] = r
+		}
+		r.sample.Interval += a.Interval
+		event := a.Event
+		if event == "" {
+			event = "ON CPU"
+		}
+		r.events[event] += a.Interval
+		r.sqlIDs[a.SQLID] += a.Interval
+	}
+	rows := make([]topRow, 0, len(byKey))
+	for _, r := range byKey {
+		rows = append(rows, *r)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].sample.Interval != rows[j].sample.Interval {
+			return rows[i].sample.Interval > rows[j].sample.Interval
+		}
+		return fmt.Sprint(rows[i].sample) < fmt.Sprint(rows[j].sample)
+	})
+	if len(rows) > ashTopN {
+		rows = rows[:ashTopN]
+	}
+	return rows
+}
+
+// busiest returns the key with the most DB time.
+func busiest(m map[string]time.Duration) string {
+	var best string
+	for k, d := range m {
+		if d > m[best] || (d == m[best] && k < best) {
+			best = k
+		}
+	}
+	return best
+}
+
+func (p *ASHPanel) renderTable() {
+	p.renderTitle()
+	p.table.Clear()
+	p.rows = p.rank()
+
+	var headers []string
+	switch p.breakdown {
+	case topSQL:
+		headers = []string{"SQL ID", "AAS", "Activity", "Top Event"}
+	case topEvents:
+		headers = []string{"Event", "Wait Class", "AAS", "Activity"}
+	case topSessions:
+		headers = []string{"Inst", "SID", "Serial#", "User", "AAS", "Activity", "Top SQL ID", "Top Event"}
+	}
+	for col, h := range headers {
+		p.table.SetCell(0, col,
+			tview.NewTableCell(h).
+				SetTextColor(tcell.ColorYellow).
+				SetSelectable(false).
+				SetExpansion(1))
+	}
+	if len(p.rows) == 0 {
+		p.table.SetCell(1, 0, tview.NewTableCell("[gray]No samples in this range[-]").SetSelectable(false))
+		return
+	}
+
+	var total time.Duration
+	for _, a := range p.samples {
+		total += a.Interval
+	}
+	window := p.to.Sub(p.from).Seconds()
+	for i, r := range p.rows {
+		aas := fmt.Sprintf("%.2f", r.sample.Interval.Seconds()/window)
+		activity := fmt.Sprintf("%5.1f%%", 100*r.sample.Interval.Seconds()/total.Seconds())
+		var cells []string
+		switch p.breakdown {
+		case topSQL:
+			sqlID := r.sample.SQLID
+			if sqlID == "" {
+				sqlID = "(no SQL)"
+			}
+			cells = []string{sqlID, aas, activity, busiest(r.events)}
+		case topEvents:
+			event := r.sample.Event
+			if event == "" {
+				event = "ON CPU"
+			}
+			cells = []string{event, r.sample.WaitClass, aas, activity}
+		case topSessions:
+			cells = []string{
+				fmt.Sprintf("%d", r.sample.InstID),
+				fmt.Sprintf("%d", r.sample.SID),
+				fmt.Sprintf("%d", r.sample.Serial),
+				r.sample.Username, aas, activity,
+				busiest(r.sqlIDs), busiest(r.events),
+			}
+		}
+		for col, c := range cells {
+			p.table.SetCell(i+1, col, tview.NewTableCell(tview.Escape(c)).SetExpansion(1))
+		}
+	}
+}
+
+func init() {
+	panel.Global.Register(panel.Entry{
+		TypeName:    "ASH",
+		Description: "Active Session History over a time range, with top SQL, events and sessions",
+		Factory:     newASHPanel,
+		Requires: panel.Requirement{
+			Views:           []string{"GV$ACTIVE_SESSION_HISTORY", "DBA_HIST_ACTIVE_SESS_HISTORY", "CDB_USERS", "V$DATABASE"},
+			DiagnosticsPack: true,
+		},
+	})
+}
@@ -0,0 +1,132 @@
+package panels
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/target"
+	uictx "github.com/mdoeren/otop/internal/ui/context"
+	"github.com/mdoeren/otop/internal/ui/panel"
+	"github.com/rivo/tview"
+)
+
+// CapabilitiesPanel reports what the connected database offers, which views
+// the connecting user can read, and which panels are unavailable and why.
+type CapabilitiesPanel struct {
+	app      *tview.Application
+	src      db.Source
+	text     *tview.TextView
+	statusFn func(error)
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+func newCapabilitiesPanel(app *tview.Application, t *target.Target) panel.Panel {
+	p := &CapabilitiesPanel{
+		app:  app,
+		src:  t.Source,
+		text: tview.NewTextView().SetDynamicColors(true).SetScrollable(true),
+	}
+	p.text.SetTitle(" Capabilities ").SetBorder(true)
+	return p
+}
+
+func (p *CapabilitiesPanel) Name() string               { return "Capabilities" }
+func (p *CapabilitiesPanel) Primitive() tview.Primitive { return p.text }
+func (p *CapabilitiesPanel) Subscriptions() []string    { return nil }
+func (p *CapabilitiesPanel) OnContext(uictx.Context)    {}
+func (p *CapabilitiesPanel) Refresh()                   {}
+func (p *CapabilitiesPanel) SetStatusFn(fn func(error)) { p.statusFn = fn }
+
+func (p *CapabilitiesPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.text.SetText("[gray]Probing…[-]")
+	go p.probe(p.ctx)
+}
+
+func (p *CapabilitiesPanel) Unmount() {
+	p.cancel()
+}
+
+func (p *CapabilitiesPanel) probe(ctx context.Context) {
+	caps, err := p.src.GetCapabilities(ctx)
+	if err != nil {
+		if p.statusFn != nil {
+			p.statusFn(err)
+		}
+		return
+	}
+	p.app.QueueUpdateDraw(func() {
+		if ctx.Err() != nil {
+			return
+		}
+		p.render(caps)
+	})
+}
+
+func (p *CapabilitiesPanel) render(caps *models.Capabilities) {
+	var sb strings.Builder
+	yesNo := func(b bool) string {
+		if b {
+			return "[green]yes[-]"
+		}
+		return "[gray]no[-]"
+	}
+
+	fmt.Fprintf(&sb, "[yellow]Database:[-]\n")
+	fmt.Fprintf(&sb, "  %s\n", tview.Escape(caps.Banner))
+	fmt.Fprintf(&sb, "  Version:           %s\n", caps.Version)
+	fmt.Fprintf(&sb, "  Edition:           %s\n", caps.Edition)
+	fmt.Fprintf(&sb, "  Connected as:      %s\n", caps.User)
+	fmt.Fprintf(&sb, "  RAC:               %s\n", yesNo(caps.RAC))
+	fmt.Fprintf(&sb, "  Multitenant (CDB): %s\n", yesNo(caps.Multitenant))
+	fmt.Fprintf(&sb, "  Diagnostics Pack:  %s\n", yesNo(caps.DiagnosticsPack))
+	if len(caps.Options) > 0 {
+		fmt.Fprintf(&sb, "  Options:           %s\n", strings.Join(caps.Options, ", "))
+	}
+
+	fmt.Fprintf(&sb, "\n[yellow]Views:[-]\n")
+	var missing []string
+	for _, v := range db.MonitoredViews {
+		if caps.CanRead(v) {
+			fmt.Fprintf(&sb, "  [green]✓[-] %s\n", v)
+		} else {
+			fmt.Fprintf(&sb, "  [red]✗[-] %s\n", v)
+			missing = append(missing, v)
+		}
+	}
+
+	fmt.Fprintf(&sb, "\n[yellow]Panels:[-]\n")
+	for _, e := range panel.Global.All() {
+		reasons := e.Unmet(caps)
+		if len(reasons) == 0 {
+			fmt.Fprintf(&sb, "  [green]✓[-] %s\n", e.TypeName)
+			continue
+		}
+		fmt.Fprintf(&sb, "  [red]✗[-] %s\n", e.TypeName)
+		for _, r := range reasons {
+			fmt.Fprintf(&sb, "      %s\n", tview.Escape(r))
+		}
+	}
+
+	if len(missing) > 0 {
+		fmt.Fprintf(&sb, "\n[yellow]Missing grants:[-]\n")
+		for _, v := range missing {
+			fmt.Fprintf(&sb, "  %s;\n", panel.GrantFor(v, caps.User))
+		}
+		fmt.Fprintf(&sb, "\n  [gray]Alternatively: GRANT SELECT_CATALOG_ROLE TO %s;[-]\n", caps.User)
+	}
+
+	p.text.SetText(sb.String())
+}
+
+func init() {
+	panel.Global.Register(panel.Entry{
+		TypeName:    "Capabilities",
+		Description: "Database version, options, grants and unavailable panels",
+		Factory:     newCapabilitiesPanel,
+	})
+}
@@ -0,0 +1,42 @@
+package panels
+
+import (
+	"context"
+
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/sampler"
+	"github.com/rivo/tview"
+)
+
+// follow subscribes to feed, handing each snapshot to apply on the main
+// goroutine and each failed sample to *statusFn. Snapshots and errors still
+// on their way when the returned function is called are dropped, so a panel
+// that moves to another feed never shows one from the feed it left.
+func follow[T any](app *tview.Application, ctx context.Context, feed *sampler.Feed[T], statusFn *func(error), apply func(sampler.Snapshot[T])) (unsubscribe func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	unsub := feed.Subscribe(func(snap sampler.Snapshot[T]) {
+		if snap.Err != nil {
+			if ctx.Err() == nil && *statusFn != nil {
+				(*statusFn)(snap.Err)
+			}
+			return
+		}
+		app.QueueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			apply(snap)
+		})
+	})
+	return func() {
+		cancel()
+		unsub()
+	}
+}
+
+// followScope follows the feed feedOf returns for the workflow's current
+// scope, as follow does. Panels call it again, after unsubscribing, when
+// InstanceContext or PDBContext changes the scope.
+func followScope[T any](app *tview.Application, ctx context.Context, feedOf func(db.Scope) *sampler.Feed[T], statusFn *func(error), apply func(sampler.Snapshot[T])) (unsubscribe func()) {
+	return follow(app, ctx, feedOf(db.ScopeOf(ctx)), statusFn, apply)
+}
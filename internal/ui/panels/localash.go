@@ -0,0 +1,119 @@
+package panels
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/ash"
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/target"
+	uictx "github.com/mdoeren/otop/internal/ui/context"
+	"github.com/mdoeren/otop/internal/ui/panel"
+	"github.com/rivo/tview"
+)
+
+// localASHWindows are the time windows 'w' cycles through.
+var localASHWindows = []time.Duration{5 * time.Minute, 15 * time.Minute, time.Hour}
+
+// LocalASHPanel charts average active sessions from otop's own samples of
+// V$SESSION, broken down by wait class, SQL_ID or user. It works without
+// the Diagnostics Pack, but only covers the time otop has been running.
+type LocalASHPanel struct {
+	app    *tview.Application
+	rec    *ash.Recorder
+	chart  *aasChart
+	window int // index into localASHWindows
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newLocalASHPanel(app *tview.Application, t *target.Target) panel.Panel {
+	p := &LocalASHPanel{
+		app:    app,
+		rec:    t.ASH,
+		chart:  newAASChart(),
+		window: 1,
+	}
+	p.chart.SetBorder(true)
+	p.chart.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'd':
+			p.chart.dim = (p.chart.dim + 1) % aasDimensions
+			p.render()
+			return nil
+		case 'w':
+			p.window = (p.window + 1) % len(localASHWindows)
+			p.render()
+			return nil
+		}
+		return event
+	})
+	p.render()
+	return p
+}
+
+func (p *LocalASHPanel) Name() string               { return "LocalASH" }
+func (p *LocalASHPanel) Primitive() tview.Primitive { return p.chart }
+func (p *LocalASHPanel) Subscriptions() []string    { return []string{"InstanceContext", "PDBContext"} }
+
+func (p *LocalASHPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.render()
+}
+
+func (p *LocalASHPanel) Unmount() {
+	p.cancel()
+}
+
+// Refresh redraws the chart from the recorder; it issues no queries.
+func (p *LocalASHPanel) Refresh() {
+	p.render()
+}
+
+// OnContext redraws the chart for the workflow's new scope.
+func (p *LocalASHPanel) OnContext(ctx uictx.Context) {
+	switch ctx.(type) {
+	case uictx.InstanceContext, uictx.PDBContext:
+		p.render()
+	}
+}
+
+func (p *LocalASHPanel) render() {
+	window := localASHWindows[p.window]
+	title := fmt.Sprintf(" Local ASH · by %s · last %s ", p.chart.dim, formatWindow(window))
+	if p.rec == nil {
+		p.chart.SetTitle(" Local ASH ")
+		p.chart.message = "Local ASH recording is disabled; start otop with -ash-interval to enable it."
+		return
+	}
+	if err := p.rec.Err(); err != nil {
+		title += "· [red]" + tview.Escape(err.Error()) + "[-] "
+	}
+	p.chart.SetTitle(title)
+
+	scope := db.Scope{}
+	if p.ctx != nil {
+		scope = db.ScopeOf(p.ctx)
+	}
+	now := time.Now()
+	samples := p.rec.Since(now.Add(-window))
+	n := 0
+	for _, a := range samples {
+		if scope.Includes(a.InstID, a.ConID) {
+			samples[n] = a
+			n++
+		}
+	}
+	p.chart.set(samples[:n], now.Add(-window), now, p.rec.Interval())
+}
+
+func init() {
+	panel.Global.Register(panel.Entry{
+		TypeName:    "LocalASH",
+		Description: "Average active sessions from otop's own V$SESSION samples",
+		Factory:     newLocalASHPanel,
+		Requires:    panel.Requirement{Views: []string{"GV$SESSION"}},
+	})
+}
@@ -0,0 +1,276 @@
+package panels
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/sampler"
+	"github.com/mdoeren/otop/internal/target"
+	uictx "github.com/mdoeren/otop/internal/ui/context"
+	"github.com/mdoeren/otop/internal/ui/panel"
+	"github.com/rivo/tview"
+)
+
+// lockKey identifies a session in the blocking tree.
+type lockKey struct {
+	inst, sid int
+}
+
+// BlockingTreePanel shows lock contention as a tree: each blocking session
+// with the sessions waiting for it underneath, level by level down the
+// chain, from the target's sampler. A deadlock not yet broken is rooted at one of its sessions and
+// marked where the chain comes back round. Space collapses or expands a
+// branch; Enter emits SessionContext for the selected session.
+type BlockingTreePanel struct {
+	app       *tview.Application
+	sampler   *sampler.Sampler
+	tree      *tview.TreeView
+	emitFn    func(uictx.Context)
+	statusFn  func(error)
+	waits     []models.LockWait
+	at        time.Time
+	collapsed map[lockKey]bool
+	ctx       context.Context
+	cancel    context.CancelFunc
+	unsub     func() // stops the feed of the current scope
+}
+
+func newBlockingTreePanel(app *tview.Application, t *target.Target) panel.Panel {
+	p := &BlockingTreePanel{
+		app:       app,
+		sampler:   t.Sampler,
+		tree:      tview.NewTreeView(),
+		collapsed: map[lockKey]bool{},
+	}
+	p.tree.SetRoot(tview.NewTreeNode("")).SetTopLevel(1)
+	p.tree.SetTitle(" Blocking Sessions ").SetBorder(true)
+	p.tree.SetSelectedFunc(func(node *tview.TreeNode) {
+		w, ok := node.GetReference().(models.LockWait)
+		if !ok || p.emitFn == nil {
+			return
+		}
+		s := models.Session{
+			InstID:         w.InstID,
+			ConID:          w.ConID,
+			SID:            w.SID,
+			Serial:         w.Serial,
+			Username:       w.Username,
+			Status:         w.Status,
+			SQLID:          w.SQLID,
+			SQLChildNumber: -1,
+			Program:        w.Program,
+			WaitEvent:      w.Event,
+			WaitSeconds:    w.WaitSeconds,
+		}
+		p.emitFn(uictx.SessionContext{Session: s})
+		if s.SQLID != "" {
+			p.emitFn(uictx.SQLContext{SQLID: s.SQLID})
+		}
+	})
+	p.tree.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyRune && event.Rune() == ' ' {
+			node := p.tree.GetCurrentNode()
+			if node == nil {
+				return nil
+			}
+			if w, ok := node.GetReference().(models.LockWait); ok && len(node.GetChildren()) > 0 {
+				node.SetExpanded(!node.IsExpanded())
+				p.collapsed[lockKey{w.InstID, w.SID}] = !node.IsExpanded()
+			}
+			return nil
+		}
+		return event
+	})
+	return p
+}
+
+func (p *BlockingTreePanel) Name() string               { return "BlockingTree" }
+func (p *BlockingTreePanel) Primitive() tview.Primitive { return p.tree }
+func (p *BlockingTreePanel) Subscriptions() []string {
+	return []string{"InstanceContext", "PDBContext"}
+}
+func (p *BlockingTreePanel) SetEmitFn(fn func(uictx.Context)) { p.emitFn = fn }
+func (p *BlockingTreePanel) SetStatusFn(fn func(error))       { p.statusFn = fn }
+
+func (p *BlockingTreePanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	if p.waits == nil {
+		p.tree.GetRoot().ClearChildren().AddChild(tview.NewTreeNode("[gray]Loading…[-]").SetSelectable(false))
+	}
+	p.subscribe()
+}
+
+func (p *BlockingTreePanel) Unmount() {
+	p.unsub()
+	p.cancel()
+}
+
+func (p *BlockingTreePanel) Refresh() {}
+
+// OnContext switches to the feed of the workflow's new scope.
+func (p *BlockingTreePanel) OnContext(ctx uictx.Context) {
+	switch ctx.(type) {
+	case uictx.InstanceContext, uictx.PDBContext:
+		if p.ctx != nil {
+			p.unsub()
+			p.subscribe()
+		}
+	}
+}
+
+// subscribe follows the lock waits feed of the workflow's current scope.
+func (p *BlockingTreePanel) subscribe() {
+	p.unsub = followScope(p.app, p.ctx, p.sampler.LockWaits, &p.statusFn, func(snap sampler.Snapshot[[]models.LockWait]) {
+		p.waits, p.at = snap.Data, snap.At
+		if p.waits == nil {
+			p.waits = []models.LockWait{}
+		}
+		p.render()
+	})
+}
+
+// render rebuilds the tree, keeping the selected session and the branches
+// the user collapsed.
+func (p *BlockingTreePanel) render() {
+	var selected lockKey
+	if node := p.tree.GetCurrentNode(); node != nil {
+		if w, ok := node.GetReference().(models.LockWait); ok {
+			selected = lockKey{w.InstID, w.SID}
+		}
+	}
+
+	byKey := make(map[lockKey]bool, len(p.waits))
+	for _, w := range p.waits {
+		byKey[lockKey{w.InstID, w.SID}] = true
+	}
+	waiters := map[lockKey][]models.LockWait{}
+	var roots []models.LockWait
+	blocked := 0
+	for _, w := range p.waits {
+		blocker := lockKey{w.BlockingInstID, w.BlockingSID}
+		if w.BlockingSID != 0 {
+			blocked++
+		}
+		if w.BlockingSID != 0 && byKey[blocker] {
+			waiters[blocker] = append(waiters[blocker], w)
+		} else {
+			roots = append(roots, w)
+		}
+	}
+
+	root := p.tree.GetRoot().ClearChildren()
+	var current *tview.TreeNode
+	placed := make(map[lockKey]bool, len(p.waits))
+	var add func(parent *tview.TreeNode, w models.LockWait, depth int)
+	add = func(parent *tview.TreeNode, w models.LockWait, depth int) {
+		key := lockKey{w.InstID, w.SID}
+		placed[key] = true
+		node := tview.NewTreeNode(lockLabel(w, len(waiters[key]))).
+			SetReference(w).
+			SetExpanded(!p.collapsed[key])
+		if depth == 0 {
+			node.SetColor(tcell.ColorRed)
+		}
+		if key == selected {
+			current = node
+		}
+		parent.AddChild(node)
+		for _, c := range waiters[key] {
+			if ck := (lockKey{c.InstID, c.SID}); placed[ck] {
+				// The chain has come back round: a deadlock.
+				node.AddChild(tview.NewTreeNode(fmt.Sprintf("↺ %d/%d · deadlock cycle", ck.inst, ck.sid)).
+					SetColor(tcell.ColorRed).
+					SetSelectable(false))
+				continue
+			}
+			add(node, c, depth+1)
+		}
+	}
+	for _, w := range roots {
+		add(root, w, 0)
+	}
+	// Sessions in a cycle all have their blocker in the list, so none of
+	// them is a root: a deadlock Oracle has yet to break, or one across RAC
+	// instances, which it detects more slowly. Root each cycle at a session
+	// on it, found by following the blockers of one not yet placed.
+	cycles := 0
+	for _, w := range p.waits {
+		if placed[lockKey{w.InstID, w.SID}] {
+			continue
+		}
+		seen := map[lockKey]bool{}
+		for key := (lockKey{w.InstID, w.SID}); !seen[key]; {
+			seen[key] = true
+			w = *lookup(p.waits, key)
+			key = lockKey{w.BlockingInstID, w.BlockingSID}
+		}
+		add(root, w, 0)
+		cycles++
+	}
+	if len(root.GetChildren()) == 0 {
+		root.AddChild(tview.NewTreeNode("[gray]No blocking sessions[-]").SetSelectable(false))
+	}
+	if current == nil && len(root.GetChildren()) > 0 {
+		current = root.GetChildren()[0]
+	}
+	p.tree.SetCurrentNode(current)
+
+	title := " Blocking Sessions "
+	if blocked > 0 {
+		title += fmt.Sprintf("· %d blocked ", blocked)
+	}
+	switch {
+	case cycles == 1:
+		title += "· [red]deadlock[-] "
+	case cycles > 1:
+		title += fmt.Sprintf("· [red]%d deadlocks[-] ", cycles)
+	}
+	p.tree.SetTitle(title + "· " + p.at.Format("15:04:05") + " ")
+}
+
+// lookup returns the wait of the session key in waits, or nil.
+func lookup(waits []models.LockWait, key lockKey) *models.LockWait {
+	for i := range waits {
+		if waits[i].InstID == key.inst && waits[i].SID == key.sid {
+			return &waits[i]
+		}
+	}
+	return nil
+}
+
+// lockLabel describes a session in the tree: who it is, what it waits on
+// and for how long, and the lock it wants or holds.
+func lockLabel(w models.LockWait, waiters int) string {
+	wait := (time.Duration(w.WaitSeconds) * time.Second).String()
+	label := fmt.Sprintf("%d/%d  %-12s %-8s %s %s", w.InstID, w.SID, w.Username, w.Status, w.Event, wait)
+	if w.ModeRequested != "" {
+		label += fmt.Sprintf(" · wants %s %s", w.LockType, w.ModeRequested)
+		if w.ModeHeld != "" {
+			label += fmt.Sprintf(", holds %s", w.ModeHeld)
+		}
+	} else if w.ModeHeld != "" {
+		label += fmt.Sprintf(" · holds %s %s", w.LockType, w.ModeHeld)
+	}
+	if w.Object != "" {
+		label += " on " + w.Object
+	}
+	if w.SQLID != "" {
+		label += " · " + w.SQLID
+	}
+	if waiters > 0 {
+		label += fmt.Sprintf(" · blocks %d", waiters)
+	}
+	return tview.Escape(label)
+}
+
+func init() {
+	panel.Global.Register(panel.Entry{
+		TypeName:    "BlockingTree",
+		Description: "Blocking lock tree: blockers with the sessions waiting for them",
+		Factory:     newBlockingTreePanel,
+		Requires:    panel.Requirement{Views: []string{"GV$SESSION", "GV$LOCK", "CDB_OBJECTS"}},
+	})
+}
@@ -0,0 +1,235 @@
+package panels
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/sampler"
+	"github.com/mdoeren/otop/internal/target"
+	uictx "github.com/mdoeren/otop/internal/ui/context"
+	"github.com/mdoeren/otop/internal/ui/panel"
+	"github.com/rivo/tview"
+)
+
+// longOpsBarWidth is the width of the progress bars, in cells.
+const longOpsBarWidth = 20
+
+// LongOpsPanel lists the long-running operations still in progress, from
+// the target's sampler feed of V$SESSION_LONGOPS: what each is doing and to which object, how far it
+// has got as a progress bar, how long it has run and how long Oracle
+// expects it still to take. It follows SessionContext and highlights the
+// operations of the selected session. Selecting a row emits SessionContext
+// and SQLContext.
+type LongOpsPanel struct {
+	app      *tview.Application
+	sampler  *sampler.Sampler
+	table    *tview.Table
+	emitFn   func(uictx.Context)
+	statusFn func(error)
+	ops      []models.LongOp
+	at       time.Time
+	session  *models.Session // the workflow's session, whose ops are highlighted
+	ctx      context.Context
+	cancel   context.CancelFunc
+	unsub    func() // stops the feed of the current scope
+}
+
+func newLongOpsPanel(app *tview.Application, t *target.Target) panel.Panel {
+	p := &LongOpsPanel{
+		app:     app,
+		sampler: t.Sampler,
+		table:   tview.NewTable().SetBorders(false).SetSelectable(true, false).SetFixed(1, 0),
+	}
+	p.table.SetTitle(" Long Operations ").SetBorder(true)
+	p.table.SetSelectedFunc(func(row, _ int) {
+		// row 0 is the header
+		idx := row - 1
+		if idx < 0 || idx >= len(p.ops) || p.emitFn == nil {
+			return
+		}
+		op := p.ops[idx]
+		p.emitFn(uictx.SessionContext{Session: models.Session{
+			InstID:         op.InstID,
+			ConID:          op.ConID,
+			SID:            op.SID,
+			Serial:         op.Serial,
+			Username:       op.Username,
+			Status:         "ACTIVE",
+			SQLID:          op.SQLID,
+			SQLChildNumber: -1,
+		}})
+		if op.SQLID != "" {
+			p.emitFn(uictx.SQLContext{SQLID: op.SQLID})
+		}
+	})
+	return p
+}
+
+func (p *LongOpsPanel) Name() string               { return "LongOps" }
+func (p *LongOpsPanel) Primitive() tview.Primitive { return p.table }
+func (p *LongOpsPanel) Subscriptions() []string {
+	return []string{"InstanceContext", "PDBContext", "SessionContext"}
+}
+func (p *LongOpsPanel) SetEmitFn(fn func(uictx.Context)) { p.emitFn = fn }
+func (p *LongOpsPanel) SetStatusFn(fn func(error))       { p.statusFn = fn }
+
+func (p *LongOpsPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	if p.ops == nil {
+		p.table.SetCell(0, 0, tview.NewTableCell("[gray]Loading…[-]").SetSelectable(false))
+	}
+	p.subscribe()
+}
+
+func (p *LongOpsPanel) Unmount() {
+	p.unsub()
+	p.cancel()
+}
+
+func (p *LongOpsPanel) Refresh() {}
+
+// OnContext switches to the feed of the workflow's new scope, or
+// highlights the operations of its new session.
+func (p *LongOpsPanel) OnContext(ctx uictx.Context) {
+	switch c := ctx.(type) {
+	case uictx.InstanceContext, uictx.PDBContext:
+		if p.ctx != nil {
+			p.unsub()
+			p.subscribe()
+		}
+	case uictx.SessionContext:
+		s := c.Session
+		p.session = &s
+		if p.ops != nil {
+			p.render(true)
+		}
+	}
+}
+
+// subscribe follows the long operations feed of the workflow's current
+// scope.
+func (p *LongOpsPanel) subscribe() {
+	p.unsub = followScope(p.app, p.ctx, p.sampler.LongOps, &p.statusFn, func(snap sampler.Snapshot[[]models.LongOp]) {
+		p.ops, p.at = snap.Data, snap.At
+		if p.ops == nil {
+			p.ops = []models.LongOp{}
+		}
+		p.render(false)
+	})
+}
+
+// ofSession reports whether op belongs to the workflow's session.
+func (p *LongOpsPanel) ofSession(op models.LongOp) bool {
+	s := p.session
+	return s != nil && op.InstID == s.InstID && op.SID == s.SID && op.Serial == s.Serial
+}
+
+// render redraws the table. It keeps the selected operation selected,
+// unless toSession is set, when it selects the first operation of the
+// workflow's session.
+func (p *LongOpsPanel) render(toSession bool) {
+	var selected *models.LongOp
+	if row, _ := p.table.GetSelection(); !toSession && row >= 1 && row <= p.table.GetRowCount()-1 {
+		if op, ok := p.table.GetCell(row, 0).GetReference().(models.LongOp); ok {
+			selected = &op
+		}
+	}
+
+	p.table.Clear()
+	headers := []string{"Inst", "SID", "User", "Operation", "Target", "Progress", "Done", "Elapsed", "Remaining", "SQL ID"}
+	for col, h := range headers {
+		p.table.SetCell(0, col, tview.NewTableCell(h).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	mine := 0
+	selectRow := 0
+	for i, op := range p.ops {
+		row := i + 1
+		color := tcell.ColorDefault
+		if p.ofSession(op) {
+			color = tcell.ColorAqua
+			mine++
+			if toSession && selectRow == 0 {
+				selectRow = row
+			}
+		}
+		if selected != nil && op.InstID == selected.InstID && op.SID == selected.SID &&
+			op.OpName == selected.OpName && op.StartTime.Equal(selected.StartTime) {
+			selectRow = row
+		}
+		remaining := ""
+		if op.Remaining >= 0 {
+			remaining = op.Remaining.String()
+		}
+		cells := []string{
+			fmt.Sprint(op.InstID),
+			fmt.Sprint(op.SID),
+			tview.Escape(op.Username),
+			tview.Escape(op.OpName),
+			tview.Escape(op.Target),
+			longOpProgress(op),
+			tview.Escape(fmt.Sprintf("%s/%s %s", formatPlanCount(op.SoFar), formatPlanCount(op.TotalWork), op.Units)),
+			op.Elapsed.String(),
+			remaining,
+			op.SQLID,
+		}
+		for col, text := range cells {
+			cell := tview.NewTableCell(text).SetTextColor(color).SetExpansion(1)
+			if col == 0 {
+				cell.SetReference(op)
+			}
+			p.table.SetCell(row, col, cell)
+		}
+	}
+	if len(p.ops) == 0 {
+		p.table.SetCell(1, 0, tview.NewTableCell("[gray]No long operations in progress[-]").SetSelectable(false))
+	}
+	if selectRow > 0 {
+		p.table.Select(selectRow, 0)
+	}
+
+	title := fmt.Sprintf(" Long Operations · %d ", len(p.ops))
+	if mine > 0 {
+		title += fmt.Sprintf("· %d for session %d/%d ", mine, p.session.InstID, p.session.SID)
+	}
+	p.table.SetTitle(title + "· " + p.at.Format("15:04:05") + " ")
+}
+
+// longOpProgress draws op's progress as a bar to an eighth of a cell,
+// followed by the percentage done.
+func longOpProgress(op models.LongOp) string {
+	done := 0.0
+	if op.TotalWork > 0 {
+		done = min(float64(op.SoFar)/float64(op.TotalWork), 1)
+	}
+	var bar strings.Builder
+	rest := 0
+	fill := done * longOpsBarWidth
+	for i := range longOpsBarWidth {
+		switch part := fill - float64(i); {
+		case part >= 1:
+			bar.WriteRune('█')
+		case part > 0:
+			bar.WriteRune(horizontalBlocks[int(part*8)])
+		default:
+			rest++
+		}
+	}
+	return fmt.Sprintf("[green]%s[gray]%s[-] %3.0f%%", bar.String(), strings.Repeat("·", rest), done*100)
+}
+
+func init() {
+	panel.Global.Register(panel.Entry{
+		TypeName:    "LongOps",
+		Description: "Long-running operations in progress with their progress and time remaining",
+		Factory:     newLongOpsPanel,
+		Requires:    panel.Requirement{Views: []string{"GV$SESSION_LONGOPS", "GV$SESSION"}},
+	})
+}
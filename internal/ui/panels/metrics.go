@@ -0,0 +1,279 @@
+package panels
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/config"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/sampler"
+	"github.com/mdoeren/otop/internal/target"
+	uictx "github.com/mdoeren/otop/internal/ui/context"
+	"github.com/mdoeren/otop/internal/ui/panel"
+	"github.com/rivo/tview"
+)
+
+// metricHistory is how many values of each metric the panel keeps for its
+// sparkline.
+const metricHistory = 240
+
+// MetricsPanel shows the target's chosen V$SYSMETRIC metrics at a glance,
+// one per line: the latest value, a gauge coloured by the metric's
+// thresholds and a sparkline of its history. Oracle recomputes its metrics
+// every 15 or 60 seconds, and the history gains a value each time it does.
+type MetricsPanel struct {
+	app      *tview.Application
+	sampler  *sampler.Sampler
+	view     *metricsView
+	statusFn func(error)
+	ctx      context.Context
+	cancel   context.CancelFunc
+	unsub    func() // stops the feed of the current scope
+}
+
+func newMetricsPanel(app *tview.Application, t *target.Target) panel.Panel {
+	metrics := t.Metrics
+	if len(metrics) == 0 {
+		metrics = config.DefaultMetrics
+	}
+	p := &MetricsPanel{
+		app:     app,
+		sampler: t.Sampler,
+		view:    newMetricsView(metrics),
+	}
+	p.view.SetTitle(" Metrics ").SetBorder(true)
+	return p
+}
+
+func (p *MetricsPanel) Name() string               { return "Metrics" }
+func (p *MetricsPanel) Primitive() tview.Primitive { return p.view }
+func (p *MetricsPanel) Subscriptions() []string    { return []string{"InstanceContext", "PDBContext"} }
+func (p *MetricsPanel) SetStatusFn(fn func(error)) { p.statusFn = fn }
+
+func (p *MetricsPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	p.subscribe()
+}
+
+func (p *MetricsPanel) Unmount() {
+	p.unsub()
+	p.cancel()
+}
+
+func (p *MetricsPanel) Refresh() {}
+
+// OnContext starts the history afresh for the workflow's new scope.
+func (p *MetricsPanel) OnContext(ctx uictx.Context) {
+	switch ctx.(type) {
+	case uictx.InstanceContext, uictx.PDBContext:
+		if p.ctx != nil {
+			p.unsub()
+			p.view.reset()
+			p.subscribe()
+		}
+	}
+}
+
+// subscribe follows the metrics feed of the workflow's current scope.
+func (p *MetricsPanel) subscribe() {
+	p.unsub = followScope(p.app, p.ctx, p.sampler.SystemMetrics, &p.statusFn, func(snap sampler.Snapshot[[]models.SystemMetric]) {
+		p.view.add(snap.Data)
+		p.view.SetTitle(" Metrics · " + snap.At.Format("15:04:05") + " ")
+	})
+}
+
+// metricsView draws a line per metric: label, value, gauge and sparkline.
+type metricsView struct {
+	*tview.Box
+	metrics []config.Metric
+	latest  map[string]models.SystemMetric // by metric name
+	history map[string][]float64           // by metric name, oldest first
+}
+
+func newMetricsView(metrics []config.Metric) *metricsView {
+	v := &metricsView{Box: tview.NewBox(), metrics: metrics}
+	v.reset()
+	return v
+}
+
+// reset forgets every value.
+func (v *metricsView) reset() {
+	v.latest = map[string]models.SystemMetric{}
+	v.history = map[string][]float64{}
+}
+
+// add records the values of a new sample of the shown metrics whose
+// interval has moved on since the last.
+func (v *metricsView) add(metrics []models.SystemMetric) {
+	for _, m := range metrics {
+		if _, shown := v.find(m.Name); !shown || !m.EndTime.After(v.latest[m.Name].EndTime) {
+			continue
+		}
+		v.latest[m.Name] = m
+		h := append(v.history[m.Name], m.Value)
+		if len(h) > metricHistory {
+			h = h[len(h)-metricHistory:]
+		}
+		v.history[m.Name] = h
+	}
+}
+
+func (v *metricsView) find(name string) (config.Metric, bool) {
+	for _, m := range v.metrics {
+		if m.Name == name {
+			return m, true
+		}
+	}
+	return config.Metric{}, false
+}
+
+// Draw draws the metrics within the box's inner rectangle.
+func (v *metricsView) Draw(screen tcell.Screen) {
+	v.Box.DrawForSubclass(screen, v)
+	x, y, width, height := v.GetInnerRect()
+	if width <= 0 || height <= 0 {
+		return
+	}
+	if len(v.latest) == 0 {
+		tview.Print(screen, "waiting for the first values…", x+1, y, width-2, tview.AlignLeft, tcell.ColorGray)
+		return
+	}
+
+	labelWidth := 0
+	for _, m := range v.metrics {
+		labelWidth = max(labelWidth, len([]rune(metricLabel(m))))
+	}
+	labelWidth = min(labelWidth, 24)
+	const valueWidth = 9
+	gaugeWidth := min(max(width/4, 10), 30)
+	sparkX := 1 + labelWidth + 1 + valueWidth + 2 + gaugeWidth + 2
+	sparkWidth := width - sparkX - 1
+
+	gray := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	for row, m := range v.metrics {
+		if row >= height {
+			break
+		}
+		ly := y + row
+		tview.Print(screen, tview.Escape(metricLabel(m)), x+1, ly, labelWidth, tview.AlignLeft, tcell.ColorDefault)
+		latest, ok := v.latest[m.Name]
+		if !ok {
+			tview.Print(screen, "n/a", x+1+labelWidth+1, ly, valueWidth, tview.AlignRight, tcell.ColorGray)
+			continue
+		}
+		history := v.history[m.Name]
+		scale := metricScale(m, latest.Unit, history)
+		color := metricColor(m, latest.Value)
+		tview.Print(screen, formatMetric(latest.Value), x+1+labelWidth+1, ly, valueWidth, tview.AlignRight, color)
+
+		// The gauge, to an eighth of a cell.
+		gx := x + 1 + labelWidth + 1 + valueWidth + 2
+		fill := math.Min(latest.Value/scale, 1) * float64(gaugeWidth)
+		for i := range gaugeWidth {
+			switch part := fill - float64(i); {
+			case part >= 1:
+				screen.SetContent(gx+i, ly, '█', nil, tcell.StyleDefault.Foreground(color))
+			case part > 0:
+				screen.SetContent(gx+i, ly, horizontalBlocks[int(part*8)], nil, tcell.StyleDefault.Foreground(color))
+			default:
+				screen.SetContent(gx+i, ly, '·', nil, gray)
+			}
+		}
+
+		// The sparkline, newest value at the right.
+		if sparkWidth < 4 {
+			continue
+		}
+		if len(history) > sparkWidth {
+			history = history[len(history)-sparkWidth:]
+		}
+		sx := x + sparkX + sparkWidth - len(history)
+		for i, h := range history {
+			level := int(math.Round(math.Min(h/scale, 1) * 8))
+			ch := partialBlocks[max(level, 1)]
+			screen.SetContent(sx+i, ly, ch, nil, tcell.StyleDefault.Foreground(metricColor(m, h)))
+		}
+	}
+}
+
+// horizontalBlocks draws the end of a gauge to an eighth of a cell.
+var horizontalBlocks = []rune{' ', '▏', '▎', '▍', '▌', '▋', '▊', '▉', '█'}
+
+func metricLabel(m config.Metric) string {
+	if m.Label != "" {
+		return m.Label
+	}
+	return m.Name
+}
+
+// metricScale returns the full scale of m's gauge and sparkline: its
+// configured maximum, 100 for a percentage, or else the highest of its
+// history and its thresholds.
+func metricScale(m config.Metric, unit string, history []float64) float64 {
+	if m.Max > 0 {
+		return m.Max
+	}
+	if strings.HasPrefix(unit, "%") {
+		return 100
+	}
+	scale := math.Max(m.Warn, m.Crit)
+	for _, h := range history {
+		scale = math.Max(scale, h)
+	}
+	if scale <= 0 {
+		return 1
+	}
+	return scale
+}
+
+// metricColor colours value by m's thresholds: red at or beyond Crit,
+// yellow at or beyond Warn, and green otherwise.
+func metricColor(m config.Metric, value float64) tcell.Color {
+	if m.Crit > 0 && m.Warn > m.Crit {
+		// Lower is worse.
+		switch {
+		case value <= m.Crit:
+			return tcell.ColorRed
+		case value <= m.Warn:
+			return tcell.ColorYellow
+		}
+		return tcell.ColorGreen
+	}
+	switch {
+	case m.Crit > 0 && value >= m.Crit:
+		return tcell.ColorRed
+	case m.Warn > 0 && value >= m.Warn:
+		return tcell.ColorYellow
+	}
+	return tcell.ColorGreen
+}
+
+// formatMetric shows a metric's value in at most 7 characters, with K, M
+// and G for large values.
+func formatMetric(v float64) string {
+	switch a := math.Abs(v); {
+	case a >= 1e9:
+		return fmt.Sprintf("%.1fG", v/1e9)
+	case a >= 1e6:
+		return fmt.Sprintf("%.1fM", v/1e6)
+	case a >= 1e4:
+		return fmt.Sprintf("%.1fK", v/1e3)
+	case a >= 100:
+		return fmt.Sprintf("%.0f", v)
+	case a >= 10:
+		return fmt.Sprintf("%.1f", v)
+	}
+	return fmt.Sprintf("%.2f", v)
+}
+
+func init() {
+	panel.Global.Register(panel.Entry{
+		TypeName:    "Metrics",
+		Description: "System metrics from V$SYSMETRIC as gauges and sparklines",
+		Factory:     newMetricsPanel,
+		Requires:    panel.Requirement{Views: []string{"GV$SYSMETRIC", "GV$CON_SYSMETRIC"}},
+	})
+}
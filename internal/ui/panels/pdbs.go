@@ -0,0 +1,141 @@
+package panels
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/target"
+	uictx "github.com/mdoeren/otop/internal/ui/context"
+	"github.com/mdoeren/otop/internal/ui/panel"
+	"github.com/rivo/tview"
+)
+
+// PDBListPanel lists the containers of a CDB and lets the user pick one.
+// Selecting a row emits PDBContext, which scopes every query in the workflow.
+type PDBListPanel struct {
+	app        *tview.Application
+	src        db.Source
+	table      *tview.Table
+	emitFn     func(uictx.Context)
+	statusFn   func(error)
+	containers []models.Container
+	conID      int
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+func newPDBListPanel(app *tview.Application, t *target.Target) panel.Panel {
+	p := &PDBListPanel{
+		app:   app,
+		src:   t.Source,
+		table: tview.NewTable().SetBorders(false).SetSelectable(true, false),
+	}
+	p.table.SetTitle(" Pluggable Databases ").SetBorder(true)
+	p.table.SetSelectedFunc(func(row, _ int) {
+		if p.emitFn == nil || row < 1 {
+			return
+		}
+		// row 1 is "all containers"; containers start at row 2
+		var c models.Container
+		if idx := row - 2; idx >= 0 && idx < len(p.containers) {
+			c = p.containers[idx]
+		}
+		p.emitFn(uictx.PDBContext{ConID: c.ConID, Name: c.Name})
+	})
+	return p
+}
+
+func (p *PDBListPanel) Name() string                     { return "PDBList" }
+func (p *PDBListPanel) Primitive() tview.Primitive       { return p.table }
+func (p *PDBListPanel) Subscriptions() []string          { return []string{"PDBContext"} }
+func (p *PDBListPanel) SetEmitFn(fn func(uictx.Context)) { p.emitFn = fn }
+func (p *PDBListPanel) SetStatusFn(fn func(error))       { p.statusFn = fn }
+
+func (p *PDBListPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	if p.containers == nil {
+		p.table.SetCell(0, 0, tview.NewTableCell("[gray]Loading…[-]").SetSelectable(false))
+	}
+	go p.loadContainers(p.ctx)
+}
+
+func (p *PDBListPanel) Unmount() {
+	p.cancel()
+}
+
+func (p *PDBListPanel) Refresh() {
+	go p.loadContainers(p.ctx)
+}
+
+// OnContext marks the container the workflow is currently scoped to.
+func (p *PDBListPanel) OnContext(ctx uictx.Context) {
+	if c, ok := ctx.(uictx.PDBContext); ok {
+		p.conID = c.ConID
+		p.renderTable()
+	}
+}
+
+func (p *PDBListPanel) loadContainers(ctx context.Context) {
+	containers, err := p.src.GetContainers(ctx)
+	if err != nil {
+		if p.statusFn != nil {
+			p.statusFn(err)
+		}
+		return
+	}
+	p.app.QueueUpdateDraw(func() {
+		if ctx.Err() != nil {
+			return
+		}
+		p.containers = containers
+		p.renderTable()
+	})
+}
+
+func (p *PDBListPanel) renderTable() {
+	p.table.Clear()
+
+	headers := []string{"", "Con ID", "Name", "Open Mode"}
+	for col, h := range headers {
+		p.table.SetCell(0, col,
+			tview.NewTableCell(h).
+				SetTextColor(tcell.ColorYellow).
+				SetSelectable(false).
+				SetExpansion(1))
+	}
+
+	marker := func(conID int) string {
+		if conID == p.conID {
+			return "▶"
+		}
+		return ""
+	}
+	p.table.SetCell(1, 0, tview.NewTableCell(marker(0)))
+	p.table.SetCell(1, 1, tview.NewTableCell(""))
+	p.table.SetCell(1, 2, tview.NewTableCell("(all containers)").SetTextColor(tcell.ColorGray))
+	p.table.SetCell(1, 3, tview.NewTableCell(""))
+
+	for i, c := range p.containers {
+		row := i + 2
+		color := tcell.ColorDefault
+		if c.ConID == p.conID {
+			color = tcell.ColorGreen
+		}
+		p.table.SetCell(row, 0, tview.NewTableCell(marker(c.ConID)).SetTextColor(color))
+		p.table.SetCell(row, 1, tview.NewTableCell(fmt.Sprintf("%d", c.ConID)).SetTextColor(color))
+		p.table.SetCell(row, 2, tview.NewTableCell(c.Name).SetTextColor(color).SetExpansion(1))
+		p.table.SetCell(row, 3, tview.NewTableCell(c.OpenMode).SetTextColor(color))
+	}
+}
+
+func init() {
+	panel.Global.Register(panel.Entry{
+		TypeName:    "PDBList",
+		Description: "Pluggable databases; selecting one scopes the workflow",
+		Factory:     newPDBListPanel,
+		Requires:    panel.Requirement{Views: []string{"GV$CONTAINERS"}},
+	})
+}
@@ -1,47 +1,365 @@
 package panels
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/audit"
 	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/target"
 	uictx "github.com/mdoeren/otop/internal/ui/context"
 	"github.com/mdoeren/otop/internal/ui/panel"
 	"github.com/rivo/tview"
 )
 
-// QueryEditorPanel is a stub panel that pre-populates with SQL text from SQLContext.
-// Future: execute queries, show results.
+// queryRowLimit caps the rows fetched for one statement, so that a stray
+// SELECT * cannot flood the terminal.
+const queryRowLimit = 1000
+
+// queryColumnWidth is the widest a result column is drawn until '<' or '>'
+// changes it.
+const queryColumnWidth = 30
+
+// QueryEditorPanel is a SQL editor, pre-populated with the statement from
+// SQLContext or EditorContext, over a grid of results. Ctrl+R runs the buffer; rows stream
+// into the grid as they are fetched, up to queryRowLimit. Esc cancels a
+// running statement, or moves back from the grid to the editor. In the
+// grid, '<' and '>' narrow and widen the selected column. Leading SQL*Plus
+// VARIABLE and EXEC lines declare and assign bind variables for the
+// statement after them.
+//
+// The editor starts read-only: it runs only plain queries, inside a
+// read-only transaction. Ctrl+T switches it to read-write where the target opts in
+// to DML; statements other than queries then go through the audit log.
 type QueryEditorPanel struct {
-	app    *tview.Application
-	db     *db.DB
-	editor *tview.TextArea
+	app       *tview.Application
+	src       db.Source
+	target    string     // target name, for the audit log
+	audit     *audit.Log // guards and records statements that are not queries
+	allowDML  bool       // the target's opt-in to read-write mode
+	readWrite bool
+	editor    *tview.TextArea
+	grid      *tview.Table
+	flex      *tview.Flex
+	statusFn  func(error)
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	run       int                // number of the latest run, to drop stale results
+	runCancel context.CancelFunc // cancels the running statement; nil when idle
+	started   time.Time
+	elapsed   time.Duration
+	rows      int
+	widths    []int  // maximum width of each result column
+	outcome   string // how the last run ended, for the title
 }
 
-func newQueryEditorPanel(app *tview.Application, database *db.DB) panel.Panel {
+func newQueryEditorPanel(app *tview.Application, t *target.Target) panel.Panel {
 	p := &QueryEditorPanel{
-		app:    app,
-		db:     database,
-		editor: tview.NewTextArea(),
+		app:      app,
+		src:      t.Source,
+		target:   t.Name,
+		audit:    t.Audit,
+		allowDML: t.AllowDML,
+		editor:   tview.NewTextArea(),
+		grid:     tview.NewTable().SetBorders(false).SetSelectable(true, true).SetFixed(1, 0),
 	}
-	p.editor.SetTitle(" Query Editor ").SetBorder(true)
+	p.editor.SetBorder(true)
+	p.grid.SetTitle(" Results ").SetBorder(true)
+	p.renderMode()
+	p.flex = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(p.editor, 0, 1, true).
+		AddItem(p.grid, 0, 2, false)
+
+	p.editor.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlR:
+			p.execute()
+			return nil
+		case tcell.KeyCtrlT:
+			p.toggleMode()
+			return nil
+		case tcell.KeyEscape:
+			p.stop()
+			return nil
+		}
+		return event
+	})
+	p.grid.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyCtrlR:
+			p.execute()
+			return nil
+		case tcell.KeyCtrlT:
+			p.toggleMode()
+			return nil
+		case tcell.KeyEscape:
+			if p.runCancel != nil {
+				p.stop()
+			} else {
+				p.app.SetFocus(p.editor)
+			}
+			return nil
+		}
+		switch event.Rune() {
+		case '<':
+			p.resizeColumn(-2)
+			return nil
+		case '>':
+			p.resizeColumn(2)
+			return nil
+		}
+		return event
+	})
 	return p
 }
 
 func (p *QueryEditorPanel) Name() string               { return "QueryEditor" }
-func (p *QueryEditorPanel) Primitive() tview.Primitive { return p.editor }
-func (p *QueryEditorPanel) Subscriptions() []string    { return []string{"SQLContext"} }
-func (p *QueryEditorPanel) Refresh()                   {}
-func (p *QueryEditorPanel) Mount()                     {}
-func (p *QueryEditorPanel) Unmount()                   {}
+func (p *QueryEditorPanel) Primitive() tview.Primitive { return p.flex }
+func (p *QueryEditorPanel) Subscriptions() []string    { return []string{"SQLContext", "EditorContext"} }
+func (p *QueryEditorPanel) SetStatusFn(fn func(error)) { p.statusFn = fn }
 
+func (p *QueryEditorPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+}
+
+// Unmount cancels the panel's context, and with it any running statement.
+func (p *QueryEditorPanel) Unmount() {
+	p.cancel()
+}
+
+// Refresh updates the elapsed time of a running statement.
+func (p *QueryEditorPanel) Refresh() {
+	if p.runCancel != nil {
+		p.renderTitle()
+	}
+}
+
+// OnContext loads the statement into the editor. An EditorContext's text
+// is loaded as is, and focused. For a SQLContext, that is first the text
+// the context carries, which is cut off at 1000 characters, then, unless
+// it has been edited in the meantime, its full text.
 func (p *QueryEditorPanel) OnContext(ctx uictx.Context) {
-	if c, ok := ctx.(uictx.SQLContext); ok {
-		p.editor.SetText(c.SQLText, true)
+	if c, ok := ctx.(uictx.EditorContext); ok {
+		p.editor.SetText(c.Text, false)
+		p.app.SetFocus(p.editor)
+		return
+	}
+	c, ok := ctx.(uictx.SQLContext)
+	if !ok {
+		return
+	}
+	p.editor.SetText(c.SQLText, true)
+	if p.ctx == nil {
+		return
+	}
+	fetchCtx, shown := p.ctx, p.editor.GetText()
+	go func() {
+		text, err := p.src.GetSQLText(fetchCtx, c.SQLID)
+		if err != nil && p.statusFn != nil {
+			p.statusFn(err)
+		}
+		if text == "" {
+			return
+		}
+		p.app.QueueUpdateDraw(func() {
+			if p.editor.GetText() == shown {
+				p.editor.SetText(text, true)
+			}
+		})
+	}()
+}
+
+// execute runs the editor's buffer, cancelling any statement still running,
+// and streams its rows into the grid.
+func (p *QueryEditorPanel) execute() {
+	script := strings.TrimSpace(p.editor.GetText())
+	if script == "" || p.ctx == nil {
+		return
+	}
+	query, binds, err := db.ParseBinds(script)
+	if err != nil {
+		if p.statusFn != nil {
+			p.statusFn(err)
+		}
+		return
+	}
+	runner, ok := p.src.(db.QueryRunner)
+	if !ok {
+		if p.statusFn != nil {
+			p.statusFn(errors.New("this target cannot run queries"))
+		}
+		return
+	}
+	if p.runCancel != nil {
+		p.runCancel()
+	}
+	p.run++
+	run := p.run
+	var ctx context.Context
+	ctx, p.runCancel = context.WithCancel(p.ctx)
+	opts := db.QueryOptions{MaxRows: queryRowLimit, ReadOnly: !p.readWrite, Binds: binds}
+	started := time.Now()
+	p.started, p.rows, p.widths, p.outcome = started, 0, nil, ""
+	p.grid.Clear()
+	p.renderTitle()
+	p.app.SetFocus(p.grid)
+
+	// Every update is queued from the fetching goroutine and dropped once
+	// a newer run has started.
+	update := func(fn func()) {
+		p.app.QueueUpdateDraw(func() {
+			if run == p.run {
+				fn()
+			}
+		})
+	}
+	go func() {
+		var result db.QueryResult
+		exec := func() (err error) {
+			result, err = runner.RunQuery(ctx, query, opts,
+				func(names []string) { update(func() { p.setColumns(names) }) },
+				func(batch [][]string) { update(func() { p.addRows(batch) }) },
+			)
+			return err
+		}
+		var err error
+		if opts.ReadOnly || db.CheckReadOnly(query) == nil {
+			err = exec()
+		} else {
+			// Anything but a plain query may change the database.
+			err = p.audit.Do(p.target, script, "query editor", exec)
+		}
+		elapsed := time.Since(started)
+		cancelled := ctx.Err() != nil
+		update(func() {
+			p.runCancel()
+			p.runCancel, p.elapsed = nil, elapsed
+			switch {
+			case cancelled:
+				p.outcome = "cancelled"
+			case err != nil:
+				p.outcome = "failed"
+				p.grid.SetCell(p.grid.GetRowCount(), 0, tview.NewTableCell(tview.Escape(err.Error())).
+					SetTextColor(tcell.ColorRed).SetSelectable(false))
+				if p.statusFn != nil {
+					p.statusFn(err)
+				}
+			case p.grid.GetRowCount() == 0:
+				p.outcome = fmt.Sprintf("%d rows affected", result.RowsAffected)
+			case result.Truncated:
+				p.outcome = fmt.Sprintf("%d rows, limit reached", result.Rows)
+			default:
+				p.outcome = fmt.Sprintf("%d rows", result.Rows)
+			}
+			p.renderTitle()
+		})
+	}()
+}
+
+// toggleMode switches the editor between read-only and read-write. Only a
+// target that opted in to DML can be written to, and never while otop as a
+// whole is read-only.
+func (p *QueryEditorPanel) toggleMode() {
+	var err error
+	switch {
+	case p.readWrite:
+	case p.audit.ReadOnly():
+		err = audit.ErrReadOnly
+	case !p.allowDML:
+		err = fmt.Errorf("%s does not allow DML: set allow_dml in its profile or pass -allow-dml", p.target)
 	}
+	if err != nil {
+		if p.statusFn != nil {
+			p.statusFn(err)
+		}
+		return
+	}
+	p.readWrite = !p.readWrite
+	p.renderMode()
+}
+
+// renderMode shows the editor's mode in its title.
+func (p *QueryEditorPanel) renderMode() {
+	if p.readWrite {
+		p.editor.SetTitle(" Query Editor · [red]READ WRITE[-] ")
+	} else {
+		p.editor.SetTitle(" Query Editor · read-only ")
+	}
+}
+
+// stop cancels the running statement, if any.
+func (p *QueryEditorPanel) stop() {
+	if p.runCancel != nil {
+		p.runCancel()
+	}
+}
+
+func (p *QueryEditorPanel) setColumns(names []string) {
+	p.widths = make([]int, len(names))
+	for col, name := range names {
+		p.widths[col] = queryColumnWidth
+		p.grid.SetCell(0, col, tview.NewTableCell(tview.Escape(name)).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetMaxWidth(queryColumnWidth))
+	}
+}
+
+func (p *QueryEditorPanel) addRows(batch [][]string) {
+	for _, values := range batch {
+		p.rows++
+		for col, v := range values {
+			p.grid.SetCell(p.rows, col, tview.NewTableCell(tview.Escape(v)).SetMaxWidth(p.widths[col]))
+		}
+	}
+	// Stay at the top while rows stream in, unless the user has moved on.
+	if row, _ := p.grid.GetSelection(); row <= 1 {
+		p.grid.Select(1, 0).ScrollToBeginning()
+	}
+	p.renderTitle()
+}
+
+// resizeColumn changes the width of the selected column by delta.
+func (p *QueryEditorPanel) resizeColumn(delta int) {
+	_, col := p.grid.GetSelection()
+	if col < 0 || col >= len(p.widths) {
+		return
+	}
+	p.widths[col] = max(p.widths[col]+delta, 4)
+	for row := 0; row < p.grid.GetRowCount(); row++ {
+		if cell := p.grid.GetCell(row, col); cell != nil {
+			cell.SetMaxWidth(p.widths[col])
+		}
+	}
+}
+
+func (p *QueryEditorPanel) renderTitle() {
+	title := " Results "
+	switch {
+	case p.runCancel != nil:
+		title += fmt.Sprintf("· running %s · %d rows ", time.Since(p.started).Truncate(time.Second), p.rows)
+	case p.outcome == "cancelled":
+		title += fmt.Sprintf("· cancelled after %s · %d rows ", formatElapsed(p.elapsed), p.rows)
+	case p.outcome != "":
+		title += fmt.Sprintf("· %s · %s ", p.outcome, formatElapsed(p.elapsed))
+	}
+	p.grid.SetTitle(title)
+}
+
+// formatElapsed shows a statement's run time to the millisecond.
+func formatElapsed(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
 }
 
 func init() {
 	panel.Global.Register(panel.Entry{
 		TypeName:    "QueryEditor",
-		Description: "SQL query editor (pre-populated from selected statement)",
+		Description: "SQL editor: run statements and browse the results",
 		Factory:     newQueryEditorPanel,
 	})
 }
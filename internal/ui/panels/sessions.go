@@ -1,32 +1,108 @@
 package panels
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/audit"
 	"github.com/mdoeren/otop/internal/db"
 	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/sampler"
+	"github.com/mdoeren/otop/internal/target"
 	uictx "github.com/mdoeren/otop/internal/ui/context"
 	"github.com/mdoeren/otop/internal/ui/panel"
 	"github.com/rivo/tview"
 )
 
 // SessionListPanel displays active Oracle sessions in a selectable table.
-// Selecting a row emits SessionContext and SQLContext to the workflow bus.
+// Sessions come from the target's shared sampler and are narrowed to the
+// workflow's scope locally. Selecting a row emits SessionContext and
+// SQLContext to the workflow bus.
+// On a RAC cluster, 'i' cycles the workflow's instance scope; on a CDB root,
+// 'p' cycles its pluggable database scope. 's' cycles the sort order through
+// the per-second rate columns. 'K' kills or disconnects the selected session
+// after confirmation, unless otop is read-only.
 type SessionListPanel struct {
-	app      *tview.Application
-	db       *db.DB
-	table    *tview.Table
-	emitFn   func(uictx.Context)
-	statusFn func(error)
-	sessions []models.Session
+	app        *tview.Application
+	src        db.Source
+	target     string     // target name, for the audit log
+	audit      *audit.Log // guards and records kills
+	feed       *sampler.Feed[[]models.Session]
+	table      *tview.Table
+	emitFn     func(uictx.Context)
+	statusFn   func(error)
+	confirmFn  panel.ConfirmFunc
+	snapshot   sampler.Snapshot[[]models.Session] // whole database
+	sessions   []models.Session                   // snapshot narrowed to scope
+	instances  []models.Instance
+	instID     int // current instance scope; 0 = whole cluster
+	containers []models.Container
+	conID      int // current container scope; 0 = every container
+	sortBy     int // index into sessionColumns of the sort column; -1 = query order
+	ctx        context.Context
+	cancel     context.CancelFunc
+	unsub      func()
 }
 
-func newSessionListPanel(app *tview.Application, database *db.DB) panel.Panel {
+// sessionColumn describes one column of the session table. Rate columns
+// also have a numeric rate the table can be sorted by.
+type sessionColumn struct {
+	header    string
+	expansion int
+	value     func(models.Session) string
+	rate      func(models.Session) float64
+}
+
+var sessionColumns = []sessionColumn{
+	{"SID", 0, func(s models.Session) string { return fmt.Sprintf("%d", s.SID) }, nil},
+	{"Username", 0, func(s models.Session) string { return s.Username }, nil},
+	{"Status", 0, func(s models.Session) string { return s.Status }, nil},
+	{"SQL ID", 0, func(s models.Session) string { return s.SQLID }, nil},
+	{"Wait Event", 1, func(s models.Session) string { return s.WaitEvent }, nil},
+	rateColumn("CPU/s", "%.2f", func(s models.Session) float64 { return s.Rates.CPU }),
+	rateColumn("DB/s", "%.2f", func(s models.Session) float64 { return s.Rates.DBTime }),
+	rateColumn("Gets/s", "%.0f", func(s models.Session) float64 { return s.Rates.LogicalReads }),
+	rateColumn("Reads/s", "%.0f", func(s models.Session) float64 { return s.Rates.PhysicalReads }),
+	{"SQL Text", 2, func(s models.Session) string {
+		if len(s.SQLText) > 50 {
+			return s.SQLText[:50] + "…"
+		}
+		return s.SQLText
+	}, nil},
+}
+
+var (
+	instanceColumn = sessionColumn{"Inst", 0, func(s models.Session) string { return fmt.Sprintf("%d", s.InstID) }, nil}
+	pdbColumn      = sessionColumn{"PDB", 0, func(s models.Session) string { return s.PDBName }, nil}
+)
+
+// rateColumn builds a column showing a per-second rate, left blank until
+// the session has been sampled twice.
+func rateColumn(header, format string, rate func(models.Session) float64) sessionColumn {
+	return sessionColumn{
+		header: header,
+		value: func(s models.Session) string {
+			if s.Rates.Interval == 0 {
+				return ""
+			}
+			return fmt.Sprintf(format, rate(s))
+		},
+		rate: rate,
+	}
+}
+
+func newSessionListPanel(app *tview.Application, t *target.Target) panel.Panel {
 	p := &SessionListPanel{
-		app:   app,
-		db:    database,
-		table: tview.NewTable().SetBorders(false).SetSelectable(true, false),
+		app:    app,
+		src:    t.Source,
+		target: t.Name,
+		audit:  t.Audit,
+		feed:   t.Sampler.Sessions,
+		table:  tview.NewTable().SetBorders(false).SetSelectable(true, false),
+		sortBy: -1,
 	}
 	p.table.SetTitle(" Sessions ").SetBorder(true)
 	p.table.SetSelectedFunc(func(row, _ int) {
@@ -44,29 +120,116 @@ func newSessionListPanel(app *tview.Application, database *db.DB) panel.Panel {
 			p.emitFn(uictx.SQLContext{SQLID: s.SQLID, SQLText: s.SQLText})
 		}
 	})
+	p.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'i':
+			p.cycleInstance()
+			return nil
+		case 'p':
+			p.cycleContainer()
+			return nil
+		case 's':
+			p.cycleSort()
+			return nil
+		case 'K':
+			p.confirmKill()
+			return nil
+		}
+		return event
+	})
 	return p
 }
 
 func (p *SessionListPanel) Name() string                      { return "SessionList" }
 func (p *SessionListPanel) Primitive() tview.Primitive        { return p.table }
-func (p *SessionListPanel) Subscriptions() []string           { return nil }
-func (p *SessionListPanel) OnContext(_ uictx.Context)         {}
+func (p *SessionListPanel) Subscriptions() []string           { return []string{"InstanceContext", "PDBContext"} }
 func (p *SessionListPanel) SetEmitFn(fn func(uictx.Context))  { p.emitFn = fn }
 func (p *SessionListPanel) SetStatusFn(fn func(error))        { p.statusFn = fn }
+func (p *SessionListPanel) SetConfirmFn(fn panel.ConfirmFunc) { p.confirmFn = fn }
 
-func (p *SessionListPanel) Mount() {
-	p.table.SetCell(0, 0, tview.NewTableCell("[gray]Loading…[-]").SetSelectable(false))
-	go p.loadSessions()
+func (p *SessionListPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	scope := db.ScopeOf(p.ctx)
+	p.instID, p.conID = scope.InstID, scope.ConID
+	if p.sessions == nil {
+		p.table.SetCell(0, 0, tview.NewTableCell("[gray]Loading…[-]").SetSelectable(false))
+	}
+	ctx = p.ctx
+	p.unsub = p.feed.Subscribe(func(snap sampler.Snapshot[[]models.Session]) {
+		p.onSnapshot(ctx, snap)
+	})
+	if p.instances == nil {
+		go p.loadInstances(p.ctx)
+	}
+	if p.containers == nil {
+		go p.loadContainers(p.ctx)
+	}
 }
 
-func (p *SessionListPanel) Unmount() {}
+func (p *SessionListPanel) Unmount() {
+	p.unsub()
+	p.cancel()
+}
+
+// Refresh is a no-op: the sampler pushes a new snapshot every interval.
+func (p *SessionListPanel) Refresh() {}
 
-func (p *SessionListPanel) Refresh() {
-	go p.loadSessions()
+// onSnapshot is called from the sampler goroutine with each new sample.
+func (p *SessionListPanel) onSnapshot(ctx context.Context, snap sampler.Snapshot[[]models.Session]) {
+	if snap.Err != nil {
+		if p.statusFn != nil {
+			p.statusFn(snap.Err)
+		}
+		return
+	}
+	p.app.QueueUpdateDraw(func() {
+		if ctx.Err() != nil {
+			return
+		}
+		p.snapshot = snap
+		p.applyScope()
+	})
+}
+
+// applyScope narrows the latest snapshot to the workflow's scope and
+// redraws the table.
+func (p *SessionListPanel) applyScope() {
+	if p.snapshot.At.IsZero() {
+		return
+	}
+	scope := db.ScopeOf(p.ctx)
+	p.sessions = p.sessions[:0]
+	for _, s := range p.snapshot.Data {
+		if scope.Includes(s.InstID, s.ConID) {
+			p.sessions = append(p.sessions, s)
+		}
+	}
+	if p.sortBy >= 0 {
+		rate := sessionColumns[p.sortBy].rate
+		sort.SliceStable(p.sessions, func(i, j int) bool {
+			return rate(p.sessions[i]) > rate(p.sessions[j])
+		})
+	}
+	p.renderTitle()
+	p.renderTable()
+}
+
+// OnContext follows the workflow's instance and container scope.
+func (p *SessionListPanel) OnContext(ctx uictx.Context) {
+	switch c := ctx.(type) {
+	case uictx.InstanceContext:
+		p.instID = c.InstID
+	case uictx.PDBContext:
+		p.conID = c.ConID
+	default:
+		return
+	}
+	p.renderTitle()
+	p.applyScope()
 }
 
-func (p *SessionListPanel) loadSessions() {
-	sessions, err := p.db.GetActiveSessions()
+func (p *SessionListPanel) loadInstances(ctx context.Context) {
+	instances, err := p.src.GetInstances(ctx)
 	if err != nil {
 		if p.statusFn != nil {
 			p.statusFn(err)
@@ -74,18 +237,197 @@ func (p *SessionListPanel) loadSessions() {
 		return
 	}
 	p.app.QueueUpdateDraw(func() {
-		p.sessions = sessions
-		p.renderTable()
+		p.instances = instances
+		p.renderTitle()
+		if p.sessions != nil {
+			p.renderTable()
+		}
+	})
+}
+
+func (p *SessionListPanel) loadContainers(ctx context.Context) {
+	containers, err := p.src.GetContainers(ctx)
+	if err != nil {
+		if p.statusFn != nil {
+			p.statusFn(err)
+		}
+		return
+	}
+	p.app.QueueUpdateDraw(func() {
+		p.containers = containers
+		p.renderTitle()
+		if p.sessions != nil {
+			p.renderTable()
+		}
 	})
 }
 
+// cycleSort moves the sort order to the next rate column, descending,
+// wrapping back to the query's order (active sessions first).
+func (p *SessionListPanel) cycleSort() {
+	next := -1
+	for i := p.sortBy + 1; i < len(sessionColumns); i++ {
+		if sessionColumns[i].rate != nil {
+			next = i
+			break
+		}
+	}
+	p.sortBy = next
+	p.applyScope()
+}
+
+// cycleContainer moves the workflow scope to the next container, wrapping
+// through "every container".
+func (p *SessionListPanel) cycleContainer() {
+	if len(p.containers) < 2 || p.emitFn == nil {
+		return
+	}
+	next := p.containers[0]
+	for i, c := range p.containers {
+		if c.ConID == p.conID {
+			next = models.Container{}
+			if i+1 < len(p.containers) {
+				next = p.containers[i+1]
+			}
+			break
+		}
+	}
+	p.emitFn(uictx.PDBContext{ConID: next.ConID, Name: next.Name})
+}
+
+// cycleInstance moves the workflow scope to the next instance, wrapping
+// through "whole cluster".
+func (p *SessionListPanel) cycleInstance() {
+	if len(p.instances) < 2 || p.emitFn == nil {
+		return
+	}
+	next := p.instances[0].InstID
+	for i, inst := range p.instances {
+		if inst.InstID == p.instID {
+			next = 0
+			if i+1 < len(p.instances) {
+				next = p.instances[i+1].InstID
+			}
+			break
+		}
+	}
+	p.emitFn(uictx.InstanceContext{InstID: next})
+}
+
+// killModes are the ways of ending a session the kill dialog offers, in the
+// order of its buttons after Cancel.
+var killModes = []db.KillMode{db.KillImmediate, db.DisconnectPostTransaction, db.DisconnectImmediate}
+
+// confirmKill shows who the selected session is and what it is running, and
+// asks how to end it.
+func (p *SessionListPanel) confirmKill() {
+	row, _ := p.table.GetSelection()
+	idx := row - 1 // row 0 is the header
+	if idx < 0 || idx >= len(p.sessions) || p.confirmFn == nil {
+		return
+	}
+	s := p.sessions[idx]
+	killer, ok := p.src.(db.SessionKiller)
+	var err error
+	switch {
+	case p.audit.ReadOnly():
+		err = audit.ErrReadOnly
+	case !ok:
+		err = errors.New("this target cannot kill sessions")
+	}
+	if err != nil {
+		if p.statusFn != nil {
+			p.statusFn(err)
+		}
+		return
+	}
+
+	sqlText := s.SQLText
+	if r := []rune(sqlText); len(r) > 200 {
+		sqlText = string(r[:200]) + "…"
+	}
+	text := fmt.Sprintf("End session %d,%d on instance %d?\n\nUser: %s\nProgram: %s\nMachine: %s\nSQL: %s %s\n\n"+
+		"Kill rolls back its transaction now. Disconnect after transaction lets the transaction finish first.",
+		s.SID, s.Serial, s.InstID, s.Username, s.Program, s.Machine, s.SQLID, sqlText)
+	// Cancel comes first so that the dialog opens on it: a stray Enter
+	// must not kill anything.
+	buttons := []string{"Cancel", "Kill", "Disconnect after transaction", "Disconnect now"}
+	p.confirmFn(tview.Escape(text), buttons, func(button int) {
+		if button >= 1 && button <= len(killModes) {
+			p.kill(killer, s, killModes[button-1])
+		}
+	})
+}
+
+// kill ends s through the audit log and reports the outcome in a dialog.
+func (p *SessionListPanel) kill(killer db.SessionKiller, s models.Session, mode db.KillMode) {
+	stmt := db.KillStatement(s, mode)
+	subject := fmt.Sprintf("%s · %s · %s · %s", s.Username, s.Program, s.Machine, s.SQLID)
+	ctx := p.ctx
+	go func() {
+		err := p.audit.Do(p.target, stmt, subject, func() error {
+			return killer.KillSession(ctx, s, mode)
+		})
+		p.app.QueueUpdateDraw(func() {
+			text := "Done:\n\n" + stmt
+			if err != nil {
+				text = "Could not end the session:\n\n" + err.Error()
+			}
+			p.confirmFn(tview.Escape(text), []string{"OK"}, nil)
+		})
+	}()
+}
+
+func (p *SessionListPanel) renderTitle() {
+	title := " Sessions "
+	if len(p.containers) > 1 {
+		scope := "all PDBs"
+		for _, c := range p.containers {
+			if c.ConID == p.conID {
+				scope = c.Name
+			}
+		}
+		title += "· " + scope + " "
+	}
+	if len(p.instances) > 1 {
+		scope := "cluster"
+		for _, inst := range p.instances {
+			if inst.InstID == p.instID {
+				scope = fmt.Sprintf("inst %d (%s)", inst.InstID, inst.Name)
+			}
+		}
+		title += "· " + scope + " "
+	}
+	if !p.snapshot.At.IsZero() {
+		title += "· " + p.snapshot.At.Format("15:04:05") + " "
+	}
+	p.table.SetTitle(title)
+}
+
+// columns returns the table columns, adding instance and PDB columns only
+// when there is more than one to tell apart.
+func (p *SessionListPanel) columns() []sessionColumn {
+	var cols []sessionColumn
+	if len(p.instances) > 1 {
+		cols = append(cols, instanceColumn)
+	}
+	if len(p.containers) > 1 {
+		cols = append(cols, pdbColumn)
+	}
+	return append(cols, sessionColumns...)
+}
+
 func (p *SessionListPanel) renderTable() {
 	p.table.Clear()
 
-	headers := []string{"SID", "Username", "Status", "SQL ID", "Wait Event", "SQL Text"}
-	for col, h := range headers {
+	cols := p.columns()
+	for col, c := range cols {
+		header := c.header
+		if p.sortBy >= 0 && c.header == sessionColumns[p.sortBy].header {
+			header += " ▼"
+		}
 		p.table.SetCell(0, col,
-			tview.NewTableCell(h).
+			tview.NewTableCell(header).
 				SetTextColor(tcell.ColorYellow).
 				SetSelectable(false).
 				SetExpansion(1))
@@ -93,20 +435,21 @@ func (p *SessionListPanel) renderTable() {
 
 	for i, s := range p.sessions {
 		row := i + 1
-		sqlText := s.SQLText
-		if len(sqlText) > 50 {
-			sqlText = sqlText[:50] + "…"
-		}
 		color := tcell.ColorDefault
 		if s.Status == "ACTIVE" {
 			color = tcell.ColorGreen
 		}
-		p.table.SetCell(row, 0, tview.NewTableCell(fmt.Sprintf("%d", s.SID)).SetTextColor(color))
-		p.table.SetCell(row, 1, tview.NewTableCell(s.Username).SetTextColor(color))
-		p.table.SetCell(row, 2, tview.NewTableCell(s.Status).SetTextColor(color))
-		p.table.SetCell(row, 3, tview.NewTableCell(s.SQLID).SetTextColor(color))
-		p.table.SetCell(row, 4, tview.NewTableCell(s.WaitEvent).SetTextColor(color).SetExpansion(1))
-		p.table.SetCell(row, 5, tview.NewTableCell(sqlText).SetTextColor(color).SetExpansion(2))
+		for col, c := range cols {
+			align := tview.AlignLeft
+			if c.rate != nil {
+				align = tview.AlignRight
+			}
+			p.table.SetCell(row, col,
+				tview.NewTableCell(c.value(s)).
+					SetTextColor(color).
+					SetAlign(align).
+					SetExpansion(c.expansion))
+		}
 	}
 }
 
@@ -115,5 +458,8 @@ func init() {
 		TypeName:    "SessionList",
 		Description: "Active Oracle sessions with SQL and wait info",
 		Factory:     newSessionListPanel,
+		Requires: panel.Requirement{Views: []string{
+			"GV$SESSION", "GV$SQL", "GV$SESSION_WAIT", "GV$SESSTAT", "GV$STATNAME", "GV$SESS_IO", "GV$CONTAINERS",
+		}},
 	})
 }
@@ -1,113 +1,496 @@
 package panels
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/gdamore/tcell/v2"
 	"github.com/mdoeren/otop/internal/db"
 	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/sampler"
+	"github.com/mdoeren/otop/internal/target"
 	uictx "github.com/mdoeren/otop/internal/ui/context"
 	"github.com/mdoeren/otop/internal/ui/panel"
 	"github.com/rivo/tview"
 )
 
 // SQLDetailPanel shows the execution plan and runtime statistics for a SQL ID.
-// It is driven by SessionContext and SQLContext events from the bus.
+// It is driven by SessionContext and SQLContext events from the bus, and
+// reloads the statement when InstanceContext or PDBContext changes the
+// workflow's scope. The statistics come from the target's sampler, so they
+// keep updating, with per-second rates over the last sampling interval.
+//
+// The statement's full text is fetched from SQL_FULLTEXT and laid out one
+// clause per line. The plan lists each step's cost and estimated rows and
+// bytes, and, if row source statistics were gathered, its actual rows,
+// starts, buffer gets and time, highlighting steps the optimizer
+// misestimated.
+//
+// The statement's child cursors are listed with their plan hash values and
+// why they were not shared. The plan shown is the selected session's child
+// cursor, or the first one; 'c' and 'C' step through the others. The bind
+// variables the child cursor's optimizer peeked at and those last captured
+// are listed with it. 'e' opens the statement in the query editor with its
+// binds filled in as literals, 'E' with them declared as SQL*Plus
+// variables.
 type SQLDetailPanel struct {
 	app      *tview.Application
-	db       *db.DB
+	src      db.Source
+	sampler  *sampler.Sampler
 	text     *tview.TextView
 	statusFn func(error)
+	emitFn   func(uictx.Context)
+	ctx      context.Context
+	cancel   context.CancelFunc
+	// fetchCancel aborts the previous fetch when a newer context arrives,
+	// planCancel the previous plan when another child cursor is picked;
+	// statsUnsub stops watching the previous statement's statistics.
+	fetchCancel context.CancelFunc
+	planCancel  context.CancelFunc
+	statsUnsub  func()
+
+	sqlID    string
+	sqlText  string
+	want     *models.ChildCursor // child cursor to show first; nil for the first one
+	children []models.ChildCursor
+	child    int // index into children of the plan shown
+	plan     []models.PlanRow
+	binds    []models.Bind // of the child cursor shown
+	stats    *models.SQLStats
 }
 
-func newSQLDetailPanel(app *tview.Application, database *db.DB) panel.Panel {
+func newSQLDetailPanel(app *tview.Application, t *target.Target) panel.Panel {
 	p := &SQLDetailPanel{
-		app:  app,
-		db:   database,
-		text: tview.NewTextView().SetDynamicColors(true).SetScrollable(true),
+		app:     app,
+		src:     t.Source,
+		sampler: t.Sampler,
+		text:    tview.NewTextView().SetDynamicColors(true).SetScrollable(true).SetWordWrap(true),
 	}
 	p.text.SetTitle(" SQL Detail ").SetBorder(true)
+	p.text.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'c':
+			p.showChild(p.child + 1)
+			return nil
+		case 'C':
+			p.showChild(p.child - 1)
+			return nil
+		case 'e':
+			p.openInEditor(substituteBinds)
+			return nil
+		case 'E':
+			p.openInEditor(declareBinds)
+			return nil
+		}
+		return event
+	})
 	return p
 }
 
 func (p *SQLDetailPanel) Name() string               { return "SQLDetail" }
 func (p *SQLDetailPanel) Primitive() tview.Primitive { return p.text }
-func (p *SQLDetailPanel) Subscriptions() []string    { return []string{"SessionContext", "SQLContext"} }
-func (p *SQLDetailPanel) Refresh()                   {}
-func (p *SQLDetailPanel) Mount()                     {}
-func (p *SQLDetailPanel) Unmount()                   {}
-func (p *SQLDetailPanel) SetStatusFn(fn func(error)) { p.statusFn = fn }
+func (p *SQLDetailPanel) Subscriptions() []string {
+	return []string{"SessionContext", "SQLContext", "InstanceContext", "PDBContext"}
+}
+func (p *SQLDetailPanel) Refresh()                         {}
+func (p *SQLDetailPanel) SetStatusFn(fn func(error))       { p.statusFn = fn }
+func (p *SQLDetailPanel) SetEmitFn(fn func(uictx.Context)) { p.emitFn = fn }
+
+func (p *SQLDetailPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	if p.sqlID != "" {
+		p.load(p.sqlID, p.sqlText, p.want)
+	}
+}
+
+func (p *SQLDetailPanel) Unmount() {
+	p.stopFetch()
+	p.cancel()
+}
 
 // OnContext is called on the tview main goroutine; it spawns a goroutine for DB I/O.
 func (p *SQLDetailPanel) OnContext(ctx uictx.Context) {
 	switch c := ctx.(type) {
 	case uictx.SQLContext:
-		p.text.SetText("[gray]Loading…[-]")
-		go p.fetchAndRender(c.SQLID, c.SQLText)
+		// A session's statement follows the session itself; keep showing
+		// the child cursor it executes.
+		if c.SQLID != p.sqlID {
+			p.load(c.SQLID, c.SQLText, nil)
+		}
 	case uictx.SessionContext:
-		if c.Session.SQLID != "" {
-			p.text.SetText("[gray]Loading…[-]")
-			go p.fetchAndRender(c.Session.SQLID, c.Session.SQLText)
+		s := c.Session
+		if s.SQLID == "" {
+			return
+		}
+		var want *models.ChildCursor
+		if s.SQLChildNumber >= 0 {
+			want = &models.ChildCursor{InstID: s.InstID, ConID: s.ConID, ChildNumber: s.SQLChildNumber}
+		}
+		p.load(s.SQLID, s.SQLText, want)
+	case uictx.InstanceContext, uictx.PDBContext:
+		// The statistics and child cursors are those of the new scope.
+		if p.ctx != nil && p.sqlID != "" {
+			p.load(p.sqlID, p.sqlText, p.want)
 		}
 	}
 }
 
-func (p *SQLDetailPanel) fetchAndRender(sqlID, sqlText string) {
-	plan, err := p.db.GetExecutionPlan(sqlID)
+// load cancels any fetch still in flight, fetches sqlID's child cursors and
+// the plan of want, or of the first child, and starts watching its
+// statistics in the workflow's current scope.
+func (p *SQLDetailPanel) load(sqlID, sqlText string, want *models.ChildCursor) {
+	p.stopFetch()
+	var ctx context.Context
+	ctx, p.fetchCancel = context.WithCancel(p.ctx)
+	p.sqlID, p.sqlText, p.want = sqlID, sqlText, want
+	p.children, p.child, p.plan, p.binds, p.stats = nil, 0, nil, nil, nil
+	p.text.SetText("[gray]Loading…[-]")
+	go p.fetchText(ctx, sqlID)
+	go p.fetchPlan(ctx, sqlID, want)
+
+	feed := p.sampler.SQLStats(sqlID, db.ScopeOf(ctx))
+	p.statsUnsub = feed.Subscribe(func(snap sampler.Snapshot[*models.SQLStats]) {
+		if snap.Err != nil {
+			if p.statusFn != nil {
+				p.statusFn(snap.Err)
+			}
+			return
+		}
+		p.app.QueueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			p.stats = snap.Data
+			p.render()
+		})
+	})
+}
+
+// stopFetch cancels the current fetch and stops watching its statistics.
+func (p *SQLDetailPanel) stopFetch() {
+	if p.fetchCancel != nil {
+		p.fetchCancel()
+		p.fetchCancel = nil
+	}
+	if p.planCancel != nil {
+		p.planCancel()
+		p.planCancel = nil
+	}
+	if p.statsUnsub != nil {
+		p.statsUnsub()
+		p.statsUnsub = nil
+	}
+}
+
+// fetchText replaces the statement's text, which contexts carry cut off at
+// 1000 characters, with its full text.
+func (p *SQLDetailPanel) fetchText(ctx context.Context, sqlID string) {
+	text, err := p.src.GetSQLText(ctx, sqlID)
 	if err != nil && p.statusFn != nil {
 		p.statusFn(err)
 	}
+	if text == "" {
+		return
+	}
+	p.app.QueueUpdateDraw(func() {
+		if ctx.Err() != nil {
+			return
+		}
+		p.sqlText = text
+		p.render()
+	})
+}
 
-	stats, err := p.db.GetSQLStats(sqlID)
+// fetchPlan fetches sqlID's child cursors and the plan and binds of the one
+// matching want, or of the first if none does.
+func (p *SQLDetailPanel) fetchPlan(ctx context.Context, sqlID string, want *models.ChildCursor) {
+	children, err := p.src.GetChildCursors(ctx, sqlID)
 	if err != nil && p.statusFn != nil {
 		p.statusFn(err)
 	}
+	child := 0
+	for i, c := range children {
+		if want != nil && c.InstID == want.InstID && c.ConID == want.ConID && c.ChildNumber == want.ChildNumber {
+			child = i
+			break
+		}
+	}
+	var pick *models.ChildCursor
+	if len(children) > 0 {
+		pick = &children[child]
+	}
+	plan, binds := p.fetchChild(ctx, sqlID, pick)
 
 	p.app.QueueUpdateDraw(func() {
-		p.render(sqlID, sqlText, plan, stats)
+		if ctx.Err() != nil {
+			return
+		}
+		p.children, p.child, p.plan, p.binds = children, child, plan, binds
+		p.render()
 	})
 }
 
-func (p *SQLDetailPanel) render(sqlID, sqlText string, plan []models.PlanRow, stats *models.SQLStats) {
+// fetchChild fetches the plan and binds of sqlID's child cursor.
+func (p *SQLDetailPanel) fetchChild(ctx context.Context, sqlID string, child *models.ChildCursor) ([]models.PlanRow, []models.Bind) {
+	plan, err := p.src.GetExecutionPlan(ctx, sqlID, child)
+	if err != nil && p.statusFn != nil {
+		p.statusFn(err)
+	}
+	binds, err := p.src.GetBinds(ctx, sqlID, child)
+	if err != nil && p.statusFn != nil {
+		p.statusFn(err)
+	}
+	return plan, binds
+}
+
+// showChild switches the plan and binds to children[i], wrapping around at
+// either end of the list.
+func (p *SQLDetailPanel) showChild(i int) {
+	if len(p.children) < 2 || p.fetchCancel == nil {
+		return
+	}
+	if p.planCancel != nil {
+		p.planCancel()
+	}
+	var ctx context.Context
+	ctx, p.planCancel = context.WithCancel(p.ctx)
+	p.child = (i + len(p.children)) % len(p.children)
+	p.plan, p.binds = nil, nil
+	p.render()
+
+	sqlID, child := p.sqlID, p.children[p.child]
+	go func() {
+		plan, binds := p.fetchChild(ctx, sqlID, &child)
+		p.app.QueueUpdateDraw(func() {
+			if ctx.Err() != nil {
+				return
+			}
+			p.plan, p.binds = plan, binds
+			p.render()
+		})
+	}()
+}
+
+// openInEditor sends the statement, with its binds written in by fill, to
+// the query editor.
+func (p *SQLDetailPanel) openInEditor(fill func(string, []models.Bind) string) {
+	text := p.sqlText
+	if text == "" && p.stats != nil {
+		text = p.stats.SQLText
+	}
+	if p.emitFn == nil || text == "" {
+		return
+	}
+	p.emitFn(uictx.EditorContext{SQLID: p.sqlID, Text: fill(text, p.binds)})
+}
+
+func (p *SQLDetailPanel) render() {
 	var sb strings.Builder
 
-	fmt.Fprintf(&sb, "[yellow]SQL ID:[-] %s\n\n", sqlID)
+	fmt.Fprintf(&sb, "[yellow]SQL ID:[-] %s\n\n", p.sqlID)
+	sqlText := p.sqlText
+	if sqlText == "" && p.stats != nil {
+		// Contexts from ASH carry only the SQL ID.
+		sqlText = p.stats.SQLText
+	}
 	if sqlText != "" {
-		fmt.Fprintf(&sb, "[yellow]SQL Text:[-]\n%s\n\n", sqlText)
+		fmt.Fprintf(&sb, "[yellow]SQL Text:[-]\n%s\n\n", tview.Escape(formatSQL(sqlText)))
 	}
 
-	if stats != nil {
-		fmt.Fprintf(&sb, "[yellow]Statistics:[-]\n")
+	if stats := p.stats; stats != nil {
+		fmt.Fprintf(&sb, "[yellow]Statistics (cumulative, all child cursors):[-]\n")
 		fmt.Fprintf(&sb, "  Executions:    %d\n", stats.Executions)
 		fmt.Fprintf(&sb, "  Elapsed (µs):  %d\n", stats.ElapsedTimeMicros)
 		fmt.Fprintf(&sb, "  CPU (µs):      %d\n", stats.CPUTimeMicros)
 		fmt.Fprintf(&sb, "  Buffer Gets:   %d\n", stats.BufferGets)
 		fmt.Fprintf(&sb, "  Disk Reads:    %d\n", stats.DiskReads)
 		fmt.Fprintf(&sb, "  Rows:          %d\n\n", stats.Rows)
-	}
 
-	if len(plan) > 0 {
-		fmt.Fprintf(&sb, "[yellow]Execution Plan:[-]\n")
-		for _, row := range plan {
-			indent := strings.Repeat("  ", row.Depth)
-			op := row.Operation
-			if row.Options != "" {
-				op += " " + row.Options
+		r := stats.Rates
+		if r.Interval == 0 {
+			fmt.Fprintf(&sb, "[yellow]Last interval:[-]\n  [gray]waiting for a second sample…[-]\n\n")
+		} else {
+			fmt.Fprintf(&sb, "[yellow]Last interval (%s):[-]\n", r.Interval.Round(time.Second))
+			fmt.Fprintf(&sb, "  Executions/s:  %.1f\n", r.Executions)
+			if r.Executions > 0 {
+				fmt.Fprintf(&sb, "  Elapsed/exec:  %.2f ms\n", r.ElapsedTimeMicros/r.Executions/1e3)
 			}
-			if row.ObjectName != "" {
-				op += " [" + row.ObjectName + "]"
-			}
-			fmt.Fprintf(&sb, "  %s%s\n", indent, op)
+			fmt.Fprintf(&sb, "  CPU/s:         %.2f s\n", r.CPUTimeMicros/1e6)
+			fmt.Fprintf(&sb, "  Buffer Gets/s: %.0f\n", r.BufferGets)
+			fmt.Fprintf(&sb, "  Disk Reads/s:  %.0f\n", r.DiskReads)
+			fmt.Fprintf(&sb, "  Rows/s:        %.0f\n\n", r.Rows)
 		}
 	}
 
+	if len(p.children) > 0 {
+		renderChildren(&sb, p.children, p.child)
+	}
+	if len(p.binds) > 0 {
+		renderBinds(&sb, p.binds)
+	}
+	if len(p.plan) > 0 {
+		renderPlan(&sb, p.plan)
+	}
+
 	p.text.SetText(sb.String())
 }
 
+// renderChildren lists a statement's child cursors, marking the one whose
+// plan is shown.
+func renderChildren(sb *strings.Builder, children []models.ChildCursor, shown int) {
+	fmt.Fprintf(sb, "[yellow]Child cursors:[-]")
+	if len(children) > 1 {
+		fmt.Fprintf(sb, " [gray](c / C to show another's plan)[-]")
+	}
+	fmt.Fprintf(sb, "\n[::b]    Inst  Con  Child   Plan hash      Execs  Elapsed/exec  Not shared because[::-]\n")
+	for i, c := range children {
+		mark := " "
+		if i == shown {
+			mark = "▶"
+		}
+		perExec := "-"
+		if c.Executions > 0 {
+			perExec = fmt.Sprintf("%.2f ms", float64(c.ElapsedTimeMicros)/float64(c.Executions)/1e3)
+		}
+		fmt.Fprintf(sb, "  %s %4d  %3d  %5d  %10d  %9d  %12s  %s\n", mark, c.InstID, c.ConID, c.ChildNumber,
+			c.PlanHashValue, c.Executions, perExec, tview.Escape(strings.Join(c.Reasons, ", ")))
+	}
+	sb.WriteString("\n")
+}
+
+// renderBinds lists a child cursor's bind variables with the values its
+// optimizer peeked at and the values last captured.
+func renderBinds(sb *strings.Builder, binds []models.Bind) {
+	fmt.Fprintf(sb, "[yellow]Binds:[-] [gray](e / E to open in the query editor with values as literals / variables)[-]\n")
+	nameWidth, typeWidth, peekWidth, captureWidth := len("Name"), len("Type"), len("Peeked"), len("Captured")
+	for _, b := range binds {
+		nameWidth = max(nameWidth, len(b.Name))
+		typeWidth = max(typeWidth, len(b.Datatype))
+		peekWidth = max(peekWidth, len(formatBindValue(b.Peeked)))
+		captureWidth = max(captureWidth, len(formatBindValue(b.Captured)))
+	}
+	fmt.Fprintf(sb, "[::b]  %3s  %-*s  %-*s  %-*s  %-*s  %s[::-]\n", "Pos", nameWidth, "Name", typeWidth, "Type",
+		peekWidth, "Peeked", captureWidth, "Captured", "Captured at")
+	for _, b := range binds {
+		at := "-"
+		if !b.CapturedAt.IsZero() {
+			at = b.CapturedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(sb, "%s\n", tview.Escape(fmt.Sprintf("  %3d  %-*s  %-*s  %-*s  %-*s  %s", b.Position, nameWidth, b.Name,
+			typeWidth, b.Datatype, peekWidth, formatBindValue(b.Peeked), captureWidth, formatBindValue(b.Captured), at)))
+	}
+	sb.WriteString("\n")
+}
+
+// formatBindValue shows a bind value, "-" if it is not known.
+func formatBindValue(v *models.BindValue) string {
+	switch {
+	case v == nil:
+		return "-"
+	case v.Null:
+		return "NULL"
+	case v.Raw:
+		return "0x" + v.Text + " (undecoded)"
+	}
+	return v.Text
+}
+
+// planMisestimate is the factor by which a step's estimated rows must be
+// off from its actual rows to be highlighted.
+const planMisestimate = 10
+
+// renderPlan writes plan as a table in the manner of DBMS_XPLAN: estimates
+// for every step and, where row source statistics were gathered, the
+// actuals of the last execution next to them.
+func renderPlan(sb *strings.Builder, plan []models.PlanRow) {
+	stats := false
+	ops := make([]string, len(plan))
+	opWidth, nameWidth := len("Operation"), len("Name")
+	for i, row := range plan {
+		ops[i] = strings.Repeat(" ", row.Depth) + row.Operation
+		if row.Options != "" {
+			ops[i] += " " + row.Options
+		}
+		opWidth = max(opWidth, len(ops[i]))
+		nameWidth = max(nameWidth, len(row.ObjectName))
+		stats = stats || row.HasStats
+	}
+
+	if stats {
+		fmt.Fprintf(sb, "[yellow]Execution Plan (actuals from the last execution):[-]\n")
+	} else {
+		fmt.Fprintf(sb, "[yellow]Execution Plan:[-]\n")
+	}
+	header := fmt.Sprintf("  %3s  %-*s  %-*s  %6s  %6s  %7s", "Id", opWidth, "Operation", nameWidth, "Name", "Cost", "E-Rows", "E-Bytes")
+	if stats {
+		header += fmt.Sprintf("  %6s  %6s  %7s  %11s", "Starts", "A-Rows", "Buffers", "A-Time")
+	}
+	fmt.Fprintf(sb, "[::b]%s[::-]\n", header)
+
+	misestimates := false
+	for i, row := range plan {
+		line := fmt.Sprintf("  %3d  %-*s  %-*s  %6s  %6s  %7s", row.ID, opWidth, ops[i], nameWidth, row.ObjectName,
+			formatPlanCount(row.Cost), formatPlanCount(row.Cardinality), formatPlanCount(row.Bytes))
+		if row.HasStats {
+			line += fmt.Sprintf("  %6s  %6s  %7s  %11s", formatPlanCount(row.Starts), formatPlanCount(row.ActualRows),
+				formatPlanCount(row.BufferGets), formatPlanTime(row.ElapsedMicros))
+		}
+		line = tview.Escape(line)
+		if misestimated(row) {
+			misestimates = true
+			line = "[red]" + line + "[-]"
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	switch {
+	case misestimates:
+		fmt.Fprintf(sb, "\n  [red]Red[-] steps returned at least %d× more, or %[1]d× fewer, rows than estimated.\n", planMisestimate)
+	case !stats:
+		fmt.Fprintf(sb, "\n  [gray]No actual rows: run the statement with the gather_plan_statistics hint or STATISTICS_LEVEL=ALL to compare.[-]\n")
+	}
+}
+
+// misestimated reports whether a step's actual rows are planMisestimate or
+// more times off from its estimate. E-Rows is per start, so it is scaled by
+// the number of starts, as DBMS_XPLAN readers do by hand.
+func misestimated(row models.PlanRow) bool {
+	if !row.HasStats || row.Starts == 0 || row.Cardinality == 0 {
+		return false
+	}
+	estimated, actual := row.Cardinality*row.Starts, max(row.ActualRows, 1)
+	return estimated >= actual*planMisestimate || actual >= estimated*planMisestimate
+}
+
+// formatPlanCount abbreviates large counts with K, M and G, as DBMS_XPLAN
+// does, and leaves zero blank.
+func formatPlanCount(n int64) string {
+	switch {
+	case n == 0:
+		return ""
+	case n < 100_000:
+		return fmt.Sprint(n)
+	case n < 100_000_000:
+		return fmt.Sprintf("%dK", n/1_000)
+	case n < 100_000_000_000:
+		return fmt.Sprintf("%dM", n/1_000_000)
+	}
+	return fmt.Sprintf("%dG", n/1_000_000_000)
+}
+
+// formatPlanTime renders microseconds as DBMS_XPLAN's A-Time, HH:MM:SS.FF.
+func formatPlanTime(micros int64) string {
+	d := time.Duration(micros) * time.Microsecond
+	return fmt.Sprintf("%02d:%02d:%02d.%02d", int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60, d.Milliseconds()%1000/10)
+}
+
 func init() {
 	panel.Global.Register(panel.Entry{
 		TypeName:    "SQLDetail",
 		Description: "Execution plan and runtime statistics for a SQL statement",
 		Factory:     newSQLDetailPanel,
+		Requires:    panel.Requirement{Views: []string{"GV$SQL", "GV$SQL_PLAN_STATISTICS_ALL", "GV$SQL_SHARED_CURSOR", "GV$SQL_BIND_CAPTURE"}},
 	})
 }
@@ -0,0 +1,392 @@
+package panels
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/mdoeren/otop/internal/models"
+)
+
+// clauseKeywords start a new line at their query's indentation.
+var clauseKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "HAVING": true,
+	"UNION": true, "INTERSECT": true, "MINUS": true, "EXCEPT": true,
+	"VALUES": true, "SET": true, "RETURNING": true,
+	"FETCH": true, "OFFSET": true,
+}
+
+// pairedClauses start a new line when followed by the word they map to.
+var pairedClauses = map[string]string{
+	"GROUP": "BY", "ORDER": "BY", "CONNECT": "BY", "START": "WITH",
+}
+
+// joinKeywords start a join, which goes on a line of its own, indented.
+var joinKeywords = map[string]bool{
+	"JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true, "FULL": true,
+	"CROSS": true, "NATURAL": true,
+}
+
+// formatSQL lays a statement out one clause per line, with joins and the
+// conditions of WHERE, ON and HAVING indented under them and subqueries
+// indented a further level. Within a line, tokens keep the spacing they
+// had; literals, quoted identifiers, comments and the case of words are
+// not changed.
+func formatSQL(text string) string {
+	tokens := tokenizeSQL(text)
+	var sb strings.Builder
+	// queries has an entry per open parenthesis, true for those that
+	// open a subquery; the statement itself is the outermost query.
+	queries := []bool{true}
+	depth := 0 // subqueries open
+	between := false
+	lineStart := true
+
+	newline := func(extra int) {
+		if !lineStart {
+			sb.WriteString("\n" + strings.Repeat("    ", depth) + strings.Repeat(" ", extra))
+			lineStart = true
+		}
+	}
+	word := func(i int) string {
+		if i < 0 || i >= len(tokens) {
+			return ""
+		}
+		return strings.ToUpper(tokens[i].text)
+	}
+	for i, tok := range tokens {
+		w, prev, next := word(i), word(i-1), word(i+1)
+		switch {
+		case tok.text == "(":
+			opens := next == "SELECT" || next == "WITH"
+			queries = append(queries, opens)
+			if opens {
+				depth++
+			}
+		case tok.text == ")":
+			if len(queries) > 1 {
+				if queries[len(queries)-1] {
+					depth--
+				}
+				queries = queries[:len(queries)-1]
+			}
+		case !queries[len(queries)-1]:
+		case clauseKeywords[w] && !(w == "SET" && prev == "CHARACTER"):
+			newline(0)
+		case pairedClauses[w] != "" && pairedClauses[w] == next:
+			newline(0)
+		case joinKeywords[w] && !joinKeywords[prev] && prev != "OUTER" && next != "(":
+			newline(2)
+		case w == "BETWEEN":
+			between = true
+		case w == "AND" && between:
+			between = false
+		case w == "AND" || w == "OR":
+			newline(4)
+		}
+
+		if tok.spaced && !lineStart {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(tok.text)
+		lineStart = false
+		if strings.HasPrefix(tok.text, "--") {
+			newline(0)
+		}
+	}
+	return sb.String()
+}
+
+// sqlToken is a word, punctuation, quoted literal or comment of a
+// statement.
+type sqlToken struct {
+	text   string
+	spaced bool // whitespace came before it
+}
+
+// tokenizeSQL splits text into tokens, dropping the whitespace between
+// them.
+func tokenizeSQL(text string) []sqlToken {
+	var tokens []sqlToken
+	r := []rune(text)
+	spaced := false
+	for i := 0; i < len(r); {
+		c := r[i]
+		start := i
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			spaced = true
+			continue
+		case c == '\'' || c == '"':
+			// '' inside a literal is an escaped quote.
+			for i++; i < len(r); i++ {
+				if r[i] == c {
+					if c == '\'' && i+1 < len(r) && r[i+1] == '\'' {
+						i++
+						continue
+					}
+					i++
+					break
+				}
+			}
+		case c == '-' && i+1 < len(r) && r[i+1] == '-':
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(r) && r[i+1] == '*':
+			for i += 2; i < len(r) && !(r[i] == '*' && i+1 < len(r) && r[i+1] == '/'); i++ {
+			}
+			i = min(i+2, len(r))
+		case strings.ContainsRune("(),;", c):
+			i++
+		default:
+			for i < len(r) && !unicode.IsSpace(r[i]) && !strings.ContainsRune("(),;'\"", r[i]) &&
+				!(r[i] == '-' && i+1 < len(r) && r[i+1] == '-') {
+				i++
+			}
+		}
+		tokens = append(tokens, sqlToken{text: string(r[start:i]), spaced: spaced})
+		spaced = false
+	}
+	return tokens
+}
+
+// placeholder is a bind variable's place in a statement: text[start:end]
+// is ":name".
+type placeholder struct {
+	start, end int
+	name       string
+}
+
+// placeholders finds the bind variables in text, skipping literals, quoted
+// identifiers and comments.
+func placeholders(text string) []placeholder {
+	var out []placeholder
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case c == '\'' || c == '"':
+			// A doubled quote inside reads as leaving and re-entering.
+			end := strings.IndexByte(text[i+1:], c)
+			if end < 0 {
+				return out
+			}
+			i += end + 2
+		case strings.HasPrefix(text[i:], "--"):
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				return out
+			}
+			i += end
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i+2:], "*/")
+			if end < 0 {
+				return out
+			}
+			i += end + 4
+		case c == ':':
+			end := i + 1
+			for end < len(text) && (text[end] == '_' || text[end] == '$' || text[end] == '#' ||
+				unicode.IsLetter(rune(text[end])) || unicode.IsDigit(rune(text[end]))) {
+				end++
+			}
+			if end > i+1 {
+				out = append(out, placeholder{start: i, end: end, name: text[i+1 : end]})
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+	return out
+}
+
+// bindFor returns the bind variable for the n-th placeholder, ph, of a
+// statement: the one of the same name, else the one at position n.
+func bindFor(binds []models.Bind, ph placeholder, n int) *models.Bind {
+	for i := range binds {
+		if strings.EqualFold(strings.TrimPrefix(binds[i].Name, ":"), ph.name) {
+			return &binds[i]
+		}
+	}
+	for i := range binds {
+		if binds[i].Position == n {
+			return &binds[i]
+		}
+	}
+	return nil
+}
+
+// bindValueOf returns the value to run a statement with: the one the
+// optimizer peeked at, which the plan was built for, else the last one
+// captured. It is nil if neither is known and can be written as a literal
+// of the bind's type.
+func bindValueOf(b *models.Bind) *models.BindValue {
+	if b == nil {
+		return nil
+	}
+	for _, v := range []*models.BindValue{b.Peeked, b.Captured} {
+		if v != nil && bindUsable(b.Datatype, v) {
+			return v
+		}
+	}
+	return nil
+}
+
+// numericLiteral matches the numbers bindLiteral may write unquoted.
+var numericLiteral = regexp.MustCompile(`^-?(\d+(\.\d*)?|\.\d+)([eE][-+]?\d+)?$`)
+
+// bindUsable reports whether v can stand for a bind of datatype: it was
+// decoded, and if the bind is numeric it is a number.
+func bindUsable(datatype string, v *models.BindValue) bool {
+	switch {
+	case v.Null:
+		return true
+	case v.Raw:
+		return false
+	case bindNumeric(datatype):
+		return numericLiteral.MatchString(v.Text)
+	}
+	return true
+}
+
+// bindDateType returns the conversion function for a bind of a date type
+// and the format its values are shown in, or "" for any other type.
+func bindDateType(datatype string) (fn, format string) {
+	switch {
+	case datatype == "DATE":
+		return "TO_DATE", "YYYY-MM-DD HH24:MI:SS"
+	case strings.HasPrefix(datatype, "TIMESTAMP") && strings.HasSuffix(datatype, "TIME ZONE"):
+		// Local time zone values are captured with an offset too.
+		return "TO_TIMESTAMP_TZ", "YYYY-MM-DD HH24:MI:SS.FF TZH:TZM"
+	case strings.HasPrefix(datatype, "TIMESTAMP"):
+		return "TO_TIMESTAMP", "YYYY-MM-DD HH24:MI:SS.FF"
+	}
+	return "", ""
+}
+
+// bindNumeric reports whether binds of datatype are numbers, written
+// without quotes.
+func bindNumeric(datatype string) bool {
+	return datatype == "NUMBER" || strings.HasPrefix(datatype, "FLOAT") || strings.HasPrefix(datatype, "BINARY_")
+}
+
+// quoteSQL makes s a string literal.
+func quoteSQL(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// bindLiteral writes a bind value of datatype as a SQL literal. v must be
+// usable, as bindValueOf returns.
+func bindLiteral(datatype string, v *models.BindValue) string {
+	switch fn, format := bindDateType(datatype); {
+	case v.Null:
+		return "NULL"
+	case fn != "":
+		return fmt.Sprintf("%s(%s, %s)", fn, quoteSQL(v.Text), quoteSQL(format))
+	case bindNumeric(datatype):
+		return v.Text
+	case strings.HasPrefix(datatype, "RAW"):
+		return fmt.Sprintf("HEXTORAW(%s)", quoteSQL(v.Text))
+	}
+	return quoteSQL(v.Text)
+}
+
+// substituteBinds replaces the bind variables in text with their values as
+// literals. A bind whose value is unknown becomes NULL, with a comment
+// saying so.
+func substituteBinds(text string, binds []models.Bind) string {
+	var sb strings.Builder
+	last := 0
+	for n, ph := range placeholders(text) {
+		sb.WriteString(text[last:ph.start])
+		last = ph.end
+		b := bindFor(binds, ph, n+1)
+		v := bindValueOf(b)
+		if v == nil {
+			fmt.Fprintf(&sb, "NULL /* :%s unknown */", ph.name)
+			continue
+		}
+		sb.WriteString(bindLiteral(b.Datatype, v))
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// rawLength matches a RAW datatype, capturing its length in bytes.
+var rawLength = regexp.MustCompile(`^RAW\((\d+)\)$`)
+
+// sqlplusVariable returns the type to declare a bind of datatype as with
+// SQL*Plus VARIABLE, and how to convert the variable where it is used: a
+// format with one %s for its name, or "" to use it as it is. VARIABLE only
+// knows numbers, character strings and LOBs, so dates and timestamps are
+// declared as strings, RAWs as their hex, and anything else unknown as a
+// string that Oracle converts implicitly.
+func sqlplusVariable(datatype string) (decl, use string) {
+	if fn, format := bindDateType(datatype); fn != "" {
+		return "VARCHAR2(40)", fn + "(%s, " + quoteSQL(format) + ")"
+	}
+	if m := rawLength.FindStringSubmatch(datatype); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return fmt.Sprintf("VARCHAR2(%d)", min(2*n, 4000)), "HEXTORAW(%s)"
+	}
+	switch {
+	case datatype == "NUMBER", datatype == "BINARY_FLOAT", datatype == "BINARY_DOUBLE",
+		datatype == "CLOB", datatype == "NCLOB":
+		return datatype, ""
+	case strings.HasPrefix(datatype, "FLOAT"):
+		return "NUMBER", ""
+	}
+	for _, prefix := range []string{"CHAR(", "NCHAR(", "VARCHAR2(", "NVARCHAR2("} {
+		if strings.HasPrefix(datatype, prefix) {
+			return datatype, ""
+		}
+	}
+	return "VARCHAR2(4000)", ""
+}
+
+// declareBinds prefixes text with SQL*Plus VARIABLE and EXEC lines that
+// declare its bind variables and assign their values, renaming them where
+// SQL*Plus would not accept the name. Binds of types VARIABLE does not
+// have are declared as strings and converted where they are used.
+func declareBinds(text string, binds []models.Bind) string {
+	var header, body strings.Builder
+	declared := map[string]bool{}
+	last := 0
+	for n, ph := range placeholders(text) {
+		body.WriteString(text[last:ph.start])
+		last = ph.end
+		b := bindFor(binds, ph, n+1)
+		name := ph.name
+		if unicode.IsDigit(rune(name[0])) {
+			name = "b" + name
+		}
+		datatype := ""
+		if b != nil {
+			datatype = b.Datatype
+		}
+		decl, use := sqlplusVariable(datatype)
+		if use != "" {
+			fmt.Fprintf(&body, use, ":"+name)
+		} else {
+			body.WriteString(":" + name)
+		}
+		if declared[strings.ToUpper(name)] {
+			continue
+		}
+		declared[strings.ToUpper(name)] = true
+		fmt.Fprintf(&header, "VARIABLE %s %s\n", name, decl)
+		switch v := bindValueOf(b); {
+		case v == nil || v.Null:
+			// Declared variables start out NULL.
+		case bindNumeric(decl):
+			fmt.Fprintf(&header, "EXEC :%s := %s;\n", name, v.Text)
+		default:
+			fmt.Fprintf(&header, "EXEC :%s := %s;\n", name, quoteSQL(v.Text))
+		}
+	}
+	body.WriteString(text[last:])
+	return header.String() + body.String()
+}
@@ -0,0 +1,173 @@
+package panels
+
+import (
+	"testing"
+
+	"github.com/mdoeren/otop/internal/models"
+)
+
+func value(text string) *models.BindValue { return &models.BindValue{Text: text} }
+
+func TestSubstituteBinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		binds []models.Bind
+		want  string
+	}{
+		{
+			name: "by name and type",
+			text: "SELECT * FROM t WHERE id = :id AND name = :name AND born > :born AND ts < :ts AND r = :r",
+			binds: []models.Bind{
+				{Position: 1, Name: ":ID", Datatype: "NUMBER", Peeked: value("42")},
+				{Position: 2, Name: ":NAME", Datatype: "VARCHAR2(32)", Captured: value("O'Brien")},
+				{Position: 3, Name: ":BORN", Datatype: "DATE", Peeked: value("2024-03-15 13:45:30")},
+				{Position: 4, Name: ":TS", Datatype: "TIMESTAMP", Peeked: value("2024-03-15 13:45:30.123456789")},
+				{Position: 5, Name: ":R", Datatype: "RAW(16)", Peeked: value("ABCD")},
+			},
+			want: "SELECT * FROM t WHERE id = 42 AND name = 'O''Brien'" +
+				" AND born > TO_DATE('2024-03-15 13:45:30', 'YYYY-MM-DD HH24:MI:SS')" +
+				" AND ts < TO_TIMESTAMP('2024-03-15 13:45:30.123456789', 'YYYY-MM-DD HH24:MI:SS.FF')" +
+				" AND r = HEXTORAW('ABCD')",
+		},
+		{
+			name: "by position",
+			text: "SELECT :1, :2 FROM dual",
+			binds: []models.Bind{
+				{Position: 2, Name: ":2", Datatype: "BINARY_DOUBLE", Peeked: value("1.5e-07")},
+				{Position: 1, Name: ":1", Datatype: "VARCHAR2(1)", Peeked: &models.BindValue{Null: true}},
+			},
+			want: "SELECT NULL, 1.5e-07 FROM dual",
+		},
+		{
+			name: "timestamps with a time zone",
+			text: "SELECT * FROM t WHERE a > :a AND b > :b",
+			binds: []models.Bind{
+				{Position: 1, Name: ":A", Datatype: "TIMESTAMP WITH TIME ZONE", Captured: value("2024-03-15 13:45:30.500000000 +01:00")},
+				{Position: 2, Name: ":B", Datatype: "TIMESTAMP WITH LOCAL TIME ZONE", Captured: value("2024-03-15 12:45:30.500000000 +00:00")},
+			},
+			want: "SELECT * FROM t WHERE" +
+				" a > TO_TIMESTAMP_TZ('2024-03-15 13:45:30.500000000 +01:00', 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM')" +
+				" AND b > TO_TIMESTAMP_TZ('2024-03-15 12:45:30.500000000 +00:00', 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM')",
+		},
+		{
+			name: "peeked wins over captured",
+			text: "SELECT * FROM t WHERE id = :id",
+			binds: []models.Bind{
+				{Position: 1, Name: ":ID", Datatype: "NUMBER", Peeked: value("1"), Captured: value("2")},
+			},
+			want: "SELECT * FROM t WHERE id = 1",
+		},
+		{
+			name: "undecoded peek falls back to the capture",
+			text: "SELECT * FROM t WHERE x = :x",
+			binds: []models.Bind{
+				{Position: 1, Name: ":X", Datatype: "BINARY_DOUBLE", Peeked: &models.BindValue{Text: "fff0000000000000", Raw: true}, Captured: value("3.25")},
+			},
+			want: "SELECT * FROM t WHERE x = 3.25",
+		},
+		{
+			name: "undecoded and uncaptured",
+			text: "SELECT * FROM t WHERE x = :x",
+			binds: []models.Bind{
+				{Position: 1, Name: ":X", Datatype: "NUMBER", Peeked: &models.BindValue{Text: "1234", Raw: true}},
+			},
+			want: "SELECT * FROM t WHERE x = NULL /* :x unknown */",
+		},
+		{
+			name: "a numeric bind that is not a number",
+			text: "SELECT * FROM t WHERE x = :x",
+			binds: []models.Bind{
+				{Position: 1, Name: ":X", Datatype: "NUMBER", Captured: value("c102")},
+			},
+			want: "SELECT * FROM t WHERE x = NULL /* :x unknown */",
+		},
+		{
+			name: "no bind",
+			text: "SELECT * FROM t WHERE x = :x",
+			want: "SELECT * FROM t WHERE x = NULL /* :x unknown */",
+		},
+		{
+			name: "not in literals or comments",
+			text: "SELECT ':x', \":x\" /* :x */ FROM t -- :x\nWHERE x = :x",
+			binds: []models.Bind{
+				{Position: 1, Name: ":X", Datatype: "NUMBER", Peeked: value("7")},
+			},
+			want: "SELECT ':x', \":x\" /* :x */ FROM t -- :x\nWHERE x = 7",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := substituteBinds(tt.text, tt.binds); got != tt.want {
+				t.Errorf("substituteBinds:\n got %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeclareBinds(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		binds []models.Bind
+		want  string
+	}{
+		{
+			name: "types and values",
+			text: "SELECT * FROM t WHERE id = :id AND name = :name AND born > :born AND x = :x",
+			binds: []models.Bind{
+				{Position: 1, Name: ":ID", Datatype: "NUMBER", Peeked: value("42")},
+				{Position: 2, Name: ":NAME", Datatype: "VARCHAR2(32)", Captured: value("O'Brien")},
+				{Position: 3, Name: ":BORN", Datatype: "DATE", Peeked: value("2024-03-15 13:45:30")},
+				{Position: 4, Name: ":X", Datatype: "VARCHAR2(1)", Peeked: &models.BindValue{Null: true}},
+			},
+			want: "VARIABLE id NUMBER\nEXEC :id := 42;\n" +
+				"VARIABLE name VARCHAR2(32)\nEXEC :name := 'O''Brien';\n" +
+				"VARIABLE born VARCHAR2(40)\nEXEC :born := '2024-03-15 13:45:30';\n" +
+				"VARIABLE x VARCHAR2(1)\n" +
+				"SELECT * FROM t WHERE id = :id AND name = :name AND born > TO_DATE(:born, 'YYYY-MM-DD HH24:MI:SS') AND x = :x",
+		},
+		{
+			name: "numbered names and repeats",
+			text: "SELECT :1 FROM dual WHERE :1 > 0",
+			binds: []models.Bind{
+				{Position: 1, Name: ":1", Datatype: "BINARY_FLOAT", Peeked: value("1.5")},
+			},
+			want: "VARIABLE b1 BINARY_FLOAT\nEXEC :b1 := 1.5;\nSELECT :b1 FROM dual WHERE :b1 > 0",
+		},
+		{
+			name: "types VARIABLE does not have",
+			text: "SELECT * FROM t WHERE r = :r AND f = :f AND rowid = :rid AND ts = :ts",
+			binds: []models.Bind{
+				{Position: 1, Name: ":R", Datatype: "RAW(16)", Peeked: value("ABCD")},
+				{Position: 2, Name: ":F", Datatype: "FLOAT(126)", Captured: value("2.5")},
+				{Position: 3, Name: ":RID", Datatype: "ROWID", Captured: value("AAAR3sAAEAAAACXAAA")},
+				{Position: 4, Name: ":TS", Datatype: "TIMESTAMP WITH TIME ZONE", Captured: value("2024-03-15 13:45:30.500000000 +01:00")},
+			},
+			want: "VARIABLE r VARCHAR2(32)\nEXEC :r := 'ABCD';\n" +
+				"VARIABLE f NUMBER\nEXEC :f := 2.5;\n" +
+				"VARIABLE rid VARCHAR2(4000)\nEXEC :rid := 'AAAR3sAAEAAAACXAAA';\n" +
+				"VARIABLE ts VARCHAR2(40)\nEXEC :ts := '2024-03-15 13:45:30.500000000 +01:00';\n" +
+				"SELECT * FROM t WHERE r = HEXTORAW(:r) AND f = :f AND rowid = :rid" +
+				" AND ts = TO_TIMESTAMP_TZ(:ts, 'YYYY-MM-DD HH24:MI:SS.FF TZH:TZM')",
+		},
+		{
+			name: "unknown values stay NULL",
+			text: "SELECT * FROM t WHERE x = :x AND y = :y AND z = :z",
+			binds: []models.Bind{
+				{Position: 1, Name: ":X", Datatype: "NUMBER", Peeked: &models.BindValue{Text: "c100", Raw: true}},
+				{Position: 2, Name: ":Y", Datatype: "BINARY_DOUBLE", Captured: value("abc")},
+				{Position: 3, Name: ":Z", Datatype: "TYPE#113"},
+			},
+			want: "VARIABLE x NUMBER\nVARIABLE y BINARY_DOUBLE\nVARIABLE z VARCHAR2(4000)\n" +
+				"SELECT * FROM t WHERE x = :x AND y = :y AND z = :z",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := declareBinds(tt.text, tt.binds); got != tt.want {
+				t.Errorf("declareBinds:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
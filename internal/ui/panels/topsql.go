@@ -0,0 +1,289 @@
+package panels
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/sampler"
+	"github.com/mdoeren/otop/internal/target"
+	uictx "github.com/mdoeren/otop/internal/ui/context"
+	"github.com/mdoeren/otop/internal/ui/panel"
+	"github.com/rivo/tview"
+)
+
+// TopSQLPanel ranks the statements in the shared pool, per plan, from the
+// target's sampler. It shows either their cumulative statistics since they
+// were loaded or only what they did in the last sampling interval; 'd'
+// switches between the two. 's' and 'S' move the sort to the next or
+// previous column, descending. Selecting a row emits SQLContext.
+type TopSQLPanel struct {
+	app      *tview.Application
+	sampler  *sampler.Sampler
+	table    *tview.Table
+	emitFn   func(uictx.Context)
+	statusFn func(error)
+	snapshot sampler.Snapshot[[]models.SQLStats]
+	rows     []models.SQLStats // snapshot in table order
+	delta    bool              // show the last interval rather than totals
+	sortBy   int               // index into topSQLColumns of the sort column
+	ctx      context.Context
+	cancel   context.CancelFunc
+	unsub    func() // stops the feed of the current scope
+}
+
+// topSQLFigures are the numbers one row shows: a statement's totals, or
+// how much they grew over the last interval.
+type topSQLFigures struct {
+	executions, elapsedMicros, cpuMicros, gets, reads float64
+}
+
+// figuresOf returns the totals of s, or in delta mode its deltas.
+func figuresOf(s models.SQLStats, delta bool) topSQLFigures {
+	if !delta {
+		return topSQLFigures{float64(s.Executions), float64(s.ElapsedTimeMicros), float64(s.CPUTimeMicros),
+			float64(s.BufferGets), float64(s.DiskReads)}
+	}
+	r, secs := s.Rates, s.Rates.Interval.Seconds()
+	return topSQLFigures{r.Executions * secs, r.ElapsedTimeMicros * secs, r.CPUTimeMicros * secs,
+		r.BufferGets * secs, r.DiskReads * secs}
+}
+
+// perExec divides v by the executions, or returns 0 if there were none.
+func (f topSQLFigures) perExec(v float64) float64 {
+	if f.executions < 1 {
+		return 0
+	}
+	return v / f.executions
+}
+
+// topSQLColumn describes one column of the top SQL table. Metric columns
+// can be sorted by; the others show text.
+type topSQLColumn struct {
+	header    string
+	expansion int
+	text      func(models.SQLStats) string
+	metric    func(topSQLFigures) float64
+	format    func(float64) string
+}
+
+var topSQLColumns = []topSQLColumn{
+	{header: "SQL ID", text: func(s models.SQLStats) string { return s.SQLID }},
+	{header: "Plan hash", text: func(s models.SQLStats) string { return fmt.Sprint(s.PlanHashValue) }},
+	metricColumn("Execs", formatTopCount, func(f topSQLFigures) float64 { return f.executions }),
+	metricColumn("Elapsed s", formatSeconds, func(f topSQLFigures) float64 { return f.elapsedMicros }),
+	metricColumn("Ela/exec ms", formatMillis, func(f topSQLFigures) float64 { return f.perExec(f.elapsedMicros) }),
+	metricColumn("CPU s", formatSeconds, func(f topSQLFigures) float64 { return f.cpuMicros }),
+	metricColumn("CPU/exec ms", formatMillis, func(f topSQLFigures) float64 { return f.perExec(f.cpuMicros) }),
+	metricColumn("Gets", formatTopCount, func(f topSQLFigures) float64 { return f.gets }),
+	metricColumn("Gets/exec", formatPerExec, func(f topSQLFigures) float64 { return f.perExec(f.gets) }),
+	metricColumn("Reads", formatTopCount, func(f topSQLFigures) float64 { return f.reads }),
+	metricColumn("Reads/exec", formatPerExec, func(f topSQLFigures) float64 { return f.perExec(f.reads) }),
+	{header: "SQL Text", expansion: 2, text: func(s models.SQLStats) string {
+		if r := []rune(s.SQLText); len(r) > 60 {
+			return string(r[:60]) + "…"
+		}
+		return s.SQLText
+	}},
+}
+
+// topSQLDefaultSort is the column the table is first sorted by, total
+// elapsed time.
+const topSQLDefaultSort = 3
+
+func metricColumn(header string, format func(float64) string, metric func(topSQLFigures) float64) topSQLColumn {
+	return topSQLColumn{header: header, metric: metric, format: format}
+}
+
+func formatTopCount(v float64) string { return formatPlanCount(int64(v + 0.5)) }
+func formatSeconds(micros float64) string {
+	return fmt.Sprintf("%.2f", micros/1e6)
+}
+func formatMillis(micros float64) string {
+	return fmt.Sprintf("%.2f", micros/1e3)
+}
+func formatPerExec(v float64) string {
+	return fmt.Sprintf("%.1f", v)
+}
+
+func newTopSQLPanel(app *tview.Application, t *target.Target) panel.Panel {
+	p := &TopSQLPanel{
+		app:     app,
+		sampler: t.Sampler,
+		table:   tview.NewTable().SetBorders(false).SetSelectable(true, false).SetFixed(1, 0),
+		sortBy:  topSQLDefaultSort,
+	}
+	p.table.SetTitle(" Top SQL ").SetBorder(true)
+	p.table.SetSelectedFunc(func(row, _ int) {
+		// row 0 is the header
+		idx := row - 1
+		if idx < 0 || idx >= len(p.rows) || p.emitFn == nil {
+			return
+		}
+		s := p.rows[idx]
+		p.emitFn(uictx.SQLContext{SQLID: s.SQLID, SQLText: s.SQLText})
+	})
+	p.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 's':
+			p.cycleSort(1)
+			return nil
+		case 'S':
+			p.cycleSort(-1)
+			return nil
+		case 'd':
+			p.delta = !p.delta
+			p.render()
+			return nil
+		}
+		return event
+	})
+	return p
+}
+
+func (p *TopSQLPanel) Name() string                     { return "TopSQL" }
+func (p *TopSQLPanel) Primitive() tview.Primitive       { return p.table }
+func (p *TopSQLPanel) Subscriptions() []string          { return []string{"InstanceContext", "PDBContext"} }
+func (p *TopSQLPanel) SetEmitFn(fn func(uictx.Context)) { p.emitFn = fn }
+func (p *TopSQLPanel) SetStatusFn(fn func(error))       { p.statusFn = fn }
+
+func (p *TopSQLPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	if p.snapshot.At.IsZero() {
+		p.table.SetCell(0, 0, tview.NewTableCell("[gray]Loading…[-]").SetSelectable(false))
+	}
+	p.subscribe()
+}
+
+func (p *TopSQLPanel) Unmount() {
+	p.unsub()
+	p.cancel()
+}
+
+func (p *TopSQLPanel) Refresh() {}
+
+// OnContext switches to the feed of the workflow's new scope.
+func (p *TopSQLPanel) OnContext(ctx uictx.Context) {
+	switch ctx.(type) {
+	case uictx.InstanceContext, uictx.PDBContext:
+		if p.ctx != nil {
+			p.unsub()
+			p.subscribe()
+		}
+	}
+}
+
+// subscribe follows the top SQL feed of the workflow's current scope.
+func (p *TopSQLPanel) subscribe() {
+	p.unsub = followScope(p.app, p.ctx, p.sampler.TopSQL, &p.statusFn, func(snap sampler.Snapshot[[]models.SQLStats]) {
+		p.snapshot = snap
+		p.render()
+	})
+}
+
+// cycleSort moves the sort order by step through the metric columns,
+// wrapping around at either end.
+func (p *TopSQLPanel) cycleSort(step int) {
+	for i := (p.sortBy + step + len(topSQLColumns)) % len(topSQLColumns); ; i = (i + step + len(topSQLColumns)) % len(topSQLColumns) {
+		if topSQLColumns[i].metric != nil {
+			p.sortBy = i
+			break
+		}
+	}
+	p.render()
+}
+
+// render sorts the latest snapshot and redraws the table, keeping the
+// selected statement selected. In delta mode only statements that ran in
+// the last interval are listed.
+func (p *TopSQLPanel) render() {
+	if p.snapshot.At.IsZero() {
+		return
+	}
+	var selected *models.SQLStats
+	if row, _ := p.table.GetSelection(); row >= 1 && row <= len(p.rows) {
+		s := p.rows[row-1]
+		selected = &s
+	}
+
+	p.rows = p.rows[:0]
+	waiting := false
+	for _, s := range p.snapshot.Data {
+		if p.delta {
+			if s.Rates.Interval == 0 {
+				waiting = true
+				continue
+			}
+			if f := figuresOf(s, true); f.executions == 0 && f.elapsedMicros == 0 {
+				continue
+			}
+		}
+		p.rows = append(p.rows, s)
+	}
+	metric := topSQLColumns[p.sortBy].metric
+	sort.SliceStable(p.rows, func(i, j int) bool {
+		return metric(figuresOf(p.rows[i], p.delta)) > metric(figuresOf(p.rows[j], p.delta))
+	})
+
+	title := " Top SQL · cumulative "
+	if p.delta {
+		title = " Top SQL · last interval "
+		if waiting && len(p.rows) == 0 {
+			title += "· waiting for a second sample… "
+		}
+	}
+	p.table.SetTitle(title + "· " + p.snapshot.At.Format("15:04:05") + " ")
+	p.renderTable(selected)
+}
+
+func (p *TopSQLPanel) renderTable(selected *models.SQLStats) {
+	p.table.Clear()
+	for col, c := range topSQLColumns {
+		header := c.header
+		if col == p.sortBy {
+			header += " ▼"
+		}
+		align := tview.AlignLeft
+		if c.metric != nil {
+			align = tview.AlignRight
+		}
+		p.table.SetCell(0, col, tview.NewTableCell(header).
+			SetTextColor(tcell.ColorYellow).
+			SetAlign(align).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+
+	for i, s := range p.rows {
+		f := figuresOf(s, p.delta)
+		for col, c := range topSQLColumns {
+			cell := tview.NewTableCell("").SetExpansion(c.expansion)
+			if c.metric != nil {
+				v := c.metric(f)
+				text := ""
+				if v != 0 {
+					text = c.format(v)
+				}
+				cell.SetText(text).SetAlign(tview.AlignRight)
+			} else {
+				cell.SetText(tview.Escape(strings.Join(strings.Fields(c.text(s)), " ")))
+			}
+			p.table.SetCell(i+1, col, cell)
+		}
+		if selected != nil && s.SQLID == selected.SQLID && s.PlanHashValue == selected.PlanHashValue {
+			p.table.Select(i+1, 0)
+		}
+	}
+}
+
+func init() {
+	panel.Global.Register(panel.Entry{
+		TypeName:    "TopSQL",
+		Description: "Top statements by elapsed time, CPU, gets, reads or executions, in total or over the last interval",
+		Factory:     newTopSQLPanel,
+		Requires:    panel.Requirement{Views: []string{"GV$SQLSTATS"}},
+	})
+}
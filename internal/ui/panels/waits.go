@@ -0,0 +1,395 @@
+package panels
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/models"
+	"github.com/mdoeren/otop/internal/sampler"
+	"github.com/mdoeren/otop/internal/target"
+	uictx "github.com/mdoeren/otop/internal/ui/context"
+	"github.com/mdoeren/otop/internal/ui/panel"
+	"github.com/rivo/tview"
+)
+
+// WaitEventsPanel shows where the instance spent its time waiting over the
+// last sampling interval, from V$SYSTEM_EVENT: a stacked bar and a table
+// of the wait classes. Enter drills down from a class to its events, and
+// from an event to the sessions waiting on it right now; Esc goes back up.
+// Selecting a session emits SessionContext and SQLContext.
+type WaitEventsPanel struct {
+	app      *tview.Application
+	sampler  *sampler.Sampler
+	flex     *tview.Flex
+	bar      *waitBar
+	table    *tview.Table
+	emitFn   func(uictx.Context)
+	statusFn func(error)
+	events   sampler.Snapshot[[]models.SystemEvent]
+	rows     []waitRow        // the classes or events in the table
+	class    string           // class drilled into, or ""
+	event    string           // event drilled into, or ""
+	sessions []models.Session // waiting on event, in table order
+	ctx      context.Context
+	cancel   context.CancelFunc
+	unsub    func() // stops the feed of the current scope
+	unsubSes func() // stops the session feed; nil unless an event is open
+}
+
+// waitRow is a wait class or event with what it waited, per second, over
+// the last interval.
+type waitRow struct {
+	name          string
+	waits, micros float64
+}
+
+func newWaitEventsPanel(app *tview.Application, t *target.Target) panel.Panel {
+	p := &WaitEventsPanel{
+		app:     app,
+		sampler: t.Sampler,
+		bar:     &waitBar{Box: tview.NewBox()},
+		table:   tview.NewTable().SetBorders(false).SetSelectable(true, false).SetFixed(1, 0),
+	}
+	p.flex = tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(p.bar, 3, 0, false).
+		AddItem(p.table, 0, 1, true)
+	p.flex.SetTitle(" Wait events ").SetBorder(true)
+	p.table.SetSelectedFunc(func(row, _ int) {
+		// row 0 is the header
+		p.open(row - 1)
+	})
+	p.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyEscape, tcell.KeyBackspace, tcell.KeyBackspace2:
+			p.back()
+			return nil
+		}
+		return event
+	})
+	return p
+}
+
+func (p *WaitEventsPanel) Name() string                     { return "WaitEvents" }
+func (p *WaitEventsPanel) Primitive() tview.Primitive       { return p.flex }
+func (p *WaitEventsPanel) Subscriptions() []string          { return []string{"InstanceContext", "PDBContext"} }
+func (p *WaitEventsPanel) SetEmitFn(fn func(uictx.Context)) { p.emitFn = fn }
+func (p *WaitEventsPanel) SetStatusFn(fn func(error))       { p.statusFn = fn }
+
+func (p *WaitEventsPanel) Mount(ctx context.Context) {
+	p.ctx, p.cancel = context.WithCancel(ctx)
+	if p.events.At.IsZero() {
+		p.bar.message = "Loading…"
+	}
+	p.subscribe()
+	if p.event != "" {
+		p.subscribeSessions()
+	}
+}
+
+func (p *WaitEventsPanel) Unmount() {
+	p.unsub()
+	if p.unsubSes != nil {
+		p.unsubSes()
+		p.unsubSes = nil
+	}
+	p.cancel()
+}
+
+func (p *WaitEventsPanel) Refresh() {}
+
+// OnContext switches to the feed of the workflow's new scope, staying on
+// the class or event that is open.
+func (p *WaitEventsPanel) OnContext(ctx uictx.Context) {
+	switch ctx.(type) {
+	case uictx.InstanceContext, uictx.PDBContext:
+		if p.ctx != nil {
+			p.unsub()
+			p.subscribe()
+			p.render()
+		}
+	}
+}
+
+// subscribe follows the wait event feed of the workflow's current scope.
+func (p *WaitEventsPanel) subscribe() {
+	p.unsub = followScope(p.app, p.ctx, p.sampler.SystemEvents, &p.statusFn, func(snap sampler.Snapshot[[]models.SystemEvent]) {
+		p.events = snap
+		p.render()
+	})
+}
+
+// subscribeSessions follows the shared session feed while an event is
+// open, to list the sessions waiting on it.
+func (p *WaitEventsPanel) subscribeSessions() {
+	p.unsubSes = follow(p.app, p.ctx, p.sampler.Sessions, &p.statusFn, func(snap sampler.Snapshot[[]models.Session]) {
+		p.setSessions(snap.Data)
+		p.render()
+	})
+}
+
+// setSessions keeps those of sessions that are in scope and waiting on the
+// open event, longest wait first.
+func (p *WaitEventsPanel) setSessions(sessions []models.Session) {
+	scope := db.ScopeOf(p.ctx)
+	p.sessions = p.sessions[:0]
+	for _, s := range sessions {
+		if s.WaitEvent == p.event && scope.Includes(s.InstID, s.ConID) {
+			p.sessions = append(p.sessions, s)
+		}
+	}
+	sort.SliceStable(p.sessions, func(i, j int) bool { return p.sessions[i].WaitSeconds > p.sessions[j].WaitSeconds })
+}
+
+// open drills down into the idx-th row of the table, or, in the list of
+// sessions, sends the idx-th session to the workflow.
+func (p *WaitEventsPanel) open(idx int) {
+	switch {
+	case p.event != "":
+		if idx < 0 || idx >= len(p.sessions) || p.emitFn == nil {
+			return
+		}
+		s := p.sessions[idx]
+		p.emitFn(uictx.SessionContext{Session: s})
+		if s.SQLID != "" {
+			p.emitFn(uictx.SQLContext{SQLID: s.SQLID, SQLText: s.SQLText})
+		}
+		return
+	case idx < 0 || idx >= len(p.rows):
+		return
+	case p.class == "":
+		p.class = p.rows[idx].name
+	default:
+		p.event = p.rows[idx].name
+		p.sessions = nil
+		if p.ctx != nil {
+			p.subscribeSessions()
+		}
+	}
+	p.table.Select(1, 0)
+	p.render()
+}
+
+// back goes up a level, selecting the class or event it came from.
+func (p *WaitEventsPanel) back() {
+	from := ""
+	switch {
+	case p.event != "":
+		from, p.event = p.event, ""
+		if p.unsubSes != nil {
+			p.unsubSes()
+			p.unsubSes = nil
+		}
+	case p.class != "":
+		from, p.class = p.class, ""
+	default:
+		return
+	}
+	p.render()
+	for i, r := range p.rows {
+		if r.name == from {
+			p.table.Select(i+1, 0)
+		}
+	}
+}
+
+// waitRows sums the latest snapshot's events by class, or lists the events
+// of class, leaving out those that did not wait in the last interval.
+// Rows are ordered by time waited, most first.
+func waitRows(events []models.SystemEvent, class string) []waitRow {
+	var rows []waitRow
+	index := map[string]int{}
+	for _, e := range events {
+		if e.Rates.Waits == 0 && e.Rates.TimeWaitedMicros == 0 {
+			continue
+		}
+		name := e.WaitClass
+		if class != "" {
+			if e.WaitClass != class {
+				continue
+			}
+			name = e.Event
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(rows)
+			index[name] = i
+			rows = append(rows, waitRow{name: name})
+		}
+		rows[i].waits += e.Rates.Waits
+		rows[i].micros += e.Rates.TimeWaitedMicros
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].micros > rows[j].micros })
+	return rows
+}
+
+// waitSeries turns rows into the bar's series, folding all but the
+// aasTopN busiest into "Other". Wait classes keep their usual colours.
+func waitSeries(rows []waitRow, classes bool) []series {
+	var out []series
+	for i, r := range rows {
+		if i == aasTopN {
+			out = append(out, series{key: aasOther, color: tcell.ColorGray})
+		}
+		if i >= aasTopN {
+			out[aasTopN].avg += r.micros / 1e6
+			continue
+		}
+		s := series{key: r.name, avg: r.micros / 1e6, color: seriesColors[i%len(seriesColors)]}
+		if classes {
+			s.color = waitClassColors[aasOther]
+			if c, ok := waitClassColors[r.name]; ok {
+				s.color = c
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func (p *WaitEventsPanel) render() {
+	if p.events.At.IsZero() {
+		return
+	}
+	title := " Wait events "
+	switch {
+	case p.event != "":
+		title += "· " + tview.Escape(p.class) + " › " + tview.Escape(p.event) + " "
+	case p.class != "":
+		title += "· " + tview.Escape(p.class) + " "
+	}
+	p.flex.SetTitle(title + "· " + p.events.At.Format("15:04:05") + " ")
+
+	// The bar breaks the open class down by event, or the whole by class.
+	p.bar.message = "waiting for a second sample…"
+	for _, e := range p.events.Data {
+		if e.Rates.Interval > 0 {
+			p.bar.message = ""
+			break
+		}
+	}
+	p.rows = waitRows(p.events.Data, p.class)
+	p.bar.series = waitSeries(p.rows, p.class == "")
+	if p.event != "" {
+		p.renderSessions()
+		return
+	}
+	p.renderRows()
+}
+
+func (p *WaitEventsPanel) renderRows() {
+	name := "Wait class"
+	if p.class != "" {
+		name = "Event"
+	}
+	p.setHeader(name, "Avg sessions", "%", "Waited s", "Waits", "Avg wait ms")
+	total := 0.0
+	for _, r := range p.rows {
+		total += r.micros
+	}
+	interval := 0.0
+	for _, e := range p.events.Data {
+		interval = max(interval, e.Rates.Interval.Seconds())
+	}
+	for i, r := range p.rows {
+		avgWait := ""
+		if r.waits > 0 {
+			avgWait = fmt.Sprintf("%.2f", r.micros/r.waits/1e3)
+		}
+		p.setRow(i+1, r.name,
+			fmt.Sprintf("%.2f", r.micros/1e6),
+			fmt.Sprintf("%.0f%%", 100*r.micros/total),
+			fmt.Sprintf("%.2f", r.micros/1e6*interval),
+			formatTopCount(r.waits*interval),
+			avgWait)
+	}
+	if len(p.rows) == 0 {
+		p.setMessage("no waits in the last interval")
+	}
+}
+
+func (p *WaitEventsPanel) renderSessions() {
+	p.setHeader("Inst", "SID", "Serial", "User", "SQL ID", "In wait s", "Program")
+	for i, s := range p.sessions {
+		p.setRow(i+1, fmt.Sprint(s.InstID), fmt.Sprint(s.SID), fmt.Sprint(s.Serial), s.Username,
+			s.SQLID, fmt.Sprintf("%.0f", s.WaitSeconds), s.Program)
+	}
+	if len(p.sessions) == 0 {
+		p.setMessage("no session is waiting on this event right now")
+	}
+}
+
+// setMessage shows text in place of an empty table.
+func (p *WaitEventsPanel) setMessage(text string) {
+	p.table.Clear()
+	p.table.SetCell(0, 0, tview.NewTableCell("[gray]"+text+"[-]").SetSelectable(false))
+}
+
+// setHeader clears the table and writes its header row.
+func (p *WaitEventsPanel) setHeader(headers ...string) {
+	p.table.Clear()
+	for col, h := range headers {
+		p.table.SetCell(0, col, tview.NewTableCell(h).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false).
+			SetExpansion(1))
+	}
+}
+
+func (p *WaitEventsPanel) setRow(row int, values ...string) {
+	for col, v := range values {
+		p.table.SetCell(row, col, tview.NewTableCell(tview.Escape(v)).SetExpansion(1))
+	}
+}
+
+// waitBar draws how a total splits between series as one stacked bar,
+// with the legend below it.
+type waitBar struct {
+	*tview.Box
+	series  []series
+	message string // shown instead of the bar when set
+}
+
+// Draw draws the bar within the box's inner rectangle.
+func (b *waitBar) Draw(screen tcell.Screen) {
+	b.Box.DrawForSubclass(screen, b)
+	x, y, width, height := b.GetInnerRect()
+	if width <= 2 || height <= 0 {
+		return
+	}
+	if b.message != "" {
+		tview.Print(screen, b.message, x+1, y, width-2, tview.AlignLeft, tcell.ColorGray)
+		return
+	}
+	total := 0.0
+	for _, s := range b.series {
+		total += s.avg
+	}
+	if total > 0 {
+		cum := 0.0
+		for _, s := range b.series {
+			from := int(cum/total*float64(width-2) + 0.5)
+			cum += s.avg
+			to := int(cum/total*float64(width-2) + 0.5)
+			for cx := from; cx < to; cx++ {
+				screen.SetContent(x+1+cx, y, '█', nil, tcell.StyleDefault.Foreground(s.color))
+			}
+		}
+	}
+	for i, line := range seriesLegend(b.series, width, "no waits in the last interval") {
+		if i+1 < height {
+			tview.Print(screen, line, x+1, y+1+i, width-1, tview.AlignLeft, tcell.ColorDefault)
+		}
+	}
+}
+
+func init() {
+	panel.Global.Register(panel.Entry{
+		TypeName:    "WaitEvents",
+		Description: "Time waited per wait class and event over the last interval, down to the sessions waiting",
+		Factory:     newWaitEventsPanel,
+		Requires:    panel.Requirement{Views: []string{"GV$SYSTEM_EVENT", "GV$CON_SYSTEM_EVENT", "GV$SESSION", "GV$SESSION_WAIT"}},
+	})
+}
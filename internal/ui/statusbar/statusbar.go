@@ -8,25 +8,46 @@ import (
 	"github.com/rivo/tview"
 )
 
-// StatusBar is a 1-row status strip that shows transient info and error messages.
-// It is safe to call from any goroutine.
+// indicatorWidth is the fixed width of the right-aligned indicator area.
+const indicatorWidth = 32
+
+// StatusBar is a 1-row status strip that shows transient info and error
+// messages on the left and a permanent indicator (e.g. connection health)
+// on the right. It is safe to call from any goroutine.
 type StatusBar struct {
-	app     *tview.Application
-	view    *tview.TextView
-	mu      sync.Mutex
-	timer   *time.Timer
-	version int
+	app       *tview.Application
+	view      *tview.TextView
+	indicator *tview.TextView
+	root      *tview.Flex
+	mu        sync.Mutex
+	timer     *time.Timer
+	version   int
+	text      string
+	indText   string
 }
 
 // New creates a StatusBar backed by app for thread-safe UI updates.
 func New(app *tview.Application) *StatusBar {
 	v := tview.NewTextView().SetDynamicColors(true)
-	return &StatusBar{app: app, view: v}
+	ind := tview.NewTextView().SetDynamicColors(true).SetTextAlign(tview.AlignRight)
+	root := tview.NewFlex().
+		AddItem(v, 0, 1, false).
+		AddItem(ind, indicatorWidth, 0, false)
+	return &StatusBar{app: app, view: v, indicator: ind, root: root}
 }
 
 // Primitive returns the tview widget to add to a layout.
 func (s *StatusBar) Primitive() tview.Primitive {
-	return s.view
+	return s.root
+}
+
+// SetIndicator replaces the permanent right-hand indicator text.
+// text may contain tview color tags.
+func (s *StatusBar) SetIndicator(text string) {
+	s.mu.Lock()
+	s.indText = text
+	s.mu.Unlock()
+	s.redraw()
 }
 
 // Error shows msg in red and auto-clears after 10 s.
@@ -49,17 +70,29 @@ func (s *StatusBar) show(text string, ttl time.Duration) {
 	s.timer = time.AfterFunc(ttl, func() {
 		s.mu.Lock()
 		stale := ver != s.version
+		if !stale {
+			s.text = ""
+		}
 		s.mu.Unlock()
-		if stale {
-			return
+		if !stale {
+			s.redraw()
 		}
-		s.app.QueueUpdateDraw(func() {
-			s.view.SetText("")
-		})
 	})
+	s.text = text
 	s.mu.Unlock()
+	s.redraw()
+}
 
-	s.app.QueueUpdateDraw(func() {
+// redraw copies the latest message and indicator into the widgets. The
+// update is queued from its own goroutine so callers never block, even
+// before the application's event loop is running; because it applies the
+// latest state rather than a snapshot, out-of-order delivery is harmless.
+func (s *StatusBar) redraw() {
+	go s.app.QueueUpdateDraw(func() {
+		s.mu.Lock()
+		text, ind := s.text, s.indText
+		s.mu.Unlock()
 		s.view.SetText(text)
+		s.indicator.SetText(ind)
 	})
 }
@@ -3,6 +3,8 @@ package workflow
 import (
 	"fmt"
 
+	"github.com/mdoeren/otop/internal/target"
+	"github.com/mdoeren/otop/internal/ui/panel"
 	"github.com/mdoeren/otop/internal/ui/statusbar"
 	"github.com/rivo/tview"
 )
@@ -16,6 +18,9 @@ type Manager struct {
 	pages     *tview.Pages
 	root      *tview.Flex
 	statusBar *statusbar.StatusBar
+	onSwitch  func(*Workflow)
+	confirmFn panel.ConfirmFunc
+	nextKey   int
 }
 
 // NewManager creates a Manager with an empty tab bar and no workflows.
@@ -35,10 +40,28 @@ func NewManager(app *tview.Application) *Manager {
 	return m
 }
 
+// SetOnSwitch registers fn to be called with the newly active workflow
+// whenever the active tab changes.
+func (m *Manager) SetOnSwitch(fn func(*Workflow)) {
+	m.onSwitch = fn
+}
+
+// SetConfirmFn sets the dialog every workflow added afterwards lets its
+// panels confirm actions with.
+func (m *Manager) SetConfirmFn(fn panel.ConfirmFunc) {
+	m.confirmFn = fn
+}
+
 // AddWorkflow registers w and activates it if it is the first workflow.
 func (m *Manager) AddWorkflow(w *Workflow) {
+	// Workflows for different targets may share a name, so page keys
+	// must be unique per manager.
+	m.nextKey++
+	w.pageKey = fmt.Sprintf("%s#%d", w.Name, m.nextKey)
 	w.SetStatusBar(m.statusBar)
+	w.SetConfirmFn(m.confirmFn)
 	w.SetPages(m.pages)
+	w.SetOnScopeChange(m.renderTabBar)
 	m.workflows = append(m.workflows, w)
 	m.renderTabBar()
 	if m.active == -1 {
@@ -62,6 +85,9 @@ func (m *Manager) SwitchTo(index int) {
 	if len(w.focusOrder) > 0 {
 		m.app.SetFocus(w.focusOrder[0])
 	}
+	if m.onSwitch != nil {
+		m.onSwitch(w)
+	}
 }
 
 // SwitchNext moves to the next workflow tab, wrapping around.
@@ -97,19 +123,34 @@ func (m *Manager) RootPrimitive() tview.Primitive {
 	return m.root
 }
 
+// StatusBar returns the status bar shared by all workflows.
+func (m *Manager) StatusBar() *statusbar.StatusBar {
+	return m.statusBar
+}
+
 // Count returns the number of registered workflows.
 func (m *Manager) Count() int {
 	return len(m.workflows)
 }
 
 // renderTabBar refreshes the tab bar text, highlighting the active tab.
+// Once workflows target more than one database, each tab is prefixed with
+// its target's name.
 func (m *Manager) renderTabBar() {
+	targets := map[*target.Target]bool{}
+	for _, w := range m.workflows {
+		targets[w.target] = true
+	}
 	text := " "
 	for i, w := range m.workflows {
+		label := w.Label()
+		if len(targets) > 1 {
+			label = w.target.Name + ": " + label
+		}
 		if i == m.active {
-			text += fmt.Sprintf("[black:white:b] %s [-:-:-]  ", w.Name)
+			text += fmt.Sprintf("[black:white:b] %s [-:-:-]  ", tview.Escape(label))
 		} else {
-			text += fmt.Sprintf("[::d] %s [-:-:-]  ", w.Name)
+			text += fmt.Sprintf("[::d] %s [-:-:-]  ", tview.Escape(label))
 		}
 	}
 	m.tabBar.SetText(text)
@@ -1,9 +1,14 @@
 package workflow
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/target"
 	uictx "github.com/mdoeren/otop/internal/ui/context"
 	"github.com/mdoeren/otop/internal/ui/layout"
 	"github.com/mdoeren/otop/internal/ui/panel"
@@ -12,11 +17,12 @@ import (
 )
 
 // Workflow is a named tab unit that owns a layout tree, a context bus,
-// and a periodic refresh ticker for its panels.
+// and a periodic refresh ticker for its panels. It is bound to one target
+// database, which all of its panels query.
 type Workflow struct {
 	Name            string
 	app             *tview.Application
-	db              *db.DB
+	target          *target.Target
 	bus             *uictx.Bus
 	root            *layout.Node
 	panels          []panel.Panel
@@ -26,34 +32,117 @@ type Workflow struct {
 	refreshInterval time.Duration
 	stopCh          chan struct{}
 	active          bool
+	ctx             context.Context
+	cancel          context.CancelFunc
 	pages           *tview.Pages
 	pageKey         string
 	statusBar       *statusbar.StatusBar
 	statusFn        func(error)
+	confirmFn       panel.ConfirmFunc
+
+	scopeMu   sync.Mutex
+	scope     db.Scope
+	scopeName string // container name for display
+	onScope   func()
 }
 
-// New creates a Workflow with the given name and refresh interval.
-func New(name string, app *tview.Application, database *db.DB, refreshInterval time.Duration) *Workflow {
-	return &Workflow{
+// New creates a Workflow with the given name and refresh interval, bound
+// to the target database t.
+func New(name string, app *tview.Application, t *target.Target, refreshInterval time.Duration) *Workflow {
+	w := &Workflow{
 		Name:            name,
 		app:             app,
-		db:              database,
+		target:          t,
 		bus:             uictx.NewBus(),
 		root:            &layout.Node{Direction: layout.Horizontal},
 		unsubs:          make(map[panel.Panel][]func()),
 		refreshInterval: refreshInterval,
 		pageKey:         name,
 	}
+	// Scope contexts narrow every query issued by the workflow's panels.
+	uictx.Subscribe(w.bus, func(c uictx.InstanceContext) {
+		w.scopeMu.Lock()
+		w.scope.InstID = c.InstID
+		w.scopeMu.Unlock()
+		w.scopeChanged()
+	})
+	uictx.Subscribe(w.bus, func(c uictx.PDBContext) {
+		w.scopeMu.Lock()
+		w.scope.ConID = c.ConID
+		w.scopeName = c.Name
+		w.scopeMu.Unlock()
+		w.scopeChanged()
+	})
+	return w
+}
+
+// SetOnScopeChange registers fn to be called (on the emitting goroutine)
+// whenever the workflow's scope changes.
+func (w *Workflow) SetOnScopeChange(fn func()) {
+	w.onScope = fn
+}
+
+func (w *Workflow) scopeChanged() {
+	if w.onScope != nil {
+		w.onScope()
+	}
+}
+
+// Label returns the tab label: the workflow name followed by its scope,
+// e.g. "Sessions · SALESPDB · inst 2".
+func (w *Workflow) Label() string {
+	w.scopeMu.Lock()
+	defer w.scopeMu.Unlock()
+	label := w.Name
+	if w.scope.ConID != 0 {
+		label += " · " + w.scopeName
+	}
+	if w.scope.InstID != 0 {
+		label += fmt.Sprintf(" · inst %d", w.scope.InstID)
+	}
+	return label
+}
+
+// Target returns the database the workflow is bound to.
+func (w *Workflow) Target() *target.Target {
+	return w.target
 }
 
-// SetStatusBar wires the status bar so panels can surface DB errors.
+// Scope returns the part of the database the workflow's queries cover.
+// Safe to call from any goroutine.
+func (w *Workflow) Scope() db.Scope {
+	w.scopeMu.Lock()
+	defer w.scopeMu.Unlock()
+	return w.scope
+}
+
+// SetStatusBar wires the status bar so panels, including those already
+// added, can surface DB errors.
 func (w *Workflow) SetStatusBar(sb *statusbar.StatusBar) {
 	w.statusBar = sb
 	w.statusFn = func(err error) {
-		if err != nil {
+		// Cancellation is how Unmount and Stop abort fetches, and a lost
+		// connection is already shown by the connection indicator.
+		if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, db.ErrReconnecting) {
 			sb.Error(err.Error())
 		}
 	}
+	for _, p := range w.panels {
+		if r, ok := p.(panel.Reporter); ok {
+			r.SetStatusFn(w.statusFn)
+		}
+	}
+}
+
+// SetConfirmFn wires the dialog panels, including those already added, use
+// to confirm actions.
+func (w *Workflow) SetConfirmFn(fn panel.ConfirmFunc) {
+	w.confirmFn = fn
+	for _, p := range w.panels {
+		if c, ok := p.(panel.Confirmer); ok && fn != nil {
+			c.SetConfirmFn(fn)
+		}
+	}
 }
 
 // AddPanel adds p to the workflow layout.
@@ -92,12 +181,21 @@ func (w *Workflow) AddPanel(p panel.Panel, splitTarget tview.Primitive, dir layo
 	if r, ok := p.(panel.Reporter); ok && w.statusFn != nil {
 		r.SetStatusFn(w.statusFn)
 	}
+	if c, ok := p.(panel.Confirmer); ok && w.confirmFn != nil {
+		c.SetConfirmFn(w.confirmFn)
+	}
 
 	w.panels = append(w.panels, p)
-	p.Mount()
+	if w.active {
+		p.Mount(w.ctx)
+	}
 	w.rebuild()
 
-	// Focus the newly added panel
+	// Focus the newly added panel; an inactive workflow focuses its first
+	// panel when it is switched to.
+	if !w.active {
+		return
+	}
 	w.app.SetFocus(p.Primitive())
 	for i, prim := range w.focusOrder {
 		if prim == p.Primitive() {
@@ -127,7 +225,9 @@ func (w *Workflow) RemovePanel(p panel.Panel) {
 	w.root.RemoveChild(p.Primitive())
 	layout.Collapse(w.root)
 
-	p.Unmount()
+	if w.active {
+		p.Unmount()
+	}
 	w.rebuild()
 
 	// Shift focus if needed
@@ -191,12 +291,17 @@ func (w *Workflow) SetPages(pages *tview.Pages) {
 	w.rebuild()
 }
 
-// Start activates the periodic refresh ticker. Called when the tab becomes active.
+// Start mounts the workflow's panels under a fresh context and activates the
+// periodic refresh ticker. Called when the tab becomes active.
 func (w *Workflow) Start() {
 	if w.stopCh != nil {
 		return
 	}
 	w.active = true
+	w.ctx, w.cancel = context.WithCancel(db.WithScope(context.Background(), w.Scope))
+	for _, p := range w.panels {
+		p.Mount(w.ctx)
+	}
 	w.stopCh = make(chan struct{})
 	ticker := time.NewTicker(w.refreshInterval)
 	go func() {
@@ -219,13 +324,19 @@ func (w *Workflow) Start() {
 	}()
 }
 
-// Stop deactivates the refresh ticker. Called when switching away from the tab.
+// Stop deactivates the refresh ticker, unmounts the panels and cancels any
+// in-flight fetches. Called when switching away from the tab.
 func (w *Workflow) Stop() {
+	if w.stopCh == nil {
+		return
+	}
 	w.active = false
-	if w.stopCh != nil {
-		close(w.stopCh)
-		w.stopCh = nil
+	close(w.stopCh)
+	w.stopCh = nil
+	for _, p := range w.panels {
+		p.Unmount()
 	}
+	w.cancel()
 }
 
 // rebuild reconstructs the tview.Flex tree from the layout node tree
@@ -1,34 +1,260 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/mdoeren/otop/internal/ash"
+	"github.com/mdoeren/otop/internal/audit"
+	"github.com/mdoeren/otop/internal/config"
 	"github.com/mdoeren/otop/internal/db"
+	"github.com/mdoeren/otop/internal/db/demo"
+	"github.com/mdoeren/otop/internal/target"
 	"github.com/mdoeren/otop/internal/ui"
+	"golang.org/x/term"
 )
 
+// errUsage is returned by run when no database to monitor was named.
+var errUsage = errors.New("-conn, -profile or -demo is required")
+
+// errNoAuditLog is returned by run when the audit log has no default
+// location and was not named, so that actions never go unrecorded unless
+// the user asked for that.
+var errNoAuditLog = errors.New(`no home directory for the audit log; name a file with -audit-log, or keep no record with -audit-log ""`)
+
 func main() {
-	connStr := flag.String("conn", "", "Oracle connection string (user/password@host:port/service)")
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		if errors.Is(err, errUsage) {
+			flag.Usage()
+		}
+		os.Exit(1)
+	}
+}
+
+// run opens every target and runs the TUI until it quits. It returns
+// rather than exiting on an error, so that the targets already opened and
+// the audit log are closed on the way out.
+func run() error {
+	var conns stringList
+	flag.Var(&conns, "conn", "Oracle connection string (user[/password]@host:port/service); the password is prompted for if omitted. Repeat to monitor several databases")
+	profiles := flag.String("profile", "", "comma-separated connection profiles from the config file (default $"+config.EnvProfile+" or the file's default)")
+	configPath := flag.String("config", config.DefaultPath(), "path of the config file with connection profiles")
+	demoMode := flag.Bool("demo", false, "add a simulated instance to monitor; no database needed")
+	cluster := flag.Bool("cluster", false, "monitor every RAC instance (GV$ views) instead of only the local one")
+	queryTimeout := flag.Duration("timeout", db.DefaultQueryTimeout, "timeout for each monitoring query")
+	ashInterval := flag.Duration("ash-interval", ash.DefaultInterval, "how often to sample active sessions for the local ASH panel; 0 disables")
+	ashRetention := flag.Duration("ash-retention", ash.DefaultRetention, "how much local ASH history to keep in memory")
+	ashDir := flag.String("ash-dir", "", "directory to also append local ASH samples to, one CSV file per target")
+	readOnly := flag.Bool("read-only", false, "disable every action that changes a database, such as killing sessions")
+	auditPath := flag.String("audit-log", audit.DefaultPath(), "file recording every action taken against a database; empty keeps no record")
+	allowDML := flag.Bool("allow-dml", false, "let the query editor be switched to read-write to run DML and DDL")
 	flag.Parse()
 
-	if *connStr == "" {
-		fmt.Fprintln(os.Stderr, "error: -conn is required")
-		flag.Usage()
-		os.Exit(1)
+	if *auditPath == "" && !*readOnly {
+		named := false
+		flag.Visit(func(f *flag.Flag) { named = named || f.Name == "audit-log" })
+		if !named {
+			return errNoAuditLog
+		}
 	}
 
-	database, err := db.Connect(*connStr)
+	toOpen, err := resolve(conns, *configPath, *profiles, !*demoMode, db.Options{
+		QueryTimeout: *queryTimeout,
+		Cluster:      *cluster,
+	}, *allowDML)
+	if errors.Is(err, config.ErrNoConfig) && len(conns) == 0 && *profiles == "" {
+		return errUsage
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: could not connect to database: %v\n", err)
-		os.Exit(1)
+		return err
 	}
-	defer database.Close()
 
-	app := ui.NewApp(database)
-	if err := app.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+	auditLog := audit.New(*auditPath, *readOnly)
+	defer auditLog.Close()
+
+	var targets []*target.Target
+	defer func() {
+		for _, t := range targets {
+			t.Close()
+		}
+	}()
+	// Databases opened with -conn or -demo have no profile to choose
+	// their metrics.
+	metrics := config.DefaultMetrics
+	if *demoMode || len(conns) > 0 {
+		metrics = fileMetrics(*configPath)
+	}
+	addTarget := func(name string, src db.Source, allowDML bool, metrics []config.Metric) error {
+		opts := target.Options{
+			ASH:      ash.Options{Interval: *ashInterval, Retention: *ashRetention},
+			Audit:    auditLog,
+			AllowDML: allowDML,
+			Metrics:  metrics,
+		}
+		if *ashDir != "" {
+			opts.ASH.File = filepath.Join(*ashDir, fileName(name)+".ash.csv")
+		}
+		t, err := target.New(name, src, opts)
+		if err != nil {
+			src.Close()
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		targets = append(targets, t)
+		return nil
+	}
+	if *demoMode {
+		instances := 1
+		if *cluster {
+			instances = 2
+		}
+		if err := addTarget("demo", demo.New(instances), *allowDML, metrics); err != nil {
+			return err
+		}
+	}
+	for _, c := range toOpen {
+		database, err := db.Connect(context.Background(), c.dsn, c.opts)
+		if err != nil {
+			return fmt.Errorf("could not connect to %s: %w", c.name, err)
+		}
+		m := c.metrics
+		if m == nil {
+			m = metrics
+		}
+		if err := addTarget(c.name, database, c.allowDML, m); err != nil {
+			return err
+		}
+	}
+
+	// Probe up front so panels the user lacks grants for are disabled with
+	// an explanation instead of failing with raw ORA- errors later.
+	for _, t := range targets {
+		caps, err := t.Source.GetCapabilities(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: capability probe of %s failed: %v\n", t.Name, err)
+		}
+		t.Caps = caps
+	}
+
+	return ui.NewApp(targets).Run()
+}
+
+// connection is a database to open: its display name, godror connection
+// string and options, whether it opts in to DML from the query editor, and,
+// for a profile, the metrics it shows.
+type connection struct {
+	name     string
+	dsn      string
+	opts     db.Options
+	allowDML bool
+	metrics  []config.Metric
+}
+
+// resolve returns the connections to open: every -conn, then every profile
+// named by -profile. If neither is given and useDefault is set, the config
+// file's default profile is used. Profile settings only apply where the
+// corresponding flag was left unset.
+func resolve(conns []string, configPath, profiles string, useDefault bool, opts db.Options, allowDML bool) ([]connection, error) {
+	var out []connection
+	for _, connStr := range conns {
+		dsn, err := config.WithPassword(connStr, promptPassword)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, connection{name: config.Describe(connStr), dsn: dsn, opts: opts, allowDML: allowDML})
+	}
+
+	var names []string
+	for _, name := range strings.Split(profiles, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 && (len(conns) > 0 || !useDefault) {
+		return out, nil
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		names = []string{""} // the default profile
+	}
+
+	set := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, name := range names {
+		name, p, err := cfg.Profile(name)
+		if err != nil {
+			return nil, err
+		}
+		o := opts
+		if !set["cluster"] {
+			o.Cluster = p.Cluster
+		}
+		if !set["timeout"] && p.Timeout > 0 {
+			o.QueryTimeout = p.Timeout
+		}
+		allow := allowDML
+		if !set["allow-dml"] {
+			allow = p.AllowDML
+		}
+		dsn, err := p.DSN(promptPassword)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", name, err)
+		}
+		out = append(out, connection{name: name, dsn: dsn, opts: o, allowDML: allow, metrics: cfg.MetricsFor(p)})
+	}
+	return out, nil
+}
+
+// fileMetrics returns the metrics the config file at path chooses for every
+// profile, or DefaultMetrics if there is no config file.
+func fileMetrics(path string) []config.Metric {
+	cfg, err := config.Load(path)
+	if err != nil {
+		if !errors.Is(err, config.ErrNoConfig) {
+			fmt.Fprintf(os.Stderr, "warning: %v; showing the default metrics\n", err)
+		}
+		return config.DefaultMetrics
+	}
+	return cfg.MetricsFor(config.Profile{})
+}
+
+// fileName turns a target name such as "scott@db-01:1521/ORCL" into
+// something safe to use as a file name.
+func fileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// stringList is a flag.Value collecting every occurrence of a repeated flag.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// promptPassword reads a password from the terminal without echoing it.
+func promptPassword(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", errors.New("stdin is not a terminal; set the profile's password_env instead")
 	}
+	fmt.Fprint(os.Stderr, label)
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return string(password), err
 }